@@ -24,6 +24,7 @@ CREATE SCHEMA IF NOT EXISTS cards;          -- Gestão de cartões
 CREATE SCHEMA IF NOT EXISTS integrations;   -- Logs de APIs externas
 CREATE SCHEMA IF NOT EXISTS event_sourcing; -- Outbox e eventos
 CREATE SCHEMA IF NOT EXISTS audit;          -- Trilha de auditoria
+CREATE SCHEMA IF NOT EXISTS archive;        -- Partições desanexadas pela política de retenção
 
 -- ============================================================================
 -- TIPOS ENUM CUSTOMIZADOS
@@ -49,7 +50,7 @@ CREATE TYPE core.account_type AS ENUM (
 );
 
 CREATE TYPE core.account_status AS ENUM (
-    'ACTIVE', 'FROZEN', 'CLOSED', 'PENDING_ACTIVATION'
+    'ACTIVE', 'BLOCKED', 'FROZEN', 'CLOSED', 'PENDING_ACTIVATION'
 );
 
 CREATE TYPE core.transaction_status AS ENUM (
@@ -283,6 +284,111 @@ CREATE INDEX idx_sessions_token_hash ON identity.sessions(token_hash);
 CREATE INDEX idx_sessions_expires_at ON identity.sessions(expires_at);
 CREATE INDEX idx_sessions_active ON identity.sessions(is_active, user_id) WHERE is_active = TRUE;
 
+-- Tentativas de senha incorreta no webhook de boletos, por telefone e por IP.
+-- A chave é o SHA-256 do telefone/IP; a contagem reinicia a cada janela de bloqueio.
+CREATE TABLE identity.webhook_auth_attempts (
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('PHONE', 'IP')),
+    key_hash VARCHAR(64) NOT NULL,
+
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    window_started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ,
+
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+
+    PRIMARY KEY (scope, key_hash)
+);
+
+CREATE INDEX idx_webhook_auth_attempts_updated ON identity.webhook_auth_attempts(updated_at);
+
+-- Senha do webhook de boletos. verifier é Argon2id no formato PHC
+-- ($argon2id$v=19$m=...,t=...,p=...$salt$hash), com salt próprio por usuário.
+-- must_change marca a senha provisória definida pelo atendimento: ela não dá
+-- acesso aos boletos até o cliente cadastrar outra após o código de verificação.
+CREATE TABLE identity.webhook_credentials (
+    user_id UUID PRIMARY KEY REFERENCES identity.users(id) ON DELETE CASCADE,
+    verifier TEXT NOT NULL,
+    must_change BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    last_verified_at TIMESTAMPTZ
+);
+
+-- Códigos de verificação (OTP) do webhook de boletos. Guarda só o HMAC do código.
+CREATE TABLE identity.webhook_otp_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES identity.users(id) ON DELETE CASCADE,
+
+    code_hash VARCHAR(64) NOT NULL,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('SMS', 'WHATSAPP')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+
+    ip_address INET,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_otp_user ON identity.webhook_otp_challenges(user_id, created_at DESC)
+    WHERE consumed_at IS NULL;
+CREATE INDEX idx_webhook_otp_expires ON identity.webhook_otp_challenges(expires_at);
+
+-- Sessões curtas emitidas após a verificação do código; autorizam a consulta de boletos
+CREATE TABLE identity.webhook_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES identity.users(id) ON DELETE CASCADE,
+    challenge_id UUID REFERENCES identity.webhook_otp_challenges(id) ON DELETE SET NULL,
+
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    ip_address INET,
+
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_sessions_expires ON identity.webhook_sessions(expires_at);
+
+-- Chaves de API dos integradores do webhook (N8N, Make, Zapier...).
+-- A chave é mostrada uma única vez; aqui fica só o SHA-256.
+CREATE TABLE identity.webhook_api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+
+    key_prefix VARCHAR(16) NOT NULL UNIQUE,
+    key_hash VARCHAR(64) NOT NULL,
+
+    scopes TEXT[] NOT NULL DEFAULT '{boletos:consultar}',
+    rate_limit_per_minute INTEGER CHECK (rate_limit_per_minute > 0), -- NULL = padrão do serviço
+    daily_quota INTEGER CHECK (daily_quota > 0),                     -- NULL = sem cota
+    require_signature BOOLEAN NOT NULL DEFAULT FALSE,
+
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+-- Uso diário por chave (dia em UTC); base da cota e da atribuição de uso
+CREATE TABLE identity.webhook_api_key_usage (
+    api_key_id UUID NOT NULL REFERENCES identity.webhook_api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (api_key_id, day)
+);
+
+-- Assinaturas já aceitas, guardadas enquanto o X-Timestamp ainda é válido:
+-- a mesma requisição assinada não é aceita duas vezes (replay)
+CREATE TABLE identity.webhook_signature_nonces (
+    signature_hash VARCHAR(64) PRIMARY KEY,
+    api_key_id UUID NOT NULL REFERENCES identity.webhook_api_keys(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_webhook_signature_nonces_expires ON identity.webhook_signature_nonces(api_key_id, expires_at);
+
 -- ============================================================================
 -- SCHEMA: CORE (LEDGER - DOUBLE-ENTRY BOOKKEEPING)
 -- ============================================================================
@@ -333,6 +439,9 @@ CREATE TABLE core.accounts (
     balance DECIMAL(18,2) NOT NULL DEFAULT 0,
     available_balance DECIMAL(18,2) NOT NULL DEFAULT 0,
     blocked_balance DECIMAL(18,2) NOT NULL DEFAULT 0,
+    -- Incrementado a cada mudança de saldo (lançamentos e bloqueios);
+    -- o cache de saldos descarta gravações com versão não maior
+    balance_version BIGINT NOT NULL DEFAULT 0,
     
     -- Configurações
     currency VARCHAR(3) NOT NULL DEFAULT 'BRL',
@@ -360,6 +469,37 @@ CREATE TABLE core.accounts (
 CREATE INDEX idx_accounts_user_id ON core.accounts(user_id);
 CREATE INDEX idx_accounts_number ON core.accounts(account_number);
 CREATE INDEX idx_accounts_status ON core.accounts(status);
+CREATE INDEX idx_accounts_created ON core.accounts(created_at DESC, id DESC);
+
+-- Pedidos de aumento temporário de limite (daily_limit/transaction_limit).
+-- Aprovados por um ADMIN diferente do solicitante; valem de valid_from a valid_until.
+CREATE TABLE core.account_limit_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    account_id UUID NOT NULL REFERENCES core.accounts(id),
+    
+    limit_type VARCHAR(20) NOT NULL CHECK (limit_type IN ('DAILY', 'TRANSACTION')),
+    requested_limit DECIMAL(18,2) NOT NULL CHECK (requested_limit > 0),
+    duration_hours INTEGER NOT NULL CHECK (duration_hours BETWEEN 1 AND 720),
+    reason TEXT NOT NULL,
+    
+    -- Aprovação
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    requested_by VARCHAR(255), -- uid ou subject do token (nem sempre UUID)
+    decided_by VARCHAR(255),
+    decision_reason TEXT,
+    decided_at TIMESTAMPTZ,
+    valid_from TIMESTAMPTZ,
+    valid_until TIMESTAMPTZ,
+    
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_limit_requests_account ON core.account_limit_requests(account_id, created_at DESC);
+CREATE INDEX idx_limit_requests_active ON core.account_limit_requests(account_id, limit_type, valid_until)
+    WHERE status = 'APPROVED';
+
+-- Sequência para geração de números de conta ("000000123-4", DV módulo 11)
+CREATE SEQUENCE core.account_number_seq START WITH 1;
 
 -- Transações do ledger (imutáveis)
 CREATE TABLE core.transactions (
@@ -401,7 +541,7 @@ CREATE TABLE core.transactions_2024_01 PARTITION OF core.transactions
     FOR VALUES FROM ('2024-01-01') TO ('2024-02-01');
 CREATE TABLE core.transactions_2024_02 PARTITION OF core.transactions
     FOR VALUES FROM ('2024-02-01') TO ('2024-03-01');
--- ... criar partições para cada mês
+-- Demais meses: criados antecipadamente pelo ledger-service (internal/partitions)
 
 CREATE INDEX idx_transactions_reference ON core.transactions(reference_id);
 CREATE INDEX idx_transactions_status ON core.transactions(status);
@@ -438,19 +578,61 @@ CREATE TABLE core.ledger_entries_2024_01 PARTITION OF core.ledger_entries
 CREATE INDEX idx_ledger_transaction ON core.ledger_entries(transaction_id);
 CREATE INDEX idx_ledger_account ON core.ledger_entries(account_id);
 CREATE INDEX idx_ledger_sequence ON core.ledger_entries(sequence_number);
+CREATE INDEX idx_ledger_account_sequence ON core.ledger_entries(account_id, sequence_number DESC);
+-- Soma das saídas na janela móvel do limite diário
+CREATE INDEX idx_ledger_account_debits ON core.ledger_entries(account_id, created_at DESC) WHERE entry_type = 'DEBIT';
+
+-- Snapshots diários de saldo (consultas "saldo em" sem varrer todas as partições)
+CREATE TABLE core.balance_snapshots (
+    account_id UUID NOT NULL REFERENCES core.accounts(id),
+    snapshot_date DATE NOT NULL,  -- saldo ao final do dia (partition_date <= snapshot_date)
+    
+    balance DECIMAL(18,2) NOT NULL,
+    last_sequence_number BIGINT NOT NULL,
+    
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    PRIMARY KEY (account_id, snapshot_date)
+);
+
+-- Último dia processado pelo job de snapshots, inclusive dias sem lançamentos
+-- (que não geram linhas em balance_snapshots); linha única
+CREATE TABLE core.balance_snapshot_progress (
+    id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    last_snapshot_date DATE NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- Chaves de idempotência (X-Idempotency-Key = core.transactions.reference_id)
+CREATE TABLE core.idempotency_keys (
+    idempotency_key VARCHAR(100) PRIMARY KEY,
+    
+    -- SHA-256 de método + rota + corpo canonicalizado
+    request_fingerprint VARCHAR(64) NOT NULL,
+    
+    -- Resposta original (devolvida byte a byte em replays)
+    transaction_id UUID REFERENCES core.transactions(id),
+    response_status INT NOT NULL,
+    response_body BYTEA NOT NULL,
+    
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_idempotency_transaction ON core.idempotency_keys(transaction_id);
 
 -- View para verificar balanço (débito = crédito)
 CREATE VIEW core.transaction_balance_check AS
 SELECT 
     t.id as transaction_id,
     t.reference_id,
+    t.partition_date,
     SUM(CASE WHEN le.entry_type = 'DEBIT' THEN le.amount ELSE 0 END) as total_debits,
     SUM(CASE WHEN le.entry_type = 'CREDIT' THEN le.amount ELSE 0 END) as total_credits,
     SUM(CASE WHEN le.entry_type = 'DEBIT' THEN le.amount ELSE 0 END) - 
     SUM(CASE WHEN le.entry_type = 'CREDIT' THEN le.amount ELSE 0 END) as difference
 FROM core.transactions t
 JOIN core.ledger_entries le ON t.id = le.transaction_id
-GROUP BY t.id, t.reference_id
+GROUP BY t.id, t.reference_id, t.partition_date
 HAVING SUM(CASE WHEN le.entry_type = 'DEBIT' THEN le.amount ELSE 0 END) != 
        SUM(CASE WHEN le.entry_type = 'CREDIT' THEN le.amount ELSE 0 END);
 
@@ -530,6 +712,9 @@ CREATE TABLE payments.payment_intents (
     scheduled_for TIMESTAMPTZ,
     is_recurring BOOLEAN DEFAULT FALSE,
     recurring_config JSONB,
+    -- Execuções desfeitas por erro de infraestrutura (o executor adia e,
+    -- no limite, marca FAILED); retentativas de negócio ficam no metadata
+    attempts INT NOT NULL DEFAULT 0,
     
     -- Metadados
     metadata JSONB DEFAULT '{}',
@@ -1107,6 +1292,10 @@ CREATE INDEX idx_outbox_pending ON event_sourcing.outbox_events(status, created_
 CREATE INDEX idx_outbox_retry ON event_sourcing.outbox_events(next_retry_at) 
     WHERE status = 'FAILED' AND retry_count < max_retries;
 CREATE INDEX idx_outbox_aggregate ON event_sourcing.outbox_events(aggregate_type, aggregate_id);
+-- Eventos ainda não publicados por chave de partição (ordem por conta no relay)
+CREATE INDEX idx_outbox_unpublished_key ON event_sourcing.outbox_events(
+    (COALESCE(NULLIF(partition_key, ''), aggregate_id::text)), created_at
+) WHERE status <> 'PUBLISHED';
 
 -- Event Store (para event sourcing completo)
 CREATE TABLE event_sourcing.event_store (
@@ -1139,6 +1328,25 @@ CREATE INDEX idx_event_store_aggregate ON event_sourcing.event_store(aggregate_t
 CREATE INDEX idx_event_store_type ON event_sourcing.event_store(event_type);
 CREATE INDEX idx_event_store_created ON event_sourcing.event_store(created_at);
 
+-- Snapshots de agregados (último estado conhecido; replay continua a partir da versão)
+CREATE TABLE event_sourcing.aggregate_snapshots (
+    aggregate_type VARCHAR(100) NOT NULL,
+    aggregate_id UUID NOT NULL,
+    aggregate_version BIGINT NOT NULL,
+    state JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    
+    PRIMARY KEY (aggregate_type, aggregate_id)
+);
+
+-- Checkpoints da reconstrução de projeções (ledger-service rebuild-projections)
+CREATE TABLE event_sourcing.projection_checkpoints (
+    projection_name VARCHAR(100) PRIMARY KEY,
+    last_account_id UUID,  -- última conta confirmada; a retomada continua a partir dela
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
 -- ============================================================================
 -- SCHEMA: AUDIT (TRILHA DE AUDITORIA)
 -- ============================================================================
@@ -1207,16 +1415,39 @@ CREATE TRIGGER trigger_cards_updated_at
     BEFORE UPDATE ON cards.cards
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+-- Função para versionar o saldo da conta (balance, available e blocked)
+CREATE OR REPLACE FUNCTION bump_balance_version()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.balance IS DISTINCT FROM OLD.balance
+       OR NEW.available_balance IS DISTINCT FROM OLD.available_balance
+       OR NEW.blocked_balance IS DISTINCT FROM OLD.blocked_balance THEN
+        NEW.balance_version = OLD.balance_version + 1;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_accounts_balance_version
+    BEFORE UPDATE ON core.accounts
+    FOR EACH ROW EXECUTE FUNCTION bump_balance_version();
+
 -- Função para atualizar saldo da conta após lançamento
+-- (available_balance acompanha balance; bloqueios alteram apenas available/blocked)
 CREATE OR REPLACE FUNCTION update_account_balance()
 RETURNS TRIGGER AS $$
+DECLARE
+    delta DECIMAL(18,2);
 BEGIN
+    delta := CASE 
+        WHEN NEW.entry_type = 'CREDIT' THEN NEW.amount 
+        ELSE -NEW.amount 
+    END;
+
     UPDATE core.accounts
     SET 
-        balance = balance + CASE 
-            WHEN NEW.entry_type = 'CREDIT' THEN NEW.amount 
-            ELSE -NEW.amount 
-        END,
+        balance = balance + delta,
+        available_balance = available_balance + delta,
         updated_at = NOW()
     WHERE id = NEW.account_id;
     
@@ -0,0 +1,145 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Endpoints administrativos: senhas e chaves de API (token de administração)
+// ============================================================================
+
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware exige "Authorization: Bearer <WEBHOOK_ADMIN_TOKEN>"
+func AdminMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		informado := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if informado == "" || subtle.ConstantTimeCompare([]byte(informado), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{
+				Success: false,
+				Error:   "Token de administração inválido.",
+				Code:    "UNAUTHORIZED",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CadastroSenhaRequest define a senha do webhook de um usuário
+type CadastroSenhaRequest struct {
+	Senha string `json:"senha" binding:"required"`
+}
+
+// cadastrarSenha grava a senha provisória do usuário no cadastro/atendimento.
+// O cliente troca por uma senha própria em PUT /webhook/boletos/senha.
+func (a *App) cadastrarSenha(c *gin.Context) {
+	userID := c.Param("id")
+	var req CadastroSenhaRequest
+	if err := c.ShouldBindJSON(&req); err != nil || !uuidValido(userID) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Informe o id do usuário e a senha.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+
+	err := a.credenciais.cadastrar(c.Request.Context(), userID, strings.TrimSpace(req.Senha), true)
+	switch {
+	case errors.Is(err, ErrSenhaInvalida):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Senha inválida. Use de 4 a 8 dígitos.",
+			Code:    "INVALID_PASSWORD",
+		})
+	case errors.Is(err, ErrUsuarioNaoExiste):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Success: false,
+			Error:   "Usuário não encontrado.",
+			Code:    "USER_NOT_FOUND",
+		})
+	case err != nil:
+		a.responderErroInterno(c, "Erro ao cadastrar senha", err)
+	default:
+		a.logger.Infow("Senha provisória do webhook cadastrada", "user_id", userID)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// emitirChave gera a chave de API de um integrador. A chave e o segredo de
+// assinatura aparecem só nesta resposta.
+func (a *App) emitirChave(c *gin.Context) {
+	var req NovaChaveRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Nome) == "" ||
+		req.LimitePorMinuto < 0 || req.CotaDiaria < 0 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Informe o nome do integrador; limites e cotas não podem ser negativos.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+	req.Nome = strings.TrimSpace(req.Nome)
+
+	chave, err := a.chavesAPI.emitir(c.Request.Context(), req)
+	if errors.Is(err, ErrEscopoInvalido) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Escopo inválido. Use boletos:consultar ou boletos:otp.",
+			Code:    "INVALID_SCOPE",
+		})
+		return
+	}
+	if err != nil {
+		a.responderErroInterno(c, "Erro ao emitir chave de API", err)
+		return
+	}
+
+	a.logger.Infow("Chave de API emitida", "api_key_id", chave.ID, "integrador", chave.Nome, "escopos", chave.Escopos)
+	c.JSON(http.StatusCreated, chave)
+}
+
+// listarChaves lista as chaves (sem segredos) com o uso do dia
+func (a *App) listarChaves(c *gin.Context) {
+	chaves, err := a.chavesAPI.listar(c.Request.Context())
+	if err != nil {
+		a.responderErroInterno(c, "Erro ao listar chaves de API", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"chaves": chaves})
+}
+
+// revogarChave desativa a chave imediatamente
+func (a *App) revogarChave(c *gin.Context) {
+	id := c.Param("id")
+	if !uuidValido(id) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Id da chave inválido.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+
+	err := a.chavesAPI.revogar(c.Request.Context(), id)
+	if errors.Is(err, ErrChaveNaoExiste) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Success: false,
+			Error:   "Chave de API não encontrada.",
+			Code:    "API_KEY_NOT_FOUND",
+		})
+		return
+	}
+	if err != nil {
+		a.responderErroInterno(c, "Erro ao revogar chave de API", err)
+		return
+	}
+
+	a.logger.Infow("Chave de API revogada", "api_key_id", id)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,482 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Chaves de API dos integradores: escopos, cotas e requisições assinadas
+// ============================================================================
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+// Escopos das chaves
+const (
+	escopoConsultar = "boletos:consultar"
+	escopoOTP       = "boletos:otp"
+)
+
+var escoposValidos = map[string]bool{escopoConsultar: true, escopoOTP: true}
+
+// prefixoChave identifica as chaves do webhook: kbw_<prefixo>_<segredo>
+const prefixoChave = "kbw_"
+
+// tamanhoMaximoCorpo é o maior corpo aceito numa requisição assinada
+const tamanhoMaximoCorpo = 1 << 20
+
+var (
+	ErrChaveInvalida      = errors.New("chave de API inválida, expirada ou revogada")
+	ErrAssinaturaInvalida = errors.New("assinatura inválida ou expirada")
+	ErrAssinaturaRepetida = errors.New("assinatura já utilizada")
+	ErrChaveNaoExiste     = errors.New("chave de API não encontrada")
+	ErrEscopoInvalido     = errors.New("escopo desconhecido")
+)
+
+var requisicoesIntegrador = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "boleto_webhook_api_key_requests_total",
+	Help: "Requests to /webhook by integrator API key and response status",
+}, []string{"integrator", "route", "status"})
+
+// Integrador é o dono de uma chave de API autenticada
+type Integrador struct {
+	ID               string
+	Nome             string
+	Prefixo          string
+	Escopos          []string
+	LimitePorMinuto  int // 0 = padrão RATE_LIMIT_API_KEY_PER_MINUTE
+	CotaDiaria       int // 0 = sem cota
+	ExigirAssinatura bool
+}
+
+func (i *Integrador) temEscopo(escopo string) bool {
+	for _, e := range i.Escopos {
+		if e == escopo {
+			return true
+		}
+	}
+	return false
+}
+
+// ChavesAPI emite, autentica e revoga chaves em identity.webhook_api_keys.
+// O segredo de assinatura de cada chave é derivado do segredo do servidor e
+// do id da chave: não fica no banco e nunca trafega nas requisições.
+type ChavesAPI struct {
+	db          *sql.DB
+	segredo     []byte
+	obrigatoria bool          // sem chave, a requisição é recusada
+	janela      time.Duration // idade máxima do X-Timestamp assinado
+	logger      *zap.SugaredLogger
+}
+
+func NewChavesAPI(db *sql.DB, segredo string, obrigatoria bool, janela time.Duration, logger *zap.SugaredLogger) *ChavesAPI {
+	return &ChavesAPI{db: db, segredo: []byte(segredo), obrigatoria: obrigatoria, janela: janela, logger: logger}
+}
+
+// segredoAssinatura devolve a chave HMAC das requisições assinadas
+func (k *ChavesAPI) segredoAssinatura(id string) string {
+	mac := hmac.New(sha256.New, k.segredo)
+	mac.Write([]byte("assinatura:" + id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NovaChaveRequest define uma chave para um integrador
+type NovaChaveRequest struct {
+	Nome             string     `json:"nome" binding:"required"`
+	Escopos          []string   `json:"escopos"`           // padrão: boletos:consultar
+	LimitePorMinuto  int        `json:"limite_por_minuto"` // 0 = padrão do serviço
+	CotaDiaria       int        `json:"cota_diaria"`       // 0 = sem cota
+	ExigirAssinatura bool       `json:"exigir_assinatura"`
+	ExpiraEm         *time.Time `json:"expira_em,omitempty"`
+}
+
+// ChaveAPI descreve uma chave sem os segredos
+type ChaveAPI struct {
+	ID               string     `json:"id"`
+	Nome             string     `json:"nome"`
+	Prefixo          string     `json:"prefixo"`
+	Escopos          []string   `json:"escopos"`
+	LimitePorMinuto  *int       `json:"limite_por_minuto,omitempty"`
+	CotaDiaria       *int       `json:"cota_diaria,omitempty"`
+	ExigirAssinatura bool       `json:"exigir_assinatura"`
+	UsoHoje          int64      `json:"uso_hoje"`
+	CriadaEm         time.Time  `json:"criada_em"`
+	ExpiraEm         *time.Time `json:"expira_em,omitempty"`
+	UltimoUso        *time.Time `json:"ultimo_uso,omitempty"`
+	RevogadaEm       *time.Time `json:"revogada_em,omitempty"`
+}
+
+// ChaveEmitida traz a chave e o segredo de assinatura, exibidos uma única vez
+type ChaveEmitida struct {
+	ChaveAPI
+	Chave             string `json:"api_key"`
+	SegredoAssinatura string `json:"signing_secret"`
+}
+
+// emitir gera uma chave nova para o integrador
+func (k *ChavesAPI) emitir(ctx context.Context, req NovaChaveRequest) (*ChaveEmitida, error) {
+	if len(req.Escopos) == 0 {
+		req.Escopos = []string{escopoConsultar}
+	}
+	for _, e := range req.Escopos {
+		if !escoposValidos[e] {
+			return nil, fmt.Errorf("%w: %s", ErrEscopoInvalido, e)
+		}
+	}
+
+	prefixo := make([]byte, 6)
+	segredo := make([]byte, 32)
+	if _, err := rand.Read(prefixo); err != nil {
+		return nil, fmt.Errorf("erro ao gerar chave: %w", err)
+	}
+	if _, err := rand.Read(segredo); err != nil {
+		return nil, fmt.Errorf("erro ao gerar chave: %w", err)
+	}
+	p := hex.EncodeToString(prefixo)
+	chave := prefixoChave + p + "_" + base64.RawURLEncoding.EncodeToString(segredo)
+
+	emitida := &ChaveEmitida{
+		ChaveAPI: ChaveAPI{
+			Nome:             req.Nome,
+			Prefixo:          p,
+			Escopos:          req.Escopos,
+			ExigirAssinatura: req.ExigirAssinatura,
+			ExpiraEm:         req.ExpiraEm,
+		},
+		Chave: chave,
+	}
+	if req.LimitePorMinuto > 0 {
+		emitida.LimitePorMinuto = &req.LimitePorMinuto
+	}
+	if req.CotaDiaria > 0 {
+		emitida.CotaDiaria = &req.CotaDiaria
+	}
+
+	err := k.db.QueryRowContext(ctx, `
+		INSERT INTO identity.webhook_api_keys
+			(name, key_prefix, key_hash, scopes, rate_limit_per_minute, daily_quota, require_signature, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, req.Nome, p, generateHash(chave), pq.Array(req.Escopos), emitida.LimitePorMinuto,
+		emitida.CotaDiaria, req.ExigirAssinatura, req.ExpiraEm,
+	).Scan(&emitida.ID, &emitida.CriadaEm)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gravar chave: %w", err)
+	}
+	emitida.SegredoAssinatura = k.segredoAssinatura(emitida.ID)
+	return emitida, nil
+}
+
+// listar devolve todas as chaves com o uso do dia
+func (k *ChavesAPI) listar(ctx context.Context) ([]ChaveAPI, error) {
+	rows, err := k.db.QueryContext(ctx, `
+		SELECT k.id, k.name, k.key_prefix, k.scopes, k.rate_limit_per_minute, k.daily_quota,
+		       k.require_signature, COALESCE(u.requests, 0), k.created_at, k.expires_at,
+		       k.last_used_at, k.revoked_at
+		FROM identity.webhook_api_keys k
+		LEFT JOIN identity.webhook_api_key_usage u
+		       ON u.api_key_id = k.id AND u.day = (NOW() AT TIME ZONE 'UTC')::date
+		ORDER BY k.created_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	chaves := []ChaveAPI{}
+	for rows.Next() {
+		var ch ChaveAPI
+		var limite, cota sql.NullInt64
+		var expira, ultimo, revogada sql.NullTime
+		if err := rows.Scan(&ch.ID, &ch.Nome, &ch.Prefixo, pq.Array(&ch.Escopos), &limite, &cota,
+			&ch.ExigirAssinatura, &ch.UsoHoje, &ch.CriadaEm, &expira, &ultimo, &revogada); err != nil {
+			return nil, err
+		}
+		if limite.Valid {
+			v := int(limite.Int64)
+			ch.LimitePorMinuto = &v
+		}
+		if cota.Valid {
+			v := int(cota.Int64)
+			ch.CotaDiaria = &v
+		}
+		if expira.Valid {
+			ch.ExpiraEm = &expira.Time
+		}
+		if ultimo.Valid {
+			ch.UltimoUso = &ultimo.Time
+		}
+		if revogada.Valid {
+			ch.RevogadaEm = &revogada.Time
+		}
+		chaves = append(chaves, ch)
+	}
+	return chaves, rows.Err()
+}
+
+// revogar desativa a chave imediatamente; revogar de novo não é erro
+func (k *ChavesAPI) revogar(ctx context.Context, id string) error {
+	res, err := k.db.ExecContext(ctx, `
+		UPDATE identity.webhook_api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1
+	`, id)
+	if err != nil {
+		return fmt.Errorf("erro ao revogar chave: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrChaveNaoExiste
+	}
+	return nil
+}
+
+// autenticar busca a chave pelo prefixo e compara o hash em tempo constante
+func (k *ChavesAPI) autenticar(ctx context.Context, chave string) (*Integrador, error) {
+	partes := strings.SplitN(strings.TrimPrefix(chave, prefixoChave), "_", 2)
+	if !strings.HasPrefix(chave, prefixoChave) || len(partes) != 2 || partes[0] == "" {
+		return nil, ErrChaveInvalida
+	}
+
+	var integ Integrador
+	var hash string
+	var limite, cota sql.NullInt64
+	err := k.db.QueryRowContext(ctx, `
+		SELECT id, name, key_prefix, key_hash, scopes, rate_limit_per_minute, daily_quota, require_signature
+		FROM identity.webhook_api_keys
+		WHERE key_prefix = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, partes[0]).Scan(&integ.ID, &integ.Nome, &integ.Prefixo, &hash, pq.Array(&integ.Escopos),
+		&limite, &cota, &integ.ExigirAssinatura)
+	if err == sql.ErrNoRows {
+		return nil, ErrChaveInvalida
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar chave: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(generateHash(chave)), []byte(hash)) != 1 {
+		return nil, ErrChaveInvalida
+	}
+	integ.LimitePorMinuto = int(limite.Int64)
+	integ.CotaDiaria = int(cota.Int64)
+	return &integ, nil
+}
+
+// conferirAssinatura valida X-Signature = "sha256=" + hex(HMAC(segredo, timestamp + "." + corpo))
+func (k *ChavesAPI) conferirAssinatura(integ *Integrador, timestamp, assinatura string, corpo []byte) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrAssinaturaInvalida
+	}
+	if idade := time.Since(time.Unix(ts, 0)); idade > k.janela || idade < -k.janela {
+		return ErrAssinaturaInvalida
+	}
+	recebida, err := hex.DecodeString(strings.TrimPrefix(assinatura, "sha256="))
+	if err != nil {
+		return ErrAssinaturaInvalida
+	}
+
+	mac := hmac.New(sha256.New, []byte(k.segredoAssinatura(integ.ID)))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(corpo)
+	if !hmac.Equal(mac.Sum(nil), recebida) {
+		return ErrAssinaturaInvalida
+	}
+	return nil
+}
+
+// registrarAssinatura guarda a assinatura aceita até o X-Timestamp sair da
+// janela; a mesma assinatura de novo é replay. Aproveita para apagar as
+// assinaturas vencidas da chave.
+func (k *ChavesAPI) registrarAssinatura(ctx context.Context, integ *Integrador, assinatura string) error {
+	res, err := k.db.ExecContext(ctx, `
+		WITH vencidas AS (
+			DELETE FROM identity.webhook_signature_nonces WHERE api_key_id = $2 AND expires_at < NOW()
+		)
+		INSERT INTO identity.webhook_signature_nonces (signature_hash, api_key_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (signature_hash) DO NOTHING
+	`, generateHash(integ.ID+":"+strings.ToLower(strings.TrimPrefix(assinatura, "sha256="))), integ.ID,
+		time.Now().Add(2*k.janela))
+	if err != nil {
+		return fmt.Errorf("erro ao registrar assinatura: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAssinaturaRepetida
+	}
+	return nil
+}
+
+// registrarUso soma a requisição ao dia da chave, se couber na cota (0 = sem
+// cota). false = cota esgotada, sem somar.
+func (k *ChavesAPI) registrarUso(ctx context.Context, id string, cota int) (bool, error) {
+	var limite sql.NullInt64
+	if cota > 0 {
+		limite = sql.NullInt64{Int64: int64(cota), Valid: true}
+	}
+	res, err := k.db.ExecContext(ctx, `
+		WITH chave AS (
+			UPDATE identity.webhook_api_keys SET last_used_at = NOW() WHERE id = $1
+		)
+		INSERT INTO identity.webhook_api_key_usage (api_key_id, day, requests)
+		VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date, 1)
+		ON CONFLICT (api_key_id, day)
+		DO UPDATE SET requests = identity.webhook_api_key_usage.requests + 1
+		WHERE $2::bigint IS NULL OR identity.webhook_api_key_usage.requests < $2
+	`, id, limite)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Middleware autentica X-API-Key e confere a assinatura. O integrador fica no
+// contexto para escopos, rate limiting, cota, logs e métricas.
+// Sem chave, a requisição segue anônima, a menos que a chave seja obrigatória.
+func (k *ChavesAPI) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chave := c.GetHeader("X-API-Key")
+		if chave == "" {
+			if k.obrigatoria {
+				k.recusar(c, "anonymous", http.StatusUnauthorized, "Informe a chave de API no cabeçalho X-API-Key.", "API_KEY_REQUIRED")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		integ, err := k.autenticar(c.Request.Context(), chave)
+		if errors.Is(err, ErrChaveInvalida) {
+			k.logger.Warnw("Chave de API inválida", "client_ip", c.ClientIP())
+			k.recusar(c, "invalid", http.StatusUnauthorized, "Chave de API inválida, expirada ou revogada.", "INVALID_API_KEY")
+			return
+		}
+		if err != nil {
+			k.logger.Errorw("Erro ao autenticar chave de API", "error", err)
+			k.recusar(c, "unknown", http.StatusInternalServerError, "Erro interno. Tente novamente.", "INTERNAL_ERROR")
+			return
+		}
+		c.Set("integrador", integ)
+		c.Set("integrador_nome", integ.Nome)
+
+		assinatura := c.GetHeader("X-Signature")
+		if integ.ExigirAssinatura || assinatura != "" {
+			// Lê um byte além do limite: corpo maior é recusado, nunca truncado
+			corpo, err := io.ReadAll(io.LimitReader(c.Request.Body, tamanhoMaximoCorpo+1))
+			if err != nil {
+				k.recusar(c, integ.Nome, http.StatusBadRequest, "Corpo da requisição inválido.", "INVALID_REQUEST")
+				return
+			}
+			if len(corpo) > tamanhoMaximoCorpo {
+				k.recusar(c, integ.Nome, http.StatusRequestEntityTooLarge, "Corpo da requisição maior que 1 MiB.", "PAYLOAD_TOO_LARGE")
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(corpo))
+			if err := k.conferirAssinatura(integ, c.GetHeader("X-Timestamp"), assinatura, corpo); err != nil {
+				k.logger.Warnw("Assinatura inválida", "integrador", integ.Nome, "api_key", integ.Prefixo)
+				k.recusar(c, integ.Nome, http.StatusUnauthorized, "Assinatura ausente, inválida ou expirada.", "INVALID_SIGNATURE")
+				return
+			}
+			err = k.registrarAssinatura(c.Request.Context(), integ, assinatura)
+			if errors.Is(err, ErrAssinaturaRepetida) {
+				k.logger.Warnw("Assinatura repetida (replay)", "integrador", integ.Nome, "api_key", integ.Prefixo,
+					"client_ip", c.ClientIP())
+				k.recusar(c, integ.Nome, http.StatusUnauthorized, "Requisição assinada já recebida.", "REPLAYED_SIGNATURE")
+				return
+			}
+			if err != nil {
+				k.logger.Errorw("Erro ao registrar assinatura", "integrador", integ.Nome, "error", err)
+				k.recusar(c, integ.Nome, http.StatusInternalServerError, "Erro interno. Tente novamente.", "INTERNAL_ERROR")
+				return
+			}
+		}
+
+		c.Next()
+		requisicoesIntegrador.WithLabelValues(integ.Nome, c.FullPath(), strconv.Itoa(c.Writer.Status())).Inc()
+	}
+}
+
+// Cota soma a requisição ao uso do dia da chave e recusa quando a cota diária
+// acabou. Fica depois do rate limiting e do escopo: só requisições aceitas contam.
+func (k *ChavesAPI) Cota() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		integ, ok := integradorDoContexto(c)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		dentro, err := k.registrarUso(c.Request.Context(), integ.ID, integ.CotaDiaria)
+		if err != nil {
+			k.logger.Warnw("Erro ao registrar uso da chave", "integrador", integ.Nome, "error", err)
+		} else if !dentro {
+			amanha := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			c.Header("Retry-After", strconv.Itoa(segundos(time.Until(amanha))))
+			k.logger.Warnw("Cota diária excedida", "integrador", integ.Nome, "cota", integ.CotaDiaria)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{
+				Success: false,
+				Error:   "Cota diária da chave de API excedida.",
+				Code:    "QUOTA_EXCEEDED",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (k *ChavesAPI) recusar(c *gin.Context, integrador string, status int, mensagem, codigo string) {
+	if status == http.StatusUnauthorized {
+		c.Set(chaveFalhaAutenticacao, true)
+	}
+	c.AbortWithStatusJSON(status, ErrorResponse{Success: false, Error: mensagem, Code: codigo})
+	requisicoesIntegrador.WithLabelValues(integrador, c.FullPath(), strconv.Itoa(status)).Inc()
+}
+
+// chaveFalhaAutenticacao marca no contexto uma requisição recusada pela
+// autenticação; o rate limiter cobra essas falhas do bucket do IP
+const chaveFalhaAutenticacao = "falha_autenticacao"
+
+// integradorDoContexto devolve o integrador autenticado, se houver
+func integradorDoContexto(c *gin.Context) (*Integrador, bool) {
+	v, ok := c.Get("integrador")
+	if !ok {
+		return nil, false
+	}
+	integ, ok := v.(*Integrador)
+	return integ, ok
+}
+
+// exigirEscopo recusa chaves sem o escopo da rota; requisições anônimas
+// (permitidas sem WEBHOOK_API_KEY_REQUIRED) seguem
+func exigirEscopo(escopo string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if integ, ok := integradorDoContexto(c); ok && !integ.temEscopo(escopo) {
+			c.AbortWithStatusJSON(http.StatusForbidden, ErrorResponse{
+				Success: false,
+				Error:   "A chave de API não tem permissão para esta operação.",
+				Code:    "INSUFFICIENT_SCOPE",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const chaveTeste = "kbw_a1b2c3d4e5f6_segredo"
+
+// esperarChave simula a busca da chave de teste
+func esperarChave(mock sqlmock.Sqlmock, exigirAssinatura bool, cota interface{}) {
+	mock.ExpectQuery("FROM identity.webhook_api_keys").WithArgs("a1b2c3d4e5f6").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "key_prefix", "key_hash", "scopes",
+			"rate_limit_per_minute", "daily_quota", "require_signature"}).
+			AddRow("k-1", "n8n", "a1b2c3d4e5f6", generateHash(chaveTeste), "{boletos:consultar,boletos:otp}",
+				nil, cota, exigirAssinatura))
+}
+
+// assinar devolve os cabeçalhos de uma requisição assinada
+func assinar(app *App, corpo string, ts time.Time) map[string]string {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(app.chavesAPI.segredoAssinatura("k-1")))
+	mac.Write([]byte(timestamp + "." + corpo))
+	return map[string]string{
+		"X-API-Key":   chaveTeste,
+		"X-Timestamp": timestamp,
+		"X-Signature": "sha256=" + hex.EncodeToString(mac.Sum(nil)),
+	}
+}
+
+func TestAssinaturaRecusada(t *testing.T) {
+	app, mock := novoAppTeste(t)
+
+	// Assinatura de outro corpo
+	esperarChave(mock, true, nil)
+	rec := requisicao(t, app, http.MethodGet, "/webhook/boletos/consultar", "", ipTeste+":4000", assinar(app, "{}", time.Now()))
+	verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_SIGNATURE")
+
+	// Timestamp fora da janela
+	esperarChave(mock, true, nil)
+	rec = requisicao(t, app, http.MethodGet, "/webhook/boletos/consultar", "", ipTeste+":4000", assinar(app, "", time.Now().Add(-time.Hour)))
+	verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_SIGNATURE")
+
+	// Sem assinatura numa chave que a exige
+	esperarChave(mock, true, nil)
+	rec = requisicao(t, app, http.MethodGet, "/webhook/boletos/consultar", "", ipTeste+":4000", map[string]string{"X-API-Key": chaveTeste})
+	verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_SIGNATURE")
+
+	// Corpo acima do limite é recusado, não truncado
+	grande := strings.Repeat("a", tamanhoMaximoCorpo+1)
+	esperarChave(mock, true, nil)
+	rec = requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", grande, ipTeste+":4000", assinar(app, grande, time.Now()))
+	verificarResposta(t, rec, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAssinaturaRepetidaRecusada(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	headers := assinar(app, "", time.Now())
+
+	esperarChave(mock, true, nil)
+	mock.ExpectExec("INSERT INTO identity.webhook_signature_nonces").WillReturnResult(sqlmock.NewResult(0, 1))
+	rec := requisicao(t, app, http.MethodGet, "/webhook/boletos/consultar", "", ipTeste+":4000", headers)
+	verificarResposta(t, rec, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED")
+
+	// A mesma requisição reenviada dentro da janela
+	esperarChave(mock, true, nil)
+	mock.ExpectExec("INSERT INTO identity.webhook_signature_nonces").WillReturnResult(sqlmock.NewResult(0, 0))
+	rec = requisicao(t, app, http.MethodGet, "/webhook/boletos/consultar", "", ipTeste+":4000", headers)
+	verificarResposta(t, rec, http.StatusUnauthorized, "REPLAYED_SIGNATURE")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCotaContaSoRequisicoesAceitas(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	comRateLimiter(t, app, 0)
+	app.rateLimiter.apiKeyPorMinuto = 1
+	headers := map[string]string{"X-API-Key": chaveTeste}
+
+	// Primeira requisição: aceita e contada
+	esperarChave(mock, false, 100)
+	mock.ExpectExec("INSERT INTO identity.webhook_api_key_usage").WithArgs("k-1", int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000", headers)
+	verificarResposta(t, rec, http.StatusBadRequest, "INVALID_REQUEST")
+
+	// Segunda: barrada pelo rate limit, sem consumir a cota
+	esperarChave(mock, false, 100)
+	rec = requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000", headers)
+	verificarResposta(t, rec, http.StatusTooManyRequests, "RATE_LIMITED")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCotaEsgotada(t *testing.T) {
+	app, mock := novoAppTeste(t)
+
+	esperarChave(mock, false, 100)
+	mock.ExpectExec("INSERT INTO identity.webhook_api_key_usage").WithArgs("k-1", int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000",
+		map[string]string{"X-API-Key": chaveTeste})
+	verificarResposta(t, rec, http.StatusTooManyRequests, "QUOTA_EXCEEDED")
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("missing Retry-After")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChaveObrigatoriaEmProducao(t *testing.T) {
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("WEBHOOK_API_KEY_REQUIRED", "")
+	if !loadConfig().APIKeyRequired {
+		t.Fatal("API key must be required by default in production")
+	}
+	t.Setenv("APP_ENV", "development")
+	if loadConfig().APIKeyRequired {
+		t.Fatal("API key must be optional by default outside production")
+	}
+}
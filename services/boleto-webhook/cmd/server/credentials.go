@@ -0,0 +1,238 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Senhas do webhook: verificadores Argon2id com salt por usuário
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrSenhaInvalida     = errors.New("senha deve ter de 4 a 8 dígitos")
+	ErrVerificadorFormat = errors.New("verificador em formato desconhecido")
+	ErrUsuarioNaoExiste  = errors.New("usuário não encontrado")
+)
+
+// argonParams segue a recomendação da OWASP para Argon2id (19 MiB, 2 passadas).
+// Os parâmetros ficam no verificador; ao mudá-los, senhas antigas continuam
+// válidas e são recalculadas no próximo acesso.
+type argonParams struct {
+	memoria   uint32 // KiB
+	iteracoes uint32
+	paralelos uint8
+	tamSalt   int
+	tamChave  uint32
+}
+
+var paramsPadrao = argonParams{memoria: 19 * 1024, iteracoes: 2, paralelos: 1, tamSalt: 16, tamChave: 32}
+
+// Credenciais gera e confere verificadores em identity.webhook_credentials.
+// Com pepper (segredo do servidor, fora do banco), um dump da tabela não
+// basta para testar as 10.000 senhas de 4 dígitos offline.
+type Credenciais struct {
+	db     *sql.DB
+	pepper []byte
+	params argonParams
+}
+
+func NewCredenciais(db *sql.DB, pepper string) *Credenciais {
+	return &Credenciais{db: db, pepper: []byte(pepper), params: paramsPadrao}
+}
+
+// validarFormatoSenha aceita somente 4 a 8 dígitos
+func validarFormatoSenha(senha string) error {
+	if len(senha) < 4 || len(senha) > 8 || !isNumeric(senha) {
+		return ErrSenhaInvalida
+	}
+	return nil
+}
+
+// entrada aplica o pepper antes do Argon2
+func (c *Credenciais) entrada(senha string) []byte {
+	if len(c.pepper) == 0 {
+		return []byte(senha)
+	}
+	mac := hmac.New(sha256.New, c.pepper)
+	mac.Write([]byte(senha))
+	return mac.Sum(nil)
+}
+
+// gerarVerificador devolve o verificador no formato PHC:
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+func (c *Credenciais) gerarVerificador(senha string) (string, error) {
+	salt := make([]byte, c.params.tamSalt)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("erro ao gerar salt: %w", err)
+	}
+	p := c.params
+	hash := argon2.IDKey(c.entrada(senha), salt, p.iteracoes, p.memoria, p.paralelos, p.tamChave)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memoria, p.iteracoes, p.paralelos,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
+}
+
+// conferir compara em tempo constante. precisaRecalcular indica parâmetros
+// diferentes dos atuais.
+func (c *Credenciais) conferir(verificador, senha string) (ok, precisaRecalcular bool, err error) {
+	partes := strings.Split(verificador, "$")
+	if len(partes) != 6 || partes[1] != "argon2id" {
+		return false, false, ErrVerificadorFormat
+	}
+	var versao int
+	if _, err := fmt.Sscanf(partes[2], "v=%d", &versao); err != nil || versao != argon2.Version {
+		return false, false, ErrVerificadorFormat
+	}
+	var p argonParams
+	if _, err := fmt.Sscanf(partes[3], "m=%d,t=%d,p=%d", &p.memoria, &p.iteracoes, &p.paralelos); err != nil {
+		return false, false, ErrVerificadorFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(partes[4])
+	if err != nil {
+		return false, false, ErrVerificadorFormat
+	}
+	esperado, err := base64.RawStdEncoding.DecodeString(partes[5])
+	if err != nil || len(esperado) == 0 {
+		return false, false, ErrVerificadorFormat
+	}
+
+	hash := argon2.IDKey(c.entrada(senha), salt, p.iteracoes, p.memoria, p.paralelos, uint32(len(esperado)))
+	ok = subtle.ConstantTimeCompare(hash, esperado) == 1
+	precisaRecalcular = p.memoria != c.params.memoria || p.iteracoes != c.params.iteracoes ||
+		p.paralelos != c.params.paralelos || len(salt) != c.params.tamSalt || uint32(len(esperado)) != c.params.tamChave
+	return ok, precisaRecalcular, nil
+}
+
+// validar confere a senha do usuário. Sem verificador cadastrado, calcula um
+// hash descartável para o tempo de resposta não revelar a falta de cadastro.
+func (c *Credenciais) validar(ctx context.Context, userID, verificador, senha string) (bool, error) {
+	if verificador == "" {
+		_, _ = c.gerarVerificador(senha)
+		return false, nil
+	}
+
+	ok, recalcular, err := c.conferir(verificador, senha)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if recalcular {
+		novo, err := c.gerarVerificador(senha)
+		if err != nil {
+			return true, err
+		}
+		_, err = c.db.ExecContext(ctx, `
+			UPDATE identity.webhook_credentials
+			SET verifier = $2, updated_at = NOW(), last_verified_at = NOW()
+			WHERE user_id = $1
+		`, userID, novo)
+		return true, err
+	}
+	_, err = c.db.ExecContext(ctx, `
+		UPDATE identity.webhook_credentials SET last_verified_at = NOW() WHERE user_id = $1
+	`, userID)
+	return true, err
+}
+
+// cadastrar cria ou troca a senha do usuário. Senha provisória (definida
+// pelo atendimento, que a conhece) só serve para trocar: a consulta por senha
+// é recusada até o próprio cliente cadastrar outra após o código de verificação.
+func (c *Credenciais) cadastrar(ctx context.Context, userID, senha string, provisoria bool) error {
+	if err := validarFormatoSenha(senha); err != nil {
+		return err
+	}
+	verificador, err := c.gerarVerificador(senha)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.db.ExecContext(ctx, `
+		INSERT INTO identity.webhook_credentials (user_id, verifier, must_change)
+		SELECT id, $2, $3 FROM identity.users WHERE id = $1 AND deleted_at IS NULL
+		ON CONFLICT (user_id) DO UPDATE SET
+			verifier = EXCLUDED.verifier,
+			must_change = EXCLUDED.must_change,
+			updated_at = NOW()
+	`, userID, verificador, provisoria)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar senha: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUsuarioNaoExiste
+	}
+	return nil
+}
+
+// ============================================================================
+// CADASTRO PELO CLIENTE
+// ============================================================================
+
+// cadastrarSenhaPropria grava a senha escolhida pelo cliente e substitui a
+// provisória. Depende da verificação em duas etapas (otp.go): o telefone é
+// provado pelo token da sessão aberta com o código, nunca pela senha atual,
+// que o atendimento conhece. Sem sessão válida a rota responde INVALID_SESSION.
+func (a *App) cadastrarSenhaPropria(c *gin.Context) {
+	userID, err := "", ErrSessaoInvalida
+	if token := tokenSessao(c); token != "" {
+		userID, err = a.otp.sessao(c.Request.Context(), token)
+	}
+	if err != nil && !errors.Is(err, ErrSessaoInvalida) {
+		a.responderErroInterno(c, "Erro ao validar sessão", err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Success: false,
+			Error:   "Sessão inválida ou expirada. Solicite um novo código.",
+			Code:    "INVALID_SESSION",
+		})
+		return
+	}
+
+	var req CadastroSenhaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Dados inválidos. Informe a nova senha.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+
+	err = a.credenciais.cadastrar(c.Request.Context(), userID, strings.TrimSpace(req.Senha), false)
+	switch {
+	case errors.Is(err, ErrSenhaInvalida):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Senha inválida. Use de 4 a 8 dígitos.",
+			Code:    "INVALID_PASSWORD",
+		})
+	case errors.Is(err, ErrUsuarioNaoExiste):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Success: false,
+			Error:   "Sessão inválida ou expirada. Solicite um novo código.",
+			Code:    "INVALID_SESSION",
+		})
+	case err != nil:
+		a.responderErroInterno(c, "Erro ao cadastrar senha", err)
+	default:
+		a.logger.Infow("Senha do webhook cadastrada pelo cliente", "user_id", userID)
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConsultarBoletosSenhaProvisoriaExigeTroca(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	verificador, err := app.credenciais.gerarVerificador("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario).AddRow("u-1", "Ana ", telefoneTeste, verificador, true))
+	mock.ExpectExec("UPDATE identity.webhook_credentials SET last_verified_at").WithArgs("u-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM identity.webhook_auth_attempts").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE identity.webhook_auth_attempts").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// Senha certa, mas provisória: nenhum boleto é consultado
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+	verificarResposta(t, rec, http.StatusForbidden, "PASSWORD_CHANGE_REQUIRED")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCadastrarSenhaPropria(t *testing.T) {
+	app, mock := novoAppTeste(t)
+
+	// Sem sessão, nada é gravado
+	rec := requisicao(t, app, http.MethodPut, "/webhook/boletos/senha", `{"senha":"5678"}`, ipTeste+":4000", nil)
+	verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_SESSION")
+
+	mock.ExpectQuery("FROM identity.webhook_sessions").WithArgs(generateHash("token-sessao")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("u-1"))
+	mock.ExpectExec("INSERT INTO identity.webhook_credentials").
+		WithArgs("u-1", sqlmock.AnyArg(), false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rec = requisicao(t, app, http.MethodPut, "/webhook/boletos/senha", `{"senha":"5678"}`, ipTeste+":4000",
+		map[string]string{"Authorization": "Bearer token-sessao"})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Bloqueio temporário após tentativas de senha incorretas
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Escopos de contagem de falhas (identity.webhook_auth_attempts.scope)
+const (
+	escopoTelefone = "PHONE"
+	escopoIP       = "IP"
+)
+
+// Bloqueio conta tentativas por telefone e por IP no PostgreSQL, para valer
+// entre réplicas. Cada tentativa é reservada antes da conferência e devolvida
+// quando dá certo; as que sobram são as falhas. O limite por IP é maior:
+// integradores (chatbots) concentram muitos clientes finais no mesmo IP.
+type Bloqueio struct {
+	db            *sql.DB
+	maxTentativas int
+	maxPorIP      int
+	duracao       time.Duration
+}
+
+func NewBloqueio(db *sql.DB, maxTentativas, maxPorIP int, duracao time.Duration) *Bloqueio {
+	return &Bloqueio{db: db, maxTentativas: maxTentativas, maxPorIP: maxPorIP, duracao: duracao}
+}
+
+// intervalo formata a duração para parâmetros ::interval
+func (b *Bloqueio) intervalo() string {
+	return fmt.Sprintf("%d seconds", int64(b.duracao.Seconds()))
+}
+
+// verificar devolve quanto falta para o fim do bloqueio do telefone ou do IP (0 = livre)
+func (b *Bloqueio) verificar(ctx context.Context, telefone, ip string) (time.Duration, error) {
+	var restante sql.NullFloat64
+	err := b.db.QueryRowContext(ctx, `
+		SELECT EXTRACT(EPOCH FROM MAX(locked_until) - NOW())
+		FROM identity.webhook_auth_attempts
+		WHERE ((scope = $1 AND key_hash = $2) OR (scope = $3 AND key_hash = $4))
+		  AND locked_until > NOW()
+	`, escopoTelefone, generateHash(telefone), escopoIP, generateHash(ip)).Scan(&restante)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao verificar bloqueio: %w", err)
+	}
+	if !restante.Valid || restante.Float64 <= 0 {
+		return 0, nil
+	}
+	return time.Duration(restante.Float64 * float64(time.Second)), nil
+}
+
+// reservar conta a tentativa para o telefone e para o IP antes de conferir o
+// segredo e devolve quanto falta para o fim do bloqueio (0 = liberada). O
+// incremento e o teste do limite são um único upsert por escopo: tentativas
+// paralelas não passam todas antes de alguma falha ser registrada. Quem passa
+// do limite na janela bloqueia o escopo e é recusado.
+func (b *Bloqueio) reservar(ctx context.Context, telefone, ip string) (time.Duration, error) {
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao iniciar transação: %w", err)
+	}
+	defer tx.Rollback()
+
+	var restante time.Duration
+	for _, chave := range []struct {
+		escopo, valor string
+		limite        int
+	}{
+		{escopoTelefone, telefone, b.maxTentativas},
+		{escopoIP, ip, b.maxPorIP},
+	} {
+		r, err := b.reservarEscopo(ctx, tx, chave.escopo, generateHash(chave.valor), chave.limite)
+		if err != nil {
+			return 0, err
+		}
+		if r > restante {
+			restante = r
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("erro ao confirmar transação: %w", err)
+	}
+	return restante, nil
+}
+
+// reservarEscopo incrementa o contador (reiniciando janelas vencidas) e,
+// acima do limite, bloqueia e zera a janela. Bloqueado, nada muda.
+func (b *Bloqueio) reservarEscopo(ctx context.Context, tx *sql.Tx, escopo, chave string, limite int) (time.Duration, error) {
+	var restante float64
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO identity.webhook_auth_attempts AS t (scope, key_hash, failed_count, window_started_at, updated_at)
+		VALUES ($1, $2, 1, NOW(), NOW())
+		ON CONFLICT (scope, key_hash) DO UPDATE SET
+			failed_count = CASE
+				WHEN t.locked_until > NOW() THEN t.failed_count
+				WHEN t.window_started_at <= NOW() - $3::interval THEN 1
+				WHEN t.failed_count + 1 > $4 THEN 0
+				ELSE t.failed_count + 1
+			END,
+			window_started_at = CASE
+				WHEN t.locked_until > NOW() THEN t.window_started_at
+				WHEN t.window_started_at <= NOW() - $3::interval OR t.failed_count + 1 > $4 THEN NOW()
+				ELSE t.window_started_at
+			END,
+			locked_until = CASE
+				WHEN t.locked_until > NOW() THEN t.locked_until
+				WHEN t.window_started_at > NOW() - $3::interval AND t.failed_count + 1 > $4 THEN NOW() + $3::interval
+				ELSE t.locked_until
+			END,
+			updated_at = NOW()
+		RETURNING COALESCE(EXTRACT(EPOCH FROM locked_until - NOW()), 0)
+	`, escopo, chave, b.intervalo(), limite).Scan(&restante)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao reservar tentativa: %w", err)
+	}
+	if restante <= 0 {
+		return 0, nil
+	}
+	return time.Duration(restante * float64(time.Second)), nil
+}
+
+// liberar zera o contador do telefone após um acesso válido e devolve a
+// reserva do IP. O contador do IP não é zerado: um acerto em uma conta não
+// libera tentativas em outras.
+func (b *Bloqueio) liberar(ctx context.Context, telefone, ip string) error {
+	if _, err := b.db.ExecContext(ctx, `
+		DELETE FROM identity.webhook_auth_attempts WHERE scope = $1 AND key_hash = $2
+	`, escopoTelefone, generateHash(telefone)); err != nil {
+		return fmt.Errorf("erro ao limpar falhas: %w", err)
+	}
+	return b.devolverEscopo(ctx, escopoIP, generateHash(ip))
+}
+
+// devolver desfaz a reserva de uma tentativa que não chegou a conferir o
+// segredo (erro interno), para não contar como falha
+func (b *Bloqueio) devolver(ctx context.Context, telefone, ip string) error {
+	if err := b.devolverEscopo(ctx, escopoTelefone, generateHash(telefone)); err != nil {
+		return err
+	}
+	return b.devolverEscopo(ctx, escopoIP, generateHash(ip))
+}
+
+func (b *Bloqueio) devolverEscopo(ctx context.Context, escopo, chave string) error {
+	_, err := b.db.ExecContext(ctx, `
+		UPDATE identity.webhook_auth_attempts
+		SET failed_count = GREATEST(failed_count - 1, 0), updated_at = NOW()
+		WHERE scope = $1 AND key_hash = $2
+		  AND (locked_until IS NULL OR locked_until <= NOW())
+	`, escopo, chave)
+	if err != nil {
+		return fmt.Errorf("erro ao devolver tentativa: %w", err)
+	}
+	return nil
+}
+
+// responderBloqueado devolve 429 com ACCOUNT_LOCKED e Retry-After em segundos
+func responderBloqueado(c *gin.Context, restante time.Duration) {
+	segundos := int(math.Ceil(restante.Seconds()))
+	minutos := int(math.Ceil(restante.Minutes()))
+	c.Header("Retry-After", strconv.Itoa(segundos))
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Success: false,
+		Error:   fmt.Sprintf("Muitas tentativas incorretas. Tente novamente em %d minuto(s).", minutos),
+		Code:    "ACCOUNT_LOCKED",
+	})
+}
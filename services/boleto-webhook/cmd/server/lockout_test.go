@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	telefoneTeste = "11999998888"
+	ipTeste       = "192.0.2.10"
+	corpoConsulta = `{"telefone":"11999998888","senha":"1234"}`
+)
+
+var colunasUsuario = []string{"id", "nome", "phone_number", "verifier", "must_change"}
+
+func TestConsultarBoletosBloqueiaAposLimite(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	verificador, err := app.credenciais.gerarVerificador("9999")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 5 tentativas reservadas e erradas; a 6ª passa do limite
+	for i := 0; i < 5; i++ {
+		esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+		mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+			WillReturnRows(sqlmock.NewRows(colunasUsuario).AddRow("u-1", "Ana ", telefoneTeste, verificador, false))
+	}
+	esperarReserva(mock, telefoneTeste, ipTeste, 900, 0)
+
+	for i := 0; i < 5; i++ {
+		rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+		verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+	verificarResposta(t, rec, http.StatusTooManyRequests, "ACCOUNT_LOCKED")
+	if rec.Header().Get("Retry-After") != "900" {
+		t.Fatalf("Retry-After = %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Bloqueado, nem o usuário é buscado nem a senha é conferida
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsultarBoletosIgnoraXForwardedForSemProxyConfiavel(t *testing.T) {
+	app, mock := novoAppTeste(t)
+
+	// Trocar o X-Forwarded-For a cada tentativa não gera um contador novo
+	for _, falso := range []string{"203.0.113.1", "203.0.113.2"} {
+		esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+		mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+			WillReturnRows(sqlmock.NewRows(colunasUsuario))
+		rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000",
+			map[string]string{"X-Forwarded-For": falso})
+		verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsultarBoletosAceitaXForwardedForDeProxyConfiavel(t *testing.T) {
+	app, mock := novoAppTeste(t, func(c *Config) { c.TrustedProxies = []string{"10.0.0.0/8"} })
+
+	esperarReserva(mock, telefoneTeste, "203.0.113.7", 0, 0)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario))
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, "10.1.2.3:4000",
+		map[string]string{"X-Forwarded-For": "203.0.113.7"})
+	verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_CREDENTIALS")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsultarBoletosTelefoneDesconhecidoIgualSenhaErrada(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	verificador, err := app.credenciais.gerarVerificador("9999")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario))
+	desconhecido := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+
+	esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario).AddRow("u-1", "Ana ", telefoneTeste, verificador, false))
+	senhaErrada := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+
+	if desconhecido.Code != http.StatusUnauthorized || desconhecido.Body.String() != senhaErrada.Body.String() {
+		t.Fatalf("unknown phone: %d %s; wrong PIN: %d %s",
+			desconhecido.Code, desconhecido.Body, senhaErrada.Code, senhaErrada.Body)
+	}
+}
+
+func TestConsultarBoletosErroNoBancoNaoContaFalha(t *testing.T) {
+	app, mock := novoAppTeste(t)
+
+	esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnError(errors.New("conexão perdida"))
+	// A reserva é devolvida nos dois escopos
+	mock.ExpectExec("UPDATE identity.webhook_auth_attempts").WithArgs(escopoTelefone, generateHash(telefoneTeste)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE identity.webhook_auth_attempts").WithArgs(escopoIP, generateHash(ipTeste)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+	verificarResposta(t, rec, http.StatusInternalServerError, "INTERNAL_ERROR")
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsultarBoletosSucessoLiberaTentativas(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	verificador, err := app.credenciais.gerarVerificador("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	esperarReserva(mock, telefoneTeste, ipTeste, 0, 0)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario).AddRow("u-1", "Ana ", telefoneTeste, verificador, false))
+	mock.ExpectExec("UPDATE identity.webhook_credentials SET last_verified_at").WithArgs("u-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM identity.webhook_auth_attempts").WithArgs(escopoTelefone, generateHash(telefoneTeste)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE identity.webhook_auth_attempts").WithArgs(escopoIP, generateHash(ipTeste)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM payments.boletos").WithArgs("u-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", corpoConsulta, ipTeste+":4000", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d %s", rec.Code, rec.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -9,18 +9,26 @@ import (
 	"context"
 	"crypto/sha256"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
+	"github.com/kaminoclone/shared/secrets"
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -39,6 +47,10 @@ type Config struct {
 	Port string
 	Env  string
 
+	// Proxies/balanceadores cujo X-Forwarded-For é aceito (IPs ou CIDRs);
+	// vazio usa sempre o IP da conexão
+	TrustedProxies []string
+
 	// Database
 	DBHost     string
 	DBPort     string
@@ -47,16 +59,61 @@ type Config struct {
 	DBName     string
 	DBSSLMode  string
 
-	// Rate limiting
-	RateLimitPerMinute int
-	MaxAttempts        int    // Máximo de tentativas de senha incorreta
-	LockDuration       string // Duração do bloqueio após tentativas excedidas
+	// Vault. POSTGRES_PASSWORD aceita vault://mount/caminho#chave;
+	// com VaultDBRole, o banco usa credenciais dinâmicas.
+	VaultAddr    string
+	VaultToken   string
+	VaultDBMount string
+	VaultDBRole  string
+
+	// Senhas do webhook. O pepper (aceita vault://) entra no hash de todas as
+	// senhas; trocá-lo invalida as senhas cadastradas.
+	PinPepper string
+
+	// Token dos endpoints /admin (aceita vault://); vazio desativa /admin
+	AdminToken string
+
+	// Chaves de API dos integradores. APIKeySecret (aceita vault://) deriva os
+	// segredos de assinatura; trocá-lo invalida as assinaturas emitidas.
+	// APIKeyRequired é sempre true em produção.
+	APIKeyRequired  bool
+	APIKeySecret    string
+	SignatureMaxAge string
+
+	// Código de verificação (OTP). OTPSender: log, file ou http; log e file
+	// são só para desenvolvimento. Com OTPRequired, a consulta exige sessão.
+	OTPSender         string
+	OTPSenderFile     string
+	OTPSenderURL      string
+	OTPSenderToken    string // aceita vault://
+	OTPTTL            string
+	OTPResendInterval string
+	OTPMaxAttempts    int
+	SessionTTL        string
+	OTPRequired       bool
+
+	// Redis (rate limiting compartilhado entre réplicas)
+	RedisHost     string
+	RedisPort     string
+	RedisPassword string
+
+	// Rate limiting (token bucket por minuto; 0 desativa a dimensão)
+	RateLimitEnabled         bool
+	RateLimitPerMinute       int // por IP
+	RateLimitAPIKeyPerMinute int
+	RateLimitPhonePerMinute  int
+	MaxAttempts              int    // Máximo de tentativas de senha incorreta por telefone
+	MaxAttemptsPerIP         int    // Máximo de tentativas de senha incorreta por IP
+	LockDuration             string // Duração do bloqueio após tentativas excedidas
 }
 
 func loadConfig() *Config {
+	env := getEnv("APP_ENV", "development")
 	return &Config{
 		Port: getEnv("WEBHOOK_PORT", "8081"),
-		Env:  getEnv("APP_ENV", "development"),
+		Env:  env,
+
+		TrustedProxies: splitList(getEnv("TRUSTED_PROXIES", "")),
 
 		DBHost:     getEnv("POSTGRES_HOST", "localhost"),
 		DBPort:     getEnv("POSTGRES_PORT", "5433"),
@@ -65,9 +122,39 @@ func loadConfig() *Config {
 		DBName:     getEnv("POSTGRES_DB", "kamino"),
 		DBSSLMode:  getEnv("POSTGRES_SSLMODE", "disable"),
 
-		RateLimitPerMinute: 30,
-		MaxAttempts:        5,
-		LockDuration:       "15m",
+		VaultAddr:    getEnv("VAULT_ADDR", "http://localhost:8200"),
+		VaultToken:   getEnv("VAULT_TOKEN", ""),
+		VaultDBMount: getEnv("VAULT_DB_MOUNT", "database"),
+		VaultDBRole:  getEnv("VAULT_DB_ROLE", ""),
+
+		PinPepper:  getEnv("WEBHOOK_PIN_PEPPER", ""),
+		AdminToken: getEnv("WEBHOOK_ADMIN_TOKEN", ""),
+
+		APIKeyRequired:  getEnv("WEBHOOK_API_KEY_REQUIRED", strconv.FormatBool(env == "production")) == "true",
+		APIKeySecret:    getEnv("WEBHOOK_API_KEY_SECRET", ""),
+		SignatureMaxAge: getEnv("WEBHOOK_SIGNATURE_MAX_AGE", "5m"),
+
+		OTPSender:         getEnv("OTP_SENDER", "log"),
+		OTPSenderFile:     getEnv("OTP_SENDER_FILE", ""),
+		OTPSenderURL:      getEnv("OTP_SENDER_URL", ""),
+		OTPSenderToken:    getEnv("OTP_SENDER_TOKEN", ""),
+		OTPTTL:            getEnv("OTP_TTL", "5m"),
+		OTPResendInterval: getEnv("OTP_RESEND_INTERVAL", "60s"),
+		OTPMaxAttempts:    getEnvInt("OTP_MAX_ATTEMPTS", 5),
+		SessionTTL:        getEnv("WEBHOOK_SESSION_TTL", "10m"),
+		OTPRequired:       getEnv("WEBHOOK_OTP_REQUIRED", "false") == "true",
+
+		RedisHost:     getEnv("REDIS_HOST", "localhost"),
+		RedisPort:     getEnv("REDIS_PORT", "6379"),
+		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+
+		RateLimitEnabled:         getEnv("RATE_LIMIT_ENABLED", "true") == "true",
+		RateLimitPerMinute:       getEnvInt("RATE_LIMIT_IP_PER_MINUTE", 30),
+		RateLimitAPIKeyPerMinute: getEnvInt("RATE_LIMIT_API_KEY_PER_MINUTE", 600),
+		RateLimitPhonePerMinute:  getEnvInt("RATE_LIMIT_PHONE_PER_MINUTE", 10),
+		MaxAttempts:              getEnvInt("LOCKOUT_MAX_ATTEMPTS", 5),
+		MaxAttemptsPerIP:         getEnvInt("LOCKOUT_MAX_ATTEMPTS_PER_IP", 20),
+		LockDuration:             getEnv("LOCKOUT_DURATION", "15m"),
 	}
 }
 
@@ -78,6 +165,36 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// splitList separa uma lista por vírgulas, ignorando itens vazios
+func splitList(value string) []string {
+	var itens []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			itens = append(itens, item)
+		}
+	}
+	return itens
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+// dsn monta a string de conexão com as credenciais vigentes
+func (c *Config) dsn(creds secrets.Credentials) string {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	return fmt.Sprintf(
+		"host=%s port=%s user='%s' password='%s' dbname=%s sslmode=%s",
+		c.DBHost, c.DBPort, quote.Replace(creds.Username),
+		quote.Replace(creds.Password), c.DBName, c.DBSSLMode,
+	)
+}
+
 // ============================================================================
 // MODELOS
 // ============================================================================
@@ -85,7 +202,7 @@ func getEnv(key, defaultValue string) string {
 // ConsultaBoletoRequest requisição para consultar boletos
 type ConsultaBoletoRequest struct {
 	Telefone string `json:"telefone" binding:"required"` // Telefone do cliente (apenas números)
-	Senha    string `json:"senha" binding:"required"`    // Primeiros 4 dígitos do CPF/CNPJ
+	Senha    string `json:"senha" binding:"required"`    // Senha numérica de 4 a 8 dígitos
 }
 
 // BoletoResponse resposta com dados do boleto
@@ -131,28 +248,118 @@ type ErrorResponse struct {
 // ============================================================================
 
 type App struct {
-	config *Config
-	db     *sql.DB
-	logger *zap.SugaredLogger
+	config      *Config
+	db          *sql.DB
+	credentials *secrets.DynamicCredentials // nil com credenciais estáticas
+	bloqueio    *Bloqueio
+	credenciais *Credenciais
+	otp         *OTP
+	chavesAPI   *ChavesAPI
+	redis       *redis.Client // nil com o rate limiting desativado
+	rateLimiter *RateLimiter  // nil com o rate limiting desativado
+	logger      *zap.SugaredLogger
 }
 
 func NewApp(config *Config, logger *zap.SugaredLogger) (*App, error) {
-	// Conectar ao banco de dados
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.DBHost, config.DBPort, config.DBUser,
-		config.DBPassword, config.DBName, config.DBSSLMode,
-	)
+	duracaoBloqueio, err := time.ParseDuration(config.LockDuration)
+	if err != nil || duracaoBloqueio <= 0 {
+		return nil, fmt.Errorf("LOCKOUT_DURATION inválido: %q", config.LockDuration)
+	}
+	if config.MaxAttempts <= 0 || config.MaxAttemptsPerIP <= 0 {
+		return nil, fmt.Errorf("LOCKOUT_MAX_ATTEMPTS e LOCKOUT_MAX_ATTEMPTS_PER_IP devem ser positivos")
+	}
+	validadeCodigo, err := time.ParseDuration(config.OTPTTL)
+	if err != nil || validadeCodigo < time.Minute {
+		return nil, fmt.Errorf("OTP_TTL inválido: %q", config.OTPTTL)
+	}
+	intervaloReenvio, err := time.ParseDuration(config.OTPResendInterval)
+	if err != nil || intervaloReenvio < 0 {
+		return nil, fmt.Errorf("OTP_RESEND_INTERVAL inválido: %q", config.OTPResendInterval)
+	}
+	validadeSessao, err := time.ParseDuration(config.SessionTTL)
+	if err != nil || validadeSessao <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_SESSION_TTL inválido: %q", config.SessionTTL)
+	}
+	if config.OTPMaxAttempts <= 0 {
+		return nil, fmt.Errorf("OTP_MAX_ATTEMPTS deve ser positivo")
+	}
+	janelaAssinatura, err := time.ParseDuration(config.SignatureMaxAge)
+	if err != nil || janelaAssinatura <= 0 {
+		return nil, fmt.Errorf("WEBHOOK_SIGNATURE_MAX_AGE inválido: %q", config.SignatureMaxAge)
+	}
 
-	db, err := sql.Open("postgres", dsn)
+	// Resolver segredos no Vault
+	var vault *secrets.VaultClient
+	if config.VaultToken != "" {
+		vault = secrets.NewVaultClient(config.VaultAddr, config.VaultToken)
+	}
+	secretsCtx, cancelSecrets := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancelSecrets()
+
+	loader := secrets.NewLoader(vault)
+	password, err := loader.Resolve(secretsCtx, config.DBPassword)
 	if err != nil {
-		return nil, fmt.Errorf("erro ao conectar ao banco: %w", err)
+		return nil, fmt.Errorf("erro ao resolver POSTGRES_PASSWORD: %w", err)
+	}
+	config.DBPassword = password
+	if config.RedisPassword, err = loader.Resolve(secretsCtx, config.RedisPassword); err != nil {
+		return nil, fmt.Errorf("erro ao resolver REDIS_PASSWORD: %w", err)
+	}
+	if config.PinPepper, err = loader.Resolve(secretsCtx, config.PinPepper); err != nil {
+		return nil, fmt.Errorf("erro ao resolver WEBHOOK_PIN_PEPPER: %w", err)
+	}
+	if config.PinPepper == "" && config.Env == "production" {
+		return nil, fmt.Errorf("WEBHOOK_PIN_PEPPER é obrigatório em produção")
+	}
+	if config.AdminToken, err = loader.Resolve(secretsCtx, config.AdminToken); err != nil {
+		return nil, fmt.Errorf("erro ao resolver WEBHOOK_ADMIN_TOKEN: %w", err)
+	}
+	if config.APIKeySecret, err = loader.Resolve(secretsCtx, config.APIKeySecret); err != nil {
+		return nil, fmt.Errorf("erro ao resolver WEBHOOK_API_KEY_SECRET: %w", err)
+	}
+	if config.APIKeySecret == "" && config.Env == "production" {
+		return nil, fmt.Errorf("WEBHOOK_API_KEY_SECRET é obrigatório em produção")
+	}
+	if !config.APIKeyRequired && config.Env == "production" {
+		return nil, fmt.Errorf("WEBHOOK_API_KEY_REQUIRED=false não é permitido em produção")
+	}
+	if config.OTPSenderToken, err = loader.Resolve(secretsCtx, config.OTPSenderToken); err != nil {
+		return nil, fmt.Errorf("erro ao resolver OTP_SENDER_TOKEN: %w", err)
+	}
+	enviador, err := novoEnviador(config, logger)
+	if err != nil {
+		return nil, err
 	}
 
+	var source secrets.CredentialSource = secrets.StaticCredentials{Username: config.DBUser, Password: config.DBPassword}
+	var dynamic *secrets.DynamicCredentials
+	if config.VaultDBRole != "" {
+		if vault == nil {
+			return nil, fmt.Errorf("VAULT_DB_ROLE exige VAULT_TOKEN")
+		}
+		dynamic, err = secrets.NewDynamicCredentials(secretsCtx, vault, config.VaultDBMount, config.VaultDBRole, logger)
+		if err != nil {
+			return nil, err
+		}
+		source = dynamic
+	} else if err := secrets.RequireNonDefaultPassword(config.Env, config.DBPassword); err != nil {
+		return nil, err
+	}
+
+	// Conectar ao banco de dados (cada conexão nova usa as credenciais vigentes)
+	db := sql.OpenDB(secrets.NewConnector(source, config.dsn, func(dsn string) (driver.Connector, error) {
+		return pq.NewConnector(dsn)
+	}))
+
 	// Configurar pool de conexões
 	db.SetMaxOpenConns(25)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
+	if dynamic != nil {
+		dynamic.OnChange(func(secrets.Credentials) {
+			secrets.FlushIdle(db, 5)
+		})
+	}
 
 	// Testar conexão
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -162,14 +369,40 @@ func NewApp(config *Config, logger *zap.SugaredLogger) (*App, error) {
 		return nil, fmt.Errorf("erro ao pingar banco: %w", err)
 	}
 
+	// Sem Redis o limiter deixa passar (fail-open) e registra o erro
+	var redisClient *redis.Client
+	var rateLimiter *RateLimiter
+	if config.RateLimitEnabled {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     config.RedisHost + ":" + config.RedisPort,
+			Password: config.RedisPassword,
+		})
+		if err := redisClient.Ping(ctx).Err(); err != nil {
+			logger.Warnw("Redis indisponível, rate limiting inativo até reconectar", "error", err)
+		}
+		rateLimiter = NewRateLimiter(redisClient, config.RateLimitPerMinute,
+			config.RateLimitAPIKeyPerMinute, config.RateLimitPhonePerMinute, logger)
+	}
+
 	return &App{
-		config: config,
-		db:     db,
-		logger: logger,
+		config:      config,
+		db:          db,
+		credentials: dynamic,
+		bloqueio:    NewBloqueio(db, config.MaxAttempts, config.MaxAttemptsPerIP, duracaoBloqueio),
+		credenciais: NewCredenciais(db, config.PinPepper),
+		otp: NewOTP(db, enviador, config.PinPepper, validadeCodigo, intervaloReenvio,
+			config.OTPMaxAttempts, validadeSessao),
+		chavesAPI:   NewChavesAPI(db, config.APIKeySecret, config.APIKeyRequired, janelaAssinatura, logger),
+		redis:       redisClient,
+		rateLimiter: rateLimiter,
+		logger:      logger,
 	}, nil
 }
 
 func (a *App) Close() error {
+	if a.redis != nil {
+		a.redis.Close()
+	}
 	return a.db.Close()
 }
 
@@ -177,8 +410,89 @@ func (a *App) Close() error {
 // HANDLERS
 // ============================================================================
 
-// consultarBoletos endpoint para consultar boletos por telefone e senha
+// DependencyCheck é o resultado da verificação de uma dependência.
+// Critical = a instância não deve receber tráfego sem ela.
+type DependencyCheck struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+var dependenciaUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "boleto_webhook_dependency_up",
+	Help: "Whether the last readiness probe of the dependency succeeded (1) or failed (0)",
+}, []string{"dependency"})
+
+// verificarDependencia executa o probe com timeout e reporta a latência
+func (a *App) verificarDependencia(ctx context.Context, nome string, critica bool, probe func(context.Context) error) DependencyCheck {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	err := probe(ctx)
+	check := DependencyCheck{
+		Status:    "ok",
+		Critical:  critica,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		check.Status, check.Error = "error", err.Error()
+		dependenciaUp.WithLabelValues(nome).Set(0)
+		a.logger.Warnw("Readiness check failed", "dependency", nome, "error", err)
+	} else {
+		dependenciaUp.WithLabelValues(nome).Set(1)
+	}
+	return check
+}
+
+// readyHandler testa o banco e, com rate limiting, o Redis. Sem o banco a
+// instância sai do balanceamento (503); sem o Redis o rate limiting libera
+// as requisições, então ela continua atendendo, mas como degraded.
+func (a *App) readyHandler(c *gin.Context) {
+	database := a.verificarDependencia(c.Request.Context(), "database", true, func(ctx context.Context) error {
+		var one int
+		return a.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	})
+	checks := gin.H{"database": database}
+
+	status, code := "ready", http.StatusOK
+	if database.Status != "ok" {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+
+	if a.redis != nil {
+		redisCheck := a.verificarDependencia(c.Request.Context(), "redis", false, func(ctx context.Context) error {
+			return a.redis.Ping(ctx).Err()
+		})
+		checks["redis"] = redisCheck
+		if redisCheck.Status != "ok" && status == "ready" {
+			status = "degraded"
+		}
+	}
+
+	c.JSON(code, gin.H{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+// consultarBoletos endpoint para consultar boletos. Aceita o token da sessão
+// aberta com o código de verificação ou, sem WEBHOOK_OTP_REQUIRED, telefone e senha.
 func (a *App) consultarBoletos(c *gin.Context) {
+	if token := tokenSessao(c); token != "" {
+		a.consultarBoletosPorSessao(c, token)
+		return
+	}
+	if a.config.OTPRequired {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Success: false,
+			Error:   "Valide o código enviado ao telefone e use o token da sessão.",
+			Code:    "OTP_REQUIRED",
+		})
+		return
+	}
+
 	var req ConsultaBoletoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
@@ -200,40 +514,104 @@ func (a *App) consultarBoletos(c *gin.Context) {
 		return
 	}
 
-	// Validar senha (deve ter exatamente 4 dígitos)
+	// Validar formato da senha (4 a 8 dígitos)
 	senha := strings.TrimSpace(req.Senha)
-	if len(senha) != 4 || !isNumeric(senha) {
+	if err := validarFormatoSenha(senha); err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Success: false,
-			Error:   "Senha inválida. Informe os 4 primeiros dígitos do seu CPF ou CNPJ.",
+			Error:   "Senha inválida. Informe sua senha numérica de 4 a 8 dígitos.",
 			Code:    "INVALID_PASSWORD",
 		})
 		return
 	}
 
-	// Buscar usuário pelo telefone
+	// Reservar a tentativa (telefone e IP) antes de testar a senha
+	ip := c.ClientIP()
+	if !a.reservarTentativa(c, telefone, ip) {
+		return
+	}
+
+	// Buscar usuário pelo telefone. Telefone desconhecido e senha errada têm a
+	// mesma resposta (e o mesmo custo de Argon2), para não revelar cadastros.
 	usuario, err := a.buscarUsuarioPorTelefone(c.Request.Context(), telefone)
+	if err != nil && !errors.Is(err, ErrUsuarioNaoExiste) {
+		a.devolverTentativa(c, telefone, ip)
+		a.responderErroInterno(c, "Erro ao buscar usuário", err)
+		return
+	}
+	if usuario == nil {
+		usuario = &Usuario{}
+	}
+
+	// Validar senha contra o verificador Argon2id do usuário
+	valida, err := a.credenciais.validar(c.Request.Context(), usuario.ID, usuario.Verificador, senha)
+	if err != nil && !valida {
+		a.devolverTentativa(c, telefone, ip)
+		a.responderErroInterno(c, "Erro ao validar senha", err)
+		return
+	}
 	if err != nil {
-		a.logger.Errorw("Erro ao buscar usuário", "telefone", telefone, "error", err)
-		c.JSON(http.StatusNotFound, ErrorResponse{
+		a.logger.Warnw("Erro ao atualizar verificador", "user_id", usuario.ID, "error", err)
+	}
+	if !valida {
+		switch {
+		case usuario.ID == "":
+			a.logger.Warnw("Telefone não cadastrado", "telefone", telefone, "client_ip", ip)
+		case usuario.Verificador == "":
+			a.logger.Warnw("Usuário sem senha cadastrada", "user_id", usuario.ID)
+		default:
+			a.logger.Warnw("Senha incorreta", "telefone", telefone, "client_ip", ip)
+		}
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Success: false,
-			Error:   "Telefone não encontrado no sistema.",
-			Code:    "USER_NOT_FOUND",
+			Error:   "Telefone ou senha incorretos.",
+			Code:    "INVALID_CREDENTIALS",
 		})
 		return
 	}
 
-	// Validar senha (primeiros 4 dígitos do documento)
-	if !a.validarSenha(senha, usuario.DocumentoHash, usuario.DocumentoPrimeiros4) {
-		a.logger.Warnw("Senha incorreta", "telefone", telefone)
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
+	if err := a.bloqueio.liberar(c.Request.Context(), telefone, ip); err != nil {
+		a.logger.Warnw("Erro ao limpar tentativas", "telefone", telefone, "error", err)
+	}
+
+	// Senha provisória (definida pelo atendimento) não dá acesso aos boletos
+	if usuario.TrocarSenha {
+		a.logger.Infow("Senha provisória usada na consulta", "user_id", usuario.ID)
+		c.JSON(http.StatusForbidden, ErrorResponse{
 			Success: false,
-			Error:   "Senha incorreta. A senha são os 4 primeiros dígitos do seu CPF ou CNPJ.",
-			Code:    "INVALID_CREDENTIALS",
+			Error:   "Senha provisória. Confirme o telefone pelo código de verificação e cadastre uma nova senha.",
+			Code:    "PASSWORD_CHANGE_REQUIRED",
 		})
 		return
 	}
 
+	a.responderBoletos(c, usuario)
+}
+
+// consultarBoletosPorSessao consulta os boletos do dono do token
+func (a *App) consultarBoletosPorSessao(c *gin.Context, token string) {
+	userID, err := a.otp.sessao(c.Request.Context(), token)
+	if err == nil {
+		var usuario *Usuario
+		usuario, err = a.buscarUsuarioPorID(c.Request.Context(), userID)
+		if err == nil {
+			a.responderBoletos(c, usuario)
+			return
+		}
+	}
+	if !errors.Is(err, ErrSessaoInvalida) && !errors.Is(err, ErrUsuarioNaoExiste) {
+		a.responderErroInterno(c, "Erro ao validar sessão", err)
+		return
+	}
+	c.JSON(http.StatusUnauthorized, ErrorResponse{
+		Success: false,
+		Error:   "Sessão inválida ou expirada. Solicite um novo código.",
+		Code:    "INVALID_SESSION",
+	})
+}
+
+// responderBoletos busca os boletos do usuário autenticado e responde
+func (a *App) responderBoletos(c *gin.Context, usuario *Usuario) {
 	// Buscar boletos do usuário
 	boletos, err := a.buscarBoletosPorUsuario(c.Request.Context(), usuario.ID)
 	if err != nil {
@@ -262,49 +640,99 @@ func (a *App) consultarBoletos(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// reservarTentativa reserva a tentativa do telefone e do IP. Bloqueado (ou
+// em erro), já responde e devolve false.
+func (a *App) reservarTentativa(c *gin.Context, telefone, ip string) bool {
+	restante, err := a.bloqueio.reservar(c.Request.Context(), telefone, ip)
+	if err != nil {
+		a.responderErroInterno(c, "Erro ao reservar tentativa", err)
+		return false
+	}
+	if restante > 0 {
+		a.logger.Warnw("Tentativa durante bloqueio", "telefone", telefone, "client_ip", ip, "restante", restante)
+		responderBloqueado(c, restante)
+		return false
+	}
+	return true
+}
+
+// devolverTentativa desfaz a reserva quando a conferência não aconteceu
+func (a *App) devolverTentativa(c *gin.Context, telefone, ip string) {
+	if err := a.bloqueio.devolver(c.Request.Context(), telefone, ip); err != nil {
+		a.logger.Warnw("Erro ao devolver tentativa", "telefone", telefone, "error", err)
+	}
+}
+
+// responderErroInterno registra o erro e responde 500 sem detalhes
+func (a *App) responderErroInterno(c *gin.Context, mensagem string, err error) {
+	a.logger.Errorw(mensagem, "error", err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Success: false,
+		Error:   "Erro interno. Tente novamente.",
+		Code:    "INTERNAL_ERROR",
+	})
+}
+
 // ============================================================================
 // TIPOS INTERNOS
 // ============================================================================
 
 type Usuario struct {
-	ID                  string
-	Nome                string
-	Telefone            string
-	DocumentoHash       string
-	DocumentoPrimeiros4 string // Cache dos primeiros 4 dígitos (em ambiente de produção, não armazenar)
+	ID          string
+	Nome        string
+	Telefone    string
+	Verificador string // Verificador Argon2id da senha; vazio sem cadastro
+	TrocarSenha bool   // senha provisória: só serve para cadastrar outra
 }
 
 // ============================================================================
 // QUERIES
 // ============================================================================
 
+// selectUsuario é a consulta base dos usuários do webhook
+const selectUsuario = `
+	SELECT 
+		u.id,
+		COALESCE(up.first_name_encrypted, '') || ' ' || COALESCE(up.last_name_encrypted, '') as nome,
+		u.phone_number,
+		COALESCE(wc.verifier, '') as verifier,
+		COALESCE(wc.must_change, FALSE) as must_change
+	FROM identity.users u
+	LEFT JOIN identity.user_profiles up ON u.id = up.user_id
+	LEFT JOIN identity.webhook_credentials wc ON u.id = wc.user_id
+`
+
 // buscarUsuarioPorTelefone busca um usuário pelo número de telefone
 func (a *App) buscarUsuarioPorTelefone(ctx context.Context, telefone string) (*Usuario, error) {
-	query := `
-		SELECT 
-			u.id,
-			COALESCE(up.first_name_encrypted, '') || ' ' || COALESCE(up.last_name_encrypted, '') as nome,
-			u.phone_number,
-			COALESCE(up.tax_id_hash, '') as tax_id_hash,
-			COALESCE(LEFT(up.tax_id_encrypted, 4), '') as primeiros_4
-		FROM identity.users u
-		LEFT JOIN identity.user_profiles up ON u.id = up.user_id
+	query := selectUsuario + `
 		WHERE u.phone_number = $1
 		  AND u.deleted_at IS NULL
 		LIMIT 1
 	`
+	return a.buscarUsuario(ctx, query, telefone)
+}
 
+// buscarUsuarioPorID busca o usuário de uma sessão
+func (a *App) buscarUsuarioPorID(ctx context.Context, userID string) (*Usuario, error) {
+	query := selectUsuario + `
+		WHERE u.id = $1
+		  AND u.deleted_at IS NULL
+	`
+	return a.buscarUsuario(ctx, query, userID)
+}
+
+func (a *App) buscarUsuario(ctx context.Context, query string, arg string) (*Usuario, error) {
 	var usuario Usuario
-	err := a.db.QueryRowContext(ctx, query, telefone).Scan(
+	err := a.db.QueryRowContext(ctx, query, arg).Scan(
 		&usuario.ID,
 		&usuario.Nome,
 		&usuario.Telefone,
-		&usuario.DocumentoHash,
-		&usuario.DocumentoPrimeiros4,
+		&usuario.Verificador,
+		&usuario.TrocarSenha,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("usuário não encontrado")
+		return nil, ErrUsuarioNaoExiste
 	}
 	if err != nil {
 		return nil, err
@@ -394,27 +822,17 @@ func (a *App) buscarBoletosPorUsuario(ctx context.Context, userID string) ([]Bol
 	return boletos, rows.Err()
 }
 
-// validarSenha valida se a senha informada corresponde aos primeiros 4 dígitos do documento
-func (a *App) validarSenha(senhaInformada, documentoHash, documentoPrimeiros4 string) bool {
-	// Opção 1: Comparar diretamente com os primeiros 4 dígitos armazenados
-	// (para ambiente de desenvolvimento/teste)
-	if documentoPrimeiros4 != "" && senhaInformada == documentoPrimeiros4 {
-		return true
-	}
-
-	// Opção 2: Gerar hash da senha + salt e comparar
-	// Em produção, você pode usar bcrypt ou similar
-	senhaHash := generateHash(senhaInformada)
-	return strings.HasPrefix(documentoHash, senhaHash)
-}
-
 // ============================================================================
 // UTILITÁRIOS
 // ============================================================================
 
 func normalizarTelefone(telefone string) string {
+	return somenteDigitos(telefone)
+}
+
+func somenteDigitos(s string) string {
 	var resultado strings.Builder
-	for _, c := range telefone {
+	for _, c := range s {
 		if c >= '0' && c <= '9' {
 			resultado.WriteRune(c)
 		}
@@ -422,6 +840,12 @@ func normalizarTelefone(telefone string) string {
 	return resultado.String()
 }
 
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+func uuidValido(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
 func isNumeric(s string) bool {
 	for _, c := range s {
 		if c < '0' || c > '9' {
@@ -474,6 +898,7 @@ func LoggingMiddleware(logger *zap.SugaredLogger) gin.HandlerFunc {
 			"latency_ms", latency.Milliseconds(),
 			"client_ip", c.ClientIP(),
 			"user_agent", c.Request.UserAgent(),
+			"integrador", c.GetString("integrador_nome"),
 		)
 	}
 }
@@ -482,7 +907,8 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID, X-API-Key, X-Timestamp, X-Signature")
+		c.Header("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -494,45 +920,20 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
-// ============================================================================
-// MAIN
-// ============================================================================
-
-func main() {
-	// Inicializar logger
-	logger, _ := zap.NewProduction()
-	if os.Getenv("APP_ENV") == "development" {
-		logger, _ = zap.NewDevelopment()
-	}
-	defer logger.Sync()
-
-	sugar := logger.Sugar()
-	sugar.Infow("Iniciando Boleto Webhook Service",
-		"version", Version,
-		"build_time", BuildTime,
-	)
-
-	// Carregar configuração
-	cfg := loadConfig()
-
-	// Criar aplicação
-	app, err := NewApp(cfg, sugar)
-	if err != nil {
-		sugar.Fatalw("Erro ao criar aplicação", "error", err)
-	}
-	defer app.Close()
+// router monta as rotas do serviço
+func (a *App) router() (*gin.Engine, error) {
+	router := gin.New()
 
-	// Configurar Gin
-	if cfg.Env == "production" {
-		gin.SetMode(gin.ReleaseMode)
+	// Só proxies configurados podem informar o IP do cliente (X-Forwarded-For);
+	// sem eles, vale o IP da conexão. Lockout e rate limiting usam esse IP.
+	if err := router.SetTrustedProxies(a.config.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES inválido: %w", err)
 	}
 
-	router := gin.New()
-
 	// Middlewares
 	router.Use(gin.Recovery())
 	router.Use(RequestIDMiddleware())
-	router.Use(LoggingMiddleware(sugar))
+	router.Use(LoggingMiddleware(a.logger))
 	router.Use(CORSMiddleware())
 
 	// Health checks
@@ -545,33 +946,45 @@ func main() {
 		})
 	})
 
-	router.GET("/ready", func(c *gin.Context) {
-		// Verificar conexão com banco
-		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
-		defer cancel()
-
-		dbStatus := "ok"
-		if err := app.db.PingContext(ctx); err != nil {
-			dbStatus = "error"
-		}
-
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ready",
-			"checks": gin.H{
-				"database": dbStatus,
-			},
-		})
+	router.GET("/live", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "alive"})
 	})
 
+	// Readiness: sem banco não há consulta de boletos, então responde 503
+	router.GET("/ready", a.readyHandler)
+
 	// Metrics
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API de Webhook para consulta de boletos
+	// Chave de API antes do rate limiting, que usa o limite da chave; falhas de
+	// autenticação são cobradas do bucket do IP, conferido antes da chave. A
+	// cota diária vem por rota, depois do rate limiting e do escopo
 	webhook := router.Group("/webhook")
+	if a.rateLimiter != nil {
+		webhook.Use(a.rateLimiter.FalhasDeAutenticacao())
+	}
+	webhook.Use(a.chavesAPI.Middleware())
+	if a.rateLimiter != nil {
+		webhook.Use(a.rateLimiter.Middleware())
+	}
 	{
 		// POST /webhook/boletos/consultar
-		// Consulta boletos por telefone e senha (primeiros 4 dígitos do CPF/CNPJ)
-		webhook.POST("/boletos/consultar", app.consultarBoletos)
+		// Consulta boletos pelo token da sessão ou por telefone e senha do webhook
+		webhook.POST("/boletos/consultar", exigirEscopo(escopoConsultar), a.chavesAPI.Cota(), a.consultarBoletos)
+
+		// POST /webhook/boletos/otp
+		// Envia o código de verificação de 6 dígitos por SMS ou WhatsApp
+		webhook.POST("/boletos/otp", exigirEscopo(escopoOTP), a.chavesAPI.Cota(), a.solicitarCodigo)
+
+		// POST /webhook/boletos/otp/verificar
+		// Troca o código por um token de sessão para a consulta
+		webhook.POST("/boletos/otp/verificar", exigirEscopo(escopoOTP), a.chavesAPI.Cota(), a.verificarCodigo)
+
+		// PUT /webhook/boletos/senha
+		// Cadastra a senha do cliente (troca a provisória). Exige o token da
+		// sessão do código de verificação, por isso usa o escopo boletos:otp
+		webhook.PUT("/boletos/senha", exigirEscopo(escopoOTP), a.chavesAPI.Cota(), a.cadastrarSenhaPropria)
 		
 		// GET /webhook/boletos/consultar (para facilitar testes)
 		webhook.GET("/boletos/consultar", func(c *gin.Context) {
@@ -583,6 +996,20 @@ func main() {
 		})
 	}
 
+	// Administração: cadastro de senhas e chaves de API
+	if a.config.AdminToken != "" {
+		admin := router.Group("/admin", AdminMiddleware(a.config.AdminToken))
+		{
+			// PUT /admin/usuarios/:id/senha
+			admin.PUT("/usuarios/:id/senha", a.cadastrarSenha)
+
+			// /admin/api-keys: emissão, listagem e revogação
+			admin.POST("/api-keys", a.emitirChave)
+			admin.GET("/api-keys", a.listarChaves)
+			admin.DELETE("/api-keys/:id", a.revogarChave)
+		}
+	}
+
 	// Documentação da API
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -590,11 +1017,25 @@ func main() {
 			"version":     Version,
 			"description": "API para consulta de boletos via webhook",
 			"endpoints": gin.H{
+				"POST /webhook/boletos/otp": gin.H{
+					"description": "Envia o código de verificação ao telefone cadastrado",
+					"body": gin.H{
+						"telefone": "Número do telefone (DDD + número)",
+						"canal":    "sms (padrão) ou whatsapp",
+					},
+				},
+				"POST /webhook/boletos/otp/verificar": gin.H{
+					"description": "Troca o código recebido por um token de sessão",
+					"body": gin.H{
+						"telefone": "Número do telefone (DDD + número)",
+						"codigo":   "Código de 6 dígitos",
+					},
+				},
 				"POST /webhook/boletos/consultar": gin.H{
-					"description": "Consulta boletos por telefone e senha",
+					"description": "Consulta boletos com \"Authorization: Bearer <token>\" ou por telefone e senha",
 					"body": gin.H{
 						"telefone": "Número do telefone (DDD + número)",
-						"senha":    "Primeiros 4 dígitos do CPF ou CNPJ",
+						"senha":    "Senha numérica de 4 a 8 dígitos",
 					},
 					"example": gin.H{
 						"telefone": "11999998888",
@@ -605,6 +1046,47 @@ func main() {
 		})
 	})
 
+	return router, nil
+}
+
+// ============================================================================
+// MAIN
+// ============================================================================
+
+func main() {
+	// Inicializar logger
+	logger, _ := zap.NewProduction()
+	if os.Getenv("APP_ENV") == "development" {
+		logger, _ = zap.NewDevelopment()
+	}
+	defer logger.Sync()
+
+	sugar := logger.Sugar()
+	sugar.Infow("Iniciando Boleto Webhook Service",
+		"version", Version,
+		"build_time", BuildTime,
+	)
+
+	// Carregar configuração
+	cfg := loadConfig()
+
+	// Criar aplicação
+	app, err := NewApp(cfg, sugar)
+	if err != nil {
+		sugar.Fatalw("Erro ao criar aplicação", "error", err)
+	}
+	defer app.Close()
+
+	// Configurar Gin
+	if cfg.Env == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router, err := app.router()
+	if err != nil {
+		sugar.Fatalw("Erro ao configurar rotas", "error", err)
+	}
+
 	// Criar servidor HTTP
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -614,6 +1096,13 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Renovação das credenciais dinâmicas do banco
+	credentialsCtx, stopCredentials := context.WithCancel(context.Background())
+	defer stopCredentials()
+	if app.credentials != nil {
+		go app.credentials.Run(credentialsCtx)
+	}
+
 	// Canal para shutdown graceful
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// novoAppTeste monta a aplicação sobre um banco simulado (sqlmock), sem Redis
+func novoAppTeste(t *testing.T, ajustes ...func(*Config)) (*App, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	config := &Config{
+		Env:              "test",
+		MaxAttempts:      5,
+		MaxAttemptsPerIP: 20,
+		PinPepper:        "pepper-teste",
+	}
+	for _, ajuste := range ajustes {
+		ajuste(config)
+	}
+
+	logger := zap.NewNop().Sugar()
+	app := &App{
+		config:      config,
+		db:          db,
+		bloqueio:    NewBloqueio(db, config.MaxAttempts, config.MaxAttemptsPerIP, 15*time.Minute),
+		credenciais: NewCredenciais(db, config.PinPepper),
+		otp:         NewOTP(db, EnviadorLog{logger: logger}, config.PinPepper, 5*time.Minute, time.Minute, 5, 10*time.Minute),
+		chavesAPI:   NewChavesAPI(db, "segredo-teste", config.APIKeyRequired, 5*time.Minute, logger),
+		logger:      logger,
+	}
+	return app, mock
+}
+
+// requisicao envia a requisição pelo router completo a partir de remoteAddr
+func requisicao(t *testing.T, app *App, metodo, caminho, corpo, remoteAddr string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	router, err := app.router()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(metodo, caminho, strings.NewReader(corpo))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// esperarReserva simula a reserva de uma tentativa; restante > 0 é bloqueio
+func esperarReserva(mock sqlmock.Sqlmock, telefone, ip string, restanteTelefone, restanteIP float64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO identity.webhook_auth_attempts").
+		WithArgs(escopoTelefone, generateHash(telefone), "900 seconds", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"restante"}).AddRow(restanteTelefone))
+	mock.ExpectQuery("INSERT INTO identity.webhook_auth_attempts").
+		WithArgs(escopoIP, generateHash(ip), "900 seconds", 20).
+		WillReturnRows(sqlmock.NewRows([]string{"restante"}).AddRow(restanteIP))
+	mock.ExpectCommit()
+}
+
+func verificarResposta(t *testing.T, rec *httptest.ResponseRecorder, status int, codigo string) {
+	t.Helper()
+	if rec.Code != status || !strings.Contains(rec.Body.String(), `"code":"`+codigo+`"`) {
+		t.Fatalf("got %d %s; want %d %s", rec.Code, rec.Body.String(), status, codigo)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	servidor := miniredis.RunT(t)
+	app.redis = redis.NewClient(&redis.Options{Addr: servidor.Addr()})
+	t.Cleanup(func() { app.redis.Close() })
+
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"one"}).AddRow(1))
+	rec := requisicao(t, app, http.MethodGet, "/ready", "", ipTeste+":4000", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"ready"`) {
+		t.Fatalf("all up: got %d %s", rec.Code, rec.Body)
+	}
+
+	// Sem Redis o rate limiting libera tudo: continua atendendo, mas degraded
+	servidor.Close()
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"one"}).AddRow(1))
+	rec = requisicao(t, app, http.MethodGet, "/ready", "", ipTeste+":4000", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"degraded"`) {
+		t.Fatalf("redis down: got %d %s", rec.Code, rec.Body)
+	}
+
+	mock.ExpectQuery("SELECT 1").WillReturnError(errors.New("conexão recusada"))
+	rec = requisicao(t, app, http.MethodGet, "/ready", "", ipTeste+":4000", nil)
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"status":"not_ready"`) {
+		t.Fatalf("database down: got %d %s", rec.Code, rec.Body)
+	}
+}
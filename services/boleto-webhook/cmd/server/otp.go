@@ -0,0 +1,403 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Verificação em duas etapas: código por SMS/WhatsApp e sessão curta
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrCodigoInvalido = errors.New("código inválido ou expirado")
+	ErrSessaoInvalida = errors.New("sessão inválida ou expirada")
+)
+
+// OTP emite códigos de 6 dígitos e troca um código correto por uma sessão.
+// Código e token ficam no banco só como hash: o código com HMAC (segredo do
+// servidor), pois 6 dígitos se testam offline; o token com SHA-256.
+type OTP struct {
+	db             *sql.DB
+	enviador       EnviadorCodigo
+	segredo        []byte
+	validade       time.Duration // validade do código
+	reenvio        time.Duration // intervalo mínimo entre dois códigos
+	maxTentativas  int           // erros aceitos por código
+	validadeSessao time.Duration
+}
+
+func NewOTP(db *sql.DB, enviador EnviadorCodigo, segredo string, validade, reenvio time.Duration, maxTentativas int, validadeSessao time.Duration) *OTP {
+	return &OTP{
+		db:             db,
+		enviador:       enviador,
+		segredo:        []byte(segredo),
+		validade:       validade,
+		reenvio:        reenvio,
+		maxTentativas:  maxTentativas,
+		validadeSessao: validadeSessao,
+	}
+}
+
+// hashCodigo amarra o código ao usuário
+func (o *OTP) hashCodigo(userID, codigo string) string {
+	mac := hmac.New(sha256.New, o.segredo)
+	mac.Write([]byte(userID + ":" + codigo))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func gerarCodigo() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", fmt.Errorf("erro ao gerar código: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func gerarTokenSessao() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erro ao gerar token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// emitir gera e envia um código ao telefone do usuário, invalidando os
+// anteriores. Dentro do intervalo de reenvio, nada é enviado e o retorno é
+// quanto falta para poder pedir outro.
+func (o *OTP) emitir(ctx context.Context, usuario *Usuario, canal, ip string) (time.Duration, error) {
+	codigo, err := gerarCodigo()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Serializa pedidos simultâneos do mesmo usuário
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('webhook_otp:' || $1))`, usuario.ID); err != nil {
+		return 0, err
+	}
+
+	var desde sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXTRACT(EPOCH FROM NOW() - MAX(created_at))
+		FROM identity.webhook_otp_challenges
+		WHERE user_id = $1
+	`, usuario.ID).Scan(&desde)
+	if err != nil {
+		return 0, fmt.Errorf("erro ao consultar último código: %w", err)
+	}
+	if desde.Valid {
+		if falta := o.reenvio - time.Duration(desde.Float64*float64(time.Second)); falta > 0 {
+			return falta, nil
+		}
+	}
+
+	// Só o código mais recente vale; registros antigos saem aqui
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM identity.webhook_otp_challenges
+		WHERE user_id = $1 AND expires_at < NOW() - INTERVAL '1 day'
+	`, usuario.ID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE identity.webhook_otp_challenges SET consumed_at = NOW()
+		WHERE user_id = $1 AND consumed_at IS NULL
+	`, usuario.ID); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO identity.webhook_otp_challenges (user_id, code_hash, channel, ip_address, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::inet, NOW() + make_interval(secs => $5))
+	`, usuario.ID, o.hashCodigo(usuario.ID, codigo), canal, ip, o.validade.Seconds()); err != nil {
+		return 0, fmt.Errorf("erro ao gravar código: %w", err)
+	}
+
+	// Envia antes do commit: se o gateway falhar, o código não fica valendo
+	err = o.enviador.Enviar(ctx, Mensagem{
+		Telefone: usuario.Telefone,
+		Canal:    canal,
+		Texto: fmt.Sprintf("KaminoClone: seu código de verificação é %s. Válido por %d minutos. Não compartilhe.",
+			codigo, int(o.validade.Minutes())),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao enviar código: %w", err)
+	}
+	return 0, tx.Commit()
+}
+
+// verificar confere o código do usuário e, se correto, abre uma sessão.
+// Cada erro conta para o código; no limite ele deixa de valer.
+func (o *OTP) verificar(ctx context.Context, userID, codigo, ip string) (string, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var id, hash string
+	var tentativas int
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, code_hash, attempts
+		FROM identity.webhook_otp_challenges
+		WHERE user_id = $1 AND consumed_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+		FOR UPDATE
+	`, userID).Scan(&id, &hash, &tentativas)
+	if err == sql.ErrNoRows {
+		return "", ErrCodigoInvalido
+	}
+	if err != nil {
+		return "", fmt.Errorf("erro ao buscar código: %w", err)
+	}
+
+	if !hmac.Equal([]byte(o.hashCodigo(userID, codigo)), []byte(hash)) {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE identity.webhook_otp_challenges
+			SET attempts = attempts + 1,
+			    consumed_at = CASE WHEN attempts + 1 >= $2 THEN NOW() END
+			WHERE id = $1
+		`, id, o.maxTentativas); err != nil {
+			return "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return "", err
+		}
+		return "", ErrCodigoInvalido
+	}
+
+	token, err := gerarTokenSessao()
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE identity.webhook_otp_challenges SET consumed_at = NOW() WHERE id = $1
+	`, id); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM identity.webhook_sessions
+		WHERE user_id = $1 AND expires_at < NOW() - INTERVAL '1 day'
+	`, userID); err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO identity.webhook_sessions (user_id, challenge_id, token_hash, ip_address, expires_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::inet, NOW() + make_interval(secs => $5))
+	`, userID, id, generateHash(token), ip, o.validadeSessao.Seconds()); err != nil {
+		return "", fmt.Errorf("erro ao gravar sessão: %w", err)
+	}
+	return token, tx.Commit()
+}
+
+// sessao devolve o usuário dono de um token ainda válido
+func (o *OTP) sessao(ctx context.Context, token string) (string, error) {
+	var userID string
+	err := o.db.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM identity.webhook_sessions
+		WHERE token_hash = $1 AND expires_at > NOW() AND revoked_at IS NULL
+	`, generateHash(token)).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrSessaoInvalida
+	}
+	return userID, err
+}
+
+// ============================================================================
+// HANDLERS
+// ============================================================================
+
+// SolicitarCodigoRequest pede o envio do código de verificação
+type SolicitarCodigoRequest struct {
+	Telefone string `json:"telefone" binding:"required"`
+	Canal    string `json:"canal"` // sms (padrão) ou whatsapp
+}
+
+// SolicitarCodigoResponse confirma o pedido sem revelar se o telefone existe
+type SolicitarCodigoResponse struct {
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	Canal     string `json:"canal"`
+	ExpiresIn int    `json:"expires_in"` // segundos
+}
+
+// VerificarCodigoRequest troca o código recebido por uma sessão
+type VerificarCodigoRequest struct {
+	Telefone string `json:"telefone" binding:"required"`
+	Codigo   string `json:"codigo" binding:"required"`
+}
+
+// SessaoResponse traz o token que autoriza a consulta de boletos
+type SessaoResponse struct {
+	Success   bool   `json:"success"`
+	Token     string `json:"token"`
+	TokenType string `json:"token_type"`
+	ExpiresIn int    `json:"expires_in"` // segundos
+}
+
+// solicitarCodigo envia um código de 6 dígitos ao telefone cadastrado
+func (a *App) solicitarCodigo(c *gin.Context) {
+	var req SolicitarCodigoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Dados inválidos. Informe o telefone.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+
+	telefone := normalizarTelefone(req.Telefone)
+	if len(telefone) < 10 || len(telefone) > 11 {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Telefone inválido. Informe DDD + número (10 ou 11 dígitos).",
+			Code:    "INVALID_PHONE",
+		})
+		return
+	}
+
+	canal := canalSMS
+	switch strings.ToLower(strings.TrimSpace(req.Canal)) {
+	case "", "sms":
+	case "whatsapp":
+		canal = canalWhatsApp
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Canal inválido. Use sms ou whatsapp.",
+			Code:    "INVALID_CHANNEL",
+		})
+		return
+	}
+
+	ip := c.ClientIP()
+	restante, err := a.bloqueio.verificar(c.Request.Context(), telefone, ip)
+	if err != nil {
+		a.responderErroInterno(c, "Erro ao verificar bloqueio", err)
+		return
+	}
+	if restante > 0 {
+		responderBloqueado(c, restante)
+		return
+	}
+
+	resposta := SolicitarCodigoResponse{
+		Success:   true,
+		Message:   "Se o telefone estiver cadastrado, o código foi enviado.",
+		Canal:     strings.ToLower(canal),
+		ExpiresIn: int(a.otp.validade.Seconds()),
+	}
+
+	// Cadastrado ou não, com código enviado, em espera de reenvio ou com falha
+	// no gateway, a resposta é a mesma: só o log mostra o que aconteceu
+	usuario, err := a.buscarUsuarioPorTelefone(c.Request.Context(), telefone)
+	switch {
+	case errors.Is(err, ErrUsuarioNaoExiste):
+		a.logger.Warnw("Código solicitado para telefone não cadastrado", "telefone", telefone, "client_ip", ip)
+	case err != nil:
+		a.logger.Errorw("Erro ao buscar usuário para o código", "telefone", telefone, "error", err)
+	default:
+		aguardar, err := a.otp.emitir(c.Request.Context(), usuario, canal, ip)
+		switch {
+		case err != nil:
+			a.logger.Errorw("Erro ao emitir código", "user_id", usuario.ID, "canal", canal, "error", err)
+		case aguardar > 0:
+			a.logger.Infow("Código pedido antes do intervalo de reenvio", "user_id", usuario.ID,
+				"client_ip", ip, "aguardar_s", segundos(aguardar))
+		default:
+			a.logger.Infow("Código de verificação enviado", "user_id", usuario.ID, "canal", canal)
+		}
+	}
+	c.JSON(http.StatusAccepted, resposta)
+}
+
+// verificarCodigo confere o código e devolve o token da sessão
+func (a *App) verificarCodigo(c *gin.Context) {
+	var req VerificarCodigoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Dados inválidos. Informe telefone e código.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+
+	telefone := normalizarTelefone(req.Telefone)
+	codigo := strings.TrimSpace(req.Codigo)
+	if len(telefone) < 10 || len(telefone) > 11 || len(codigo) != 6 || !isNumeric(codigo) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Success: false,
+			Error:   "Informe o telefone (DDD + número) e o código de 6 dígitos.",
+			Code:    "INVALID_REQUEST",
+		})
+		return
+	}
+
+	ip := c.ClientIP()
+	if !a.reservarTentativa(c, telefone, ip) {
+		return
+	}
+
+	token := ""
+	usuario, err := a.buscarUsuarioPorTelefone(c.Request.Context(), telefone)
+	if err == nil {
+		token, err = a.otp.verificar(c.Request.Context(), usuario.ID, codigo, ip)
+	}
+	if err != nil && !errors.Is(err, ErrUsuarioNaoExiste) && !errors.Is(err, ErrCodigoInvalido) {
+		a.devolverTentativa(c, telefone, ip)
+		a.responderErroInterno(c, "Erro ao verificar código", err)
+		return
+	}
+	if err != nil {
+		a.logger.Warnw("Código de verificação incorreto", "telefone", telefone, "client_ip", ip)
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Success: false,
+			Error:   "Código incorreto ou expirado.",
+			Code:    "INVALID_CODE",
+		})
+		return
+	}
+
+	if err := a.bloqueio.liberar(c.Request.Context(), telefone, ip); err != nil {
+		a.logger.Warnw("Erro ao limpar tentativas", "telefone", telefone, "error", err)
+	}
+
+	a.logger.Infow("Sessão do webhook aberta", "user_id", usuario.ID)
+	c.JSON(http.StatusOK, SessaoResponse{
+		Success:   true,
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresIn: int(a.otp.validadeSessao.Seconds()),
+	})
+}
+
+// tokenSessao extrai o token de "Authorization: Bearer <token>"
+func tokenSessao(c *gin.Context) string {
+	cabecalho := c.GetHeader("Authorization")
+	if len(cabecalho) < 7 || !strings.EqualFold(cabecalho[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(cabecalho[7:])
+}
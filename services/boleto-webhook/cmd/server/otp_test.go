@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// enviadorFalho simula o gateway de mensagens fora do ar
+type enviadorFalho struct{}
+
+func (enviadorFalho) Enviar(context.Context, Mensagem) error {
+	return errors.New("gateway respondeu 503")
+}
+
+const corpoOTP = `{"telefone":"11999998888","canal":"sms"}`
+
+func esperarSemBloqueio(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM identity.webhook_auth_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"restante"}).AddRow(nil))
+}
+
+func TestSolicitarCodigoRespostaUniforme(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	app.otp.enviador = enviadorFalho{}
+
+	// Telefone não cadastrado
+	esperarSemBloqueio(mock)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario))
+	desconhecido := requisicao(t, app, http.MethodPost, "/webhook/boletos/otp", corpoOTP, ipTeste+":4000", nil)
+
+	// Cadastrado, mas dentro do intervalo de reenvio
+	esperarSemBloqueio(mock)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario).AddRow("u-1", "Ana ", telefoneTeste, "", false))
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM identity.webhook_otp_challenges").
+		WillReturnRows(sqlmock.NewRows([]string{"desde"}).AddRow(10.0))
+	mock.ExpectRollback()
+	reenvio := requisicao(t, app, http.MethodPost, "/webhook/boletos/otp", corpoOTP, ipTeste+":4000", nil)
+
+	// Cadastrado, com o gateway fora do ar
+	esperarSemBloqueio(mock)
+	mock.ExpectQuery("FROM identity.users u").WithArgs(telefoneTeste).
+		WillReturnRows(sqlmock.NewRows(colunasUsuario).AddRow("u-1", "Ana ", telefoneTeste, "", false))
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FROM identity.webhook_otp_challenges").
+		WillReturnRows(sqlmock.NewRows([]string{"desde"}).AddRow(nil))
+	mock.ExpectExec("DELETE FROM identity.webhook_otp_challenges").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE identity.webhook_otp_challenges").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO identity.webhook_otp_challenges").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+	falha := requisicao(t, app, http.MethodPost, "/webhook/boletos/otp", corpoOTP, ipTeste+":4000", nil)
+
+	for nome, codigo := range map[string]int{"unknown phone": desconhecido.Code, "resend too soon": reenvio.Code, "sender failure": falha.Code} {
+		if codigo != http.StatusAccepted {
+			t.Errorf("%s: got %d; want 202", nome, codigo)
+		}
+	}
+	if desconhecido.Body.String() != reenvio.Body.String() || desconhecido.Body.String() != falha.Body.String() {
+		t.Fatalf("bodies differ:\n%s\n%s\n%s", desconhecido.Body, reenvio.Body, falha.Body)
+	}
+	if reenvio.Header().Get("Retry-After") != "" {
+		t.Fatalf("Retry-After leaks the resend cooldown: %q", reenvio.Header().Get("Retry-After"))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,274 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Rate limiting por token bucket no Redis (IP, API key e telefone)
+// ============================================================================
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// tokenBucket consome custo tokens (último ARGV, 0 ou 1) de cada bucket, só
+// se todos tiverem saldo; com custo 0 apenas confere. Cada bucket recebe
+// capacidade e reposição por minuto; o relógio é o do Redis, comum a todas as
+// réplicas. Devolve {permitido, e por bucket: restante, ms até encher, ms até
+// o próximo token}.
+var tokenBucket = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local cost = tonumber(ARGV[#KEYS + 1])
+local tokens, rates = {}, {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i])
+	local rate = capacity / 60000
+	local data = redis.call('HMGET', key, 'tokens', 'ts')
+	local current = tonumber(data[1])
+	local ts = tonumber(data[2])
+	if current == nil then
+		current = capacity
+		ts = now
+	end
+	current = math.min(capacity, current + math.max(0, now - ts) * rate)
+	if current < 1 then
+		allowed = 0
+	end
+	tokens[i], rates[i] = current, rate
+end
+local result = {allowed}
+for i, key in ipairs(KEYS) do
+	local capacity = tonumber(ARGV[i])
+	local current = tokens[i]
+	if allowed == 1 then
+		current = current - cost
+	end
+	local full = math.ceil((capacity - current) / rates[i])
+	redis.call('HSET', key, 'tokens', tostring(current), 'ts', now)
+	redis.call('PEXPIRE', key, full + 1000)
+	local retry = 0
+	if current < 1 then
+		retry = math.ceil((1 - current) / rates[i])
+	end
+	table.insert(result, math.floor(current))
+	table.insert(result, full)
+	table.insert(result, retry)
+end
+return result
+`)
+
+var rateLimitLiberadas = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "boleto_webhook_rate_limit_fail_open_total",
+	Help: "Requests let through without rate limiting because Redis was unavailable",
+})
+
+// RateLimiter limita requisições por IP ou chave de API, e por telefone
+type RateLimiter struct {
+	client            *redis.Client
+	ipPorMinuto       int
+	apiKeyPorMinuto   int
+	telefonePorMinuto int
+	logger            *zap.SugaredLogger
+}
+
+func NewRateLimiter(client *redis.Client, ipPorMinuto, apiKeyPorMinuto, telefonePorMinuto int, logger *zap.SugaredLogger) *RateLimiter {
+	return &RateLimiter{
+		client:            client,
+		ipPorMinuto:       ipPorMinuto,
+		apiKeyPorMinuto:   apiKeyPorMinuto,
+		telefonePorMinuto: telefonePorMinuto,
+		logger:            logger,
+	}
+}
+
+// bucket é um limite aplicado à requisição; porMinuto <= 0 desativa
+type bucket struct {
+	chave     string
+	porMinuto int
+}
+
+// cota é o resultado do bucket mais restritivo
+type cota struct {
+	permitido  bool
+	limite     int
+	restante   int
+	reset      time.Duration
+	retryAfter time.Duration
+}
+
+// Middleware aplica os limites e devolve os cabeçalhos RateLimit-*.
+// Se o Redis falhar, a requisição segue: o bloqueio por senha continua valendo.
+// Cada liberação sem limite é logada e contada, e o /ready fica degraded.
+func (r *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		buckets := r.buckets(c)
+		if len(buckets) == 0 {
+			c.Next()
+			return
+		}
+
+		resultado, ok := r.aplicar(c, buckets, 1)
+		if ok && !resultado.permitido {
+			r.recusar(c, resultado)
+			return
+		}
+		c.Next()
+	}
+}
+
+// FalhasDeAutenticacao fica antes da autenticação da chave de API e cobra do
+// bucket do IP cada requisição recusada por ela (chave ausente, inválida,
+// assinatura inválida ou repetida). Com o bucket vazio, o IP recebe 429 antes
+// de qualquer consulta de chave ou gravação de nonce: tentativas de adivinhar
+// chaves ficam limitadas como requisições anônimas.
+func (r *RateLimiter) FalhasDeAutenticacao() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if r.ipPorMinuto <= 0 {
+			c.Next()
+			return
+		}
+		buckets := []bucket{{"ratelimit:boleto:ip:" + c.ClientIP(), r.ipPorMinuto}}
+
+		// Só confere o saldo: requisições aceitas não são cobradas aqui
+		resultado, ok := r.aplicar(c, buckets, 0)
+		if ok && !resultado.permitido {
+			r.recusar(c, resultado)
+			return
+		}
+
+		c.Next()
+		if c.GetBool(chaveFalhaAutenticacao) {
+			r.aplicar(c, buckets, 1)
+		}
+	}
+}
+
+// aplicar consulta ou consome os buckets; ok=false com o Redis indisponível
+// (a requisição segue sem limite, com log e métrica)
+func (r *RateLimiter) aplicar(c *gin.Context, buckets []bucket, custo int) (cota, bool) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 500*time.Millisecond)
+	resultado, err := r.consumir(ctx, buckets, custo)
+	cancel()
+	if err != nil {
+		// Conta uma vez por requisição, mesmo passando pelos dois middlewares
+		if !c.GetBool("ratelimit_liberada") {
+			c.Set("ratelimit_liberada", true)
+			rateLimitLiberadas.Inc()
+			r.logger.Errorw("Rate limiter indisponível, requisição liberada sem limite",
+				"client_ip", c.ClientIP(), "path", c.Request.URL.Path, "error", err)
+		}
+		return cota{}, false
+	}
+	if custo > 0 {
+		c.Header("RateLimit-Limit", strconv.Itoa(resultado.limite))
+		c.Header("RateLimit-Remaining", strconv.Itoa(resultado.restante))
+		c.Header("RateLimit-Reset", strconv.Itoa(segundos(resultado.reset)))
+	}
+	return resultado, true
+}
+
+// recusar responde 429 com os cabeçalhos do bucket que bloqueou
+func (r *RateLimiter) recusar(c *gin.Context, resultado cota) {
+	c.Header("RateLimit-Limit", strconv.Itoa(resultado.limite))
+	c.Header("RateLimit-Remaining", strconv.Itoa(resultado.restante))
+	c.Header("RateLimit-Reset", strconv.Itoa(segundos(resultado.reset)))
+	c.Header("Retry-After", strconv.Itoa(segundos(resultado.retryAfter)))
+	r.logger.Warnw("Rate limit excedido", "client_ip", c.ClientIP(), "path", c.Request.URL.Path)
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, ErrorResponse{
+		Success: false,
+		Error:   "Muitas requisições. Aguarde e tente novamente.",
+		Code:    "RATE_LIMITED",
+	})
+}
+
+// buckets monta as chaves da requisição; telefone entra como hash. Com chave
+// de API autenticada, o bucket da chave (com o limite próprio dela, se houver)
+// substitui o do IP: integradores concentram muitos clientes no mesmo IP.
+// O IP é o da conexão; X-Forwarded-For só vale vindo de TRUSTED_PROXIES.
+func (r *RateLimiter) buckets(c *gin.Context) []bucket {
+	var buckets []bucket
+	if integ, ok := integradorDoContexto(c); ok {
+		porMinuto := r.apiKeyPorMinuto
+		if integ.LimitePorMinuto > 0 {
+			porMinuto = integ.LimitePorMinuto
+		}
+		if porMinuto > 0 {
+			buckets = append(buckets, bucket{"ratelimit:boleto:apikey:" + integ.ID, porMinuto})
+		}
+	} else if r.ipPorMinuto > 0 {
+		buckets = append(buckets, bucket{"ratelimit:boleto:ip:" + c.ClientIP(), r.ipPorMinuto})
+	}
+	if telefone := telefoneDoCorpo(c); telefone != "" && r.telefonePorMinuto > 0 {
+		buckets = append(buckets, bucket{"ratelimit:boleto:phone:" + generateHash(telefone), r.telefonePorMinuto})
+	}
+	return buckets
+}
+
+func (r *RateLimiter) consumir(ctx context.Context, buckets []bucket, custo int) (cota, error) {
+	keys := make([]string, len(buckets))
+	args := make([]interface{}, len(buckets), len(buckets)+1)
+	for i, b := range buckets {
+		keys[i], args[i] = b.chave, b.porMinuto
+	}
+	args = append(args, custo)
+
+	valores, err := tokenBucket.Run(ctx, r.client, keys, args...).Int64Slice()
+	if err != nil {
+		return cota{}, err
+	}
+
+	resultado := cota{permitido: valores[0] == 1, restante: math.MaxInt}
+	for i, b := range buckets {
+		restante := int(valores[1+i*3])
+		if restante < 0 {
+			restante = 0
+		}
+		reset := time.Duration(valores[2+i*3]) * time.Millisecond
+		retry := time.Duration(valores[3+i*3]) * time.Millisecond
+
+		// Cabeçalhos refletem o bucket com menos saldo (o que bloqueou, se algum)
+		if restante < resultado.restante || (restante == resultado.restante && retry > resultado.retryAfter) {
+			resultado.limite, resultado.restante, resultado.reset = b.porMinuto, restante, reset
+		}
+		if retry > resultado.retryAfter {
+			resultado.retryAfter = retry
+		}
+	}
+	return resultado, nil
+}
+
+// telefoneDoCorpo lê o campo telefone do JSON sem consumir o corpo
+func telefoneDoCorpo(c *gin.Context) string {
+	if c.Request.Body == nil || c.Request.Method != http.MethodPost {
+		return ""
+	}
+	corpo, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(corpo), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		Telefone string `json:"telefone"`
+	}
+	if json.Unmarshal(corpo, &req) != nil {
+		return ""
+	}
+	return normalizarTelefone(req.Telefone)
+}
+
+func segundos(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
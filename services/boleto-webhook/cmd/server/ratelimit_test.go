@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// comRateLimiter liga o rate limiting do app de teste a um miniredis
+func comRateLimiter(t *testing.T, app *App, ipPorMinuto int) *miniredis.Miniredis {
+	t.Helper()
+	servidor := miniredis.RunT(t)
+	app.redis = redis.NewClient(&redis.Options{Addr: servidor.Addr()})
+	t.Cleanup(func() { app.redis.Close() })
+	app.rateLimiter = NewRateLimiter(app.redis, ipPorMinuto, 600, 0, zap.NewNop().Sugar())
+	return servidor
+}
+
+func TestRateLimitIgnoraXForwardedForSemProxyConfiavel(t *testing.T) {
+	app, _ := novoAppTeste(t)
+	comRateLimiter(t, app, 2)
+
+	// Cada requisição finge outro IP; todas caem no bucket da conexão
+	var codigos []int
+	for _, falso := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+		rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000",
+			map[string]string{"X-Forwarded-For": falso})
+		codigos = append(codigos, rec.Code)
+	}
+	if codigos[0] == http.StatusTooManyRequests || codigos[1] == http.StatusTooManyRequests || codigos[2] != http.StatusTooManyRequests {
+		t.Fatalf("got %v; want the third request rate limited", codigos)
+	}
+}
+
+func TestRateLimitLiberaEContaSemRedis(t *testing.T) {
+	app, _ := novoAppTeste(t)
+	servidor := comRateLimiter(t, app, 1)
+	servidor.Close()
+
+	antes := testutil.ToFloat64(rateLimitLiberadas)
+	for i := 0; i < 2; i++ {
+		rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000", nil)
+		verificarResposta(t, rec, http.StatusBadRequest, "INVALID_REQUEST")
+	}
+	if liberadas := testutil.ToFloat64(rateLimitLiberadas) - antes; liberadas != 2 {
+		t.Fatalf("fail-open counter increased by %v; want 2", liberadas)
+	}
+}
+
+func TestRateLimitCobraFalhasDeChaveDoIP(t *testing.T) {
+	app, mock := novoAppTeste(t)
+	comRateLimiter(t, app, 2)
+	headers := map[string]string{"X-API-Key": "kbw_a1b2c3d4e5f6_errado"}
+
+	// Cada chave recusada consome o bucket do IP
+	for i := 0; i < 2; i++ {
+		esperarChave(mock, false, nil)
+		rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000", headers)
+		verificarResposta(t, rec, http.StatusUnauthorized, "INVALID_API_KEY")
+	}
+
+	// Bucket vazio: recusada antes de consultar a chave no banco
+	rec := requisicao(t, app, http.MethodPost, "/webhook/boletos/consultar", `{}`, ipTeste+":4000", headers)
+	verificarResposta(t, rec, http.StatusTooManyRequests, "RATE_LIMITED")
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
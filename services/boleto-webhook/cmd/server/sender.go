@@ -0,0 +1,135 @@
+// ============================================================================
+// KAMINOCLONE - BOLETO WEBHOOK SERVICE
+// Envio do código de verificação (SMS/WhatsApp)
+// ============================================================================
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Canais de entrega do código (identity.webhook_otp_challenges.channel)
+const (
+	canalSMS      = "SMS"
+	canalWhatsApp = "WHATSAPP"
+)
+
+// Mensagem é o que o provedor entrega ao cliente
+type Mensagem struct {
+	Telefone string `json:"to"`
+	Canal    string `json:"channel"`
+	Texto    string `json:"message"`
+}
+
+// EnviadorCodigo entrega o código ao telefone do cliente
+type EnviadorCodigo interface {
+	Enviar(ctx context.Context, msg Mensagem) error
+}
+
+// novoEnviador escolhe a implementação por OTP_SENDER (log, file ou http).
+// log e file expõem o código e só são aceitos fora de produção.
+func novoEnviador(config *Config, logger *zap.SugaredLogger) (EnviadorCodigo, error) {
+	tipo := strings.ToLower(config.OTPSender)
+	if (tipo == "log" || tipo == "file") && config.Env == "production" {
+		return nil, fmt.Errorf("OTP_SENDER=%s não é permitido em produção", tipo)
+	}
+	switch tipo {
+	case "log":
+		return EnviadorLog{logger: logger}, nil
+	case "file":
+		if config.OTPSenderFile == "" {
+			return nil, fmt.Errorf("OTP_SENDER=file exige OTP_SENDER_FILE")
+		}
+		return &EnviadorArquivo{caminho: config.OTPSenderFile}, nil
+	case "http":
+		if config.OTPSenderURL == "" {
+			return nil, fmt.Errorf("OTP_SENDER=http exige OTP_SENDER_URL")
+		}
+		return &EnviadorHTTP{
+			url:    config.OTPSenderURL,
+			token:  config.OTPSenderToken,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("OTP_SENDER desconhecido: %q", config.OTPSender)
+	}
+}
+
+// EnviadorLog registra a mensagem no log (desenvolvimento)
+type EnviadorLog struct {
+	logger *zap.SugaredLogger
+}
+
+func (e EnviadorLog) Enviar(_ context.Context, msg Mensagem) error {
+	e.logger.Infow("Código de verificação (OTP_SENDER=log)", "telefone", msg.Telefone, "canal", msg.Canal, "mensagem", msg.Texto)
+	return nil
+}
+
+// EnviadorArquivo acrescenta cada mensagem como uma linha JSON (desenvolvimento e testes)
+type EnviadorArquivo struct {
+	caminho string
+	mu      sync.Mutex
+}
+
+func (e *EnviadorArquivo) Enviar(_ context.Context, msg Mensagem) error {
+	linha, err := json.Marshal(struct {
+		Mensagem
+		EnviadoEm time.Time `json:"sent_at"`
+	}{msg, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	f, err := os.OpenFile(e.caminho, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("erro ao abrir %s: %w", e.caminho, err)
+	}
+	defer f.Close()
+	_, err = f.Write(append(linha, '\n'))
+	return err
+}
+
+// EnviadorHTTP envia {to, channel, message} ao gateway de SMS/WhatsApp
+type EnviadorHTTP struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func (e *EnviadorHTTP) Enviar(ctx context.Context, msg Mensagem) error {
+	corpo, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(corpo))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("erro ao chamar gateway de mensagens: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gateway de mensagens respondeu %d", resp.StatusCode)
+	}
+	return nil
+}
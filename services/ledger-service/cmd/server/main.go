@@ -6,17 +6,45 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+
+	"github.com/kaminoclone/ledger-service/internal/accounts"
+	"github.com/kaminoclone/ledger-service/internal/auth"
+	"github.com/kaminoclone/ledger-service/internal/balances"
+	"github.com/kaminoclone/ledger-service/internal/cache"
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+	"github.com/kaminoclone/ledger-service/internal/health"
+	"github.com/kaminoclone/ledger-service/internal/holds"
+	"github.com/kaminoclone/ledger-service/internal/idempotency"
+	"github.com/kaminoclone/ledger-service/internal/integrity"
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+	"github.com/kaminoclone/ledger-service/internal/limits"
+	"github.com/kaminoclone/ledger-service/internal/outbox"
+	"github.com/kaminoclone/ledger-service/internal/partitions"
+	"github.com/kaminoclone/ledger-service/internal/payments"
+	"github.com/kaminoclone/ledger-service/internal/policy"
+	"github.com/kaminoclone/ledger-service/internal/statements"
+	"github.com/kaminoclone/shared/secrets"
 )
 
 // Build info (injetado no build)
@@ -38,19 +66,74 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+	DBMaxConns int
+
+	// Background workers
+	HoldSweepInterval    time.Duration
+	BalanceCheckInterval time.Duration
+	SnapshotInterval     time.Duration // 0 desativa os snapshots (consultas "saldo em" varrem as partições)
+
+	// Partições
+	PartitionMonthsAhead int
+	PartitionRetention   string // "schema.tabela=meses,..."
+	PartitionArchive     string
+	PartitionInterval    time.Duration // 0 mantém só a manutenção da inicialização
+
+	// Event store
+	EventSnapshotEvery int
 
 	// Redis
 	RedisHost     string
 	RedisPort     string
 	RedisPassword string
 
+	// Cache de saldos
+	BalanceCacheEnabled bool
+	BalanceCacheTTL     time.Duration
+
 	// Kafka
 	KafkaBrokers string
 	KafkaGroupID string
 
-	// Vault
-	VaultAddr  string
-	VaultToken string
+	// Outbox
+	OutboxRelayEnabled bool
+	OutboxPollInterval time.Duration
+	OutboxDLQTopic     string
+
+	// Vault. Valores vault://mount/caminho#chave em DB_PASSWORD e REDIS_PASSWORD
+	// são lidos do KV v2; com VaultDBRole, o banco usa credenciais dinâmicas.
+	VaultAddr    string
+	VaultToken   string
+	VaultDBMount string
+	VaultDBRole  string
+
+	// Autenticação. Tokens são verificados pelo JWKS ou pela chave pública local;
+	// AUTH_DISABLED só é aceito fora de produção, onde emissor e audiência são obrigatórios.
+	AuthJWKSURL      string
+	AuthKeyFile      string
+	AuthIssuer       string
+	AuthAudience     string
+	AuthJWKSCacheTTL time.Duration
+	AuthLeeway       time.Duration
+	AuthDisabled     bool
+
+	// CORS
+	CORSAllowedOrigins string // lista separada por vírgula
+
+	// Políticas OPA/Rego; vazio desativa a avaliação
+	PolicyBundlePath     string
+	PolicyQuery          string
+	PolicyReloadInterval time.Duration // 0 carrega o bundle só na inicialização
+
+	// Pagamentos agendados e recorrentes
+	ScheduledPaymentsEnabled  bool
+	ScheduledPaymentsInterval time.Duration
+	ScheduledPaymentsRetries  int
+	ScheduledPaymentsBackoff  time.Duration
+	ScheduledPaymentsAttempts int // execuções desfeitas por erro de infraestrutura antes de FAILED
+	PaymentsFeeAccountID      string
+	PaymentsSettlementAccount string
+	BusinessHolidays          string // feriados extras YYYY-MM-DD, separados por vírgula
 }
 
 func loadConfig() *Config {
@@ -64,17 +147,199 @@ func loadConfig() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "kamino_secure_password"),
 		DBName:     getEnv("DB_NAME", "kamino"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
+		DBMaxConns: getEnvInt("DB_MAX_CONNECTIONS", 25),
+
+		HoldSweepInterval:    time.Duration(getEnvInt("HOLD_SWEEP_INTERVAL_SECONDS", 60)) * time.Second,
+		BalanceCheckInterval: time.Duration(getEnvInt("BALANCE_CHECK_INTERVAL_SECONDS", 300)) * time.Second,
+		SnapshotInterval:     time.Duration(getEnvInt("BALANCE_SNAPSHOT_INTERVAL_SECONDS", 3600)) * time.Second,
+
+		PartitionMonthsAhead: getEnvInt("PARTITION_MONTHS_AHEAD", 3),
+		PartitionRetention:   getEnv("PARTITION_RETENTION", ""),
+		PartitionArchive:     getEnv("PARTITION_ARCHIVE_SCHEMA", "archive"),
+		PartitionInterval:    time.Duration(getEnvInt("PARTITION_MAINTENANCE_INTERVAL_SECONDS", 21600)) * time.Second,
+
+		EventSnapshotEvery: getEnvInt("EVENT_SNAPSHOT_EVERY", eventstore.DefaultSnapshotEvery),
 
 		RedisHost:     getEnv("REDIS_HOST", "localhost"),
 		RedisPort:     getEnv("REDIS_PORT", "6379"),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 
+		BalanceCacheEnabled: getEnv("BALANCE_CACHE_ENABLED", "true") == "true",
+		BalanceCacheTTL:     time.Duration(getEnvInt("BALANCE_CACHE_TTL_SECONDS", 30)) * time.Second,
+
 		KafkaBrokers: getEnv("KAFKA_BROKERS", "localhost:9092"),
 		KafkaGroupID: getEnv("KAFKA_GROUP_ID", "ledger-service"),
 
+		OutboxRelayEnabled: getEnv("OUTBOX_RELAY_ENABLED", "true") == "true",
+		OutboxPollInterval: time.Duration(getEnvInt("OUTBOX_POLL_INTERVAL_MS", 500)) * time.Millisecond,
+		OutboxDLQTopic:     getEnv("OUTBOX_DLQ_TOPIC", outbox.TopicDLQTransactions),
+
 		VaultAddr:  getEnv("VAULT_ADDR", "http://localhost:8200"),
 		VaultToken: getEnv("VAULT_TOKEN", ""),
+
+		VaultDBMount: getEnv("VAULT_DB_MOUNT", "database"),
+		VaultDBRole:  getEnv("VAULT_DB_ROLE", ""),
+
+		AuthJWKSURL:      getEnv("AUTH_JWKS_URL", ""),
+		AuthKeyFile:      getEnv("AUTH_PUBLIC_KEY_FILE", ""),
+		AuthIssuer:       getEnv("AUTH_ISSUER", ""),
+		AuthAudience:     getEnv("AUTH_AUDIENCE", ""),
+		AuthJWKSCacheTTL: time.Duration(getEnvInt("AUTH_JWKS_CACHE_TTL_SECONDS", 3600)) * time.Second,
+		AuthLeeway:       time.Duration(getEnvInt("AUTH_LEEWAY_SECONDS", 30)) * time.Second,
+		AuthDisabled:     getEnv("AUTH_DISABLED", "false") == "true",
+
+		CORSAllowedOrigins: getEnv("CORS_ALLOWED_ORIGINS", "http://localhost:3000"),
+
+		PolicyBundlePath:     getEnv("POLICY_BUNDLE_PATH", ""),
+		PolicyQuery:          getEnv("POLICY_QUERY", policy.DefaultQuery),
+		PolicyReloadInterval: time.Duration(getEnvInt("POLICY_RELOAD_INTERVAL_SECONDS", 30)) * time.Second,
+
+		ScheduledPaymentsEnabled:  getEnv("SCHEDULED_PAYMENTS_ENABLED", "true") == "true",
+		ScheduledPaymentsInterval: time.Duration(getEnvInt("SCHEDULED_PAYMENTS_INTERVAL_SECONDS", 60)) * time.Second,
+		ScheduledPaymentsRetries:  getEnvInt("SCHEDULED_PAYMENTS_MAX_RETRIES", 3),
+		ScheduledPaymentsBackoff:  time.Duration(getEnvInt("SCHEDULED_PAYMENTS_RETRY_DELAY_SECONDS", 14400)) * time.Second,
+		ScheduledPaymentsAttempts: getEnvInt("SCHEDULED_PAYMENTS_MAX_ATTEMPTS", 5),
+		PaymentsFeeAccountID:      getEnv("PAYMENTS_FEE_ACCOUNT_ID", ""),
+		PaymentsSettlementAccount: getEnv("PAYMENTS_SETTLEMENT_ACCOUNT_ID", ""),
+		BusinessHolidays:          getEnv("BUSINESS_HOLIDAYS", ""),
+	}
+}
+
+// dsn monta a string de conexão com as credenciais vigentes
+func (c *Config) dsn(creds secrets.Credentials) string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		c.DBHost, c.DBPort, quoteDSN(creds.Username),
+		quoteDSN(creds.Password), c.DBName, c.DBSSLMode,
+	)
+}
+
+// quoteDSN escapa um valor para o formato key=value do lib/pq
+func quoteDSN(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// resolveSecrets troca referências ao Vault pelos valores e recusa a senha
+// padrão do banco em produção
+func resolveSecrets(ctx context.Context, config *Config, vault *secrets.VaultClient) error {
+	loader := secrets.NewLoader(vault)
+	var err error
+	if config.DBPassword, err = loader.Resolve(ctx, config.DBPassword); err != nil {
+		return fmt.Errorf("DB_PASSWORD: %w", err)
+	}
+	if config.RedisPassword, err = loader.Resolve(ctx, config.RedisPassword); err != nil {
+		return fmt.Errorf("REDIS_PASSWORD: %w", err)
+	}
+	if config.VaultDBRole == "" {
+		return secrets.RequireNonDefaultPassword(config.Env, config.DBPassword)
+	}
+	return nil
+}
+
+// newVerifier escolhe a fonte das chaves; nil significa autenticação desativada
+func newVerifier(config *Config, logger *zap.SugaredLogger) (*auth.Verifier, error) {
+	var keys auth.KeySource
+	switch {
+	case config.AuthJWKSURL != "":
+		jwks := auth.NewJWKS(config.AuthJWKSURL, config.AuthJWKSCacheTTL)
+		jwks.OnSkippedKey(func(kid string, err error) {
+			logger.Warnw("Ignoring unusable JWKS key", "kid", kid, "error", err)
+		})
+		keys = jwks
+	case config.AuthKeyFile != "":
+		key, err := auth.LoadKeyFile(config.AuthKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_PUBLIC_KEY_FILE: %w", err)
+		}
+		keys = key
+	case config.AuthDisabled && config.Env != "production":
+		return nil, nil
+	case config.AuthDisabled:
+		return nil, errors.New("AUTH_DISABLED is not allowed in production")
+	default:
+		return nil, errors.New("AUTH_JWKS_URL or AUTH_PUBLIC_KEY_FILE is required")
+	}
+	// Sem emissor e audiência, qualquer token assinado pelo provedor (inclusive
+	// os emitidos para outros serviços) seria aceito
+	if config.Env == "production" && (config.AuthIssuer == "" || config.AuthAudience == "") {
+		return nil, errors.New("AUTH_ISSUER and AUTH_AUDIENCE are required in production")
+	}
+	return auth.NewVerifier(keys, config.AuthIssuer, config.AuthAudience, config.AuthLeeway), nil
+}
+
+// openDatabase resolve os segredos e abre o pool do PostgreSQL. Com
+// VAULT_DB_ROLE, devolve também as credenciais dinâmicas, que quem abriu
+// precisa manter renovadas (DynamicCredentials.Run).
+func openDatabase(config *Config, logger *zap.SugaredLogger) (*sql.DB, *secrets.DynamicCredentials, error) {
+	var vault *secrets.VaultClient
+	if config.VaultToken != "" {
+		vault = secrets.NewVaultClient(config.VaultAddr, config.VaultToken)
+	}
+
+	secretsCtx, cancelSecrets := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancelSecrets()
+	if err := resolveSecrets(secretsCtx, config, vault); err != nil {
+		return nil, nil, err
+	}
+
+	var source secrets.CredentialSource = secrets.StaticCredentials{Username: config.DBUser, Password: config.DBPassword}
+	var dynamic *secrets.DynamicCredentials
+	if config.VaultDBRole != "" {
+		if vault == nil {
+			return nil, nil, errors.New("VAULT_DB_ROLE requires VAULT_TOKEN")
+		}
+		var err error
+		dynamic, err = secrets.NewDynamicCredentials(secretsCtx, vault, config.VaultDBMount, config.VaultDBRole, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		source = dynamic
 	}
+
+	// O connector lê as credenciais vigentes a cada conexão nova
+	db := sql.OpenDB(secrets.NewConnector(source, config.dsn, func(dsn string) (driver.Connector, error) {
+		return pq.NewConnector(dsn)
+	}))
+
+	// Configurar pool de conexões
+	maxIdle := config.DBMaxConns / 5
+	db.SetMaxOpenConns(config.DBMaxConns)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	// Após uma rotação, conexões ociosas com o usuário antigo são descartadas
+	if dynamic != nil {
+		dynamic.OnChange(func(secrets.Credentials) {
+			secrets.FlushIdle(db, maxIdle)
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	return db, dynamic, nil
+}
+
+// newBalanceCache conecta ao Redis do cache de saldos; nil com o cache desativado.
+// Redis fora do ar não impede a subida: as leituras caem no banco.
+func newBalanceCache(config *Config, logger *zap.SugaredLogger) (*redis.Client, *cache.BalanceCache) {
+	if !config.BalanceCacheEnabled {
+		return nil, nil
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.RedisHost + ":" + config.RedisPort,
+		Password: config.RedisPassword,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		logger.Warnw("Redis unavailable, balance reads will fall back to database", "error", err)
+	}
+	return client, cache.NewBalanceCache(client, config.BalanceCacheTTL)
 }
 
 func getEnv(key, defaultValue string) string {
@@ -84,6 +349,180 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// ============================================================================
+// APLICAÇÃO
+// ============================================================================
+
+type App struct {
+	config      *Config
+	db          *sql.DB
+	ledger      *ledger.Store
+	accounts    *accounts.Store
+	events      *eventstore.Store
+	balances    *balances.Store
+	holds       *holds.Store
+	idempotency *idempotency.Store
+	integrity   *integrity.Checker
+	statements  *statements.Store
+	limits      *limits.Store
+	redis       *redis.Client       // nil com o cache de saldos desativado
+	cache       *cache.BalanceCache // nil com o cache de saldos desativado
+	balanceRead *cache.Reader       // nil com o cache de saldos desativado
+	health      *health.Checker
+	credentials *secrets.DynamicCredentials // nil com credenciais estáticas
+	verifier    *auth.Verifier              // nil com a autenticação desativada
+	policy      *policy.Engine              // nil sem POLICY_BUNDLE_PATH
+	partitions  *partitions.Manager
+	payments    *payments.Executor // nil com SCHEDULED_PAYMENTS_ENABLED=false
+	logger      *zap.SugaredLogger
+}
+
+func NewApp(config *Config, logger *zap.SugaredLogger) (*App, error) {
+	// Sem o sweeper, bloqueios vencidos nunca devolvem o disponível
+	if config.HoldSweepInterval <= 0 {
+		return nil, errors.New("HOLD_SWEEP_INTERVAL_SECONDS must be positive")
+	}
+
+	verifier, err := newVerifier(config, logger)
+	if err != nil {
+		return nil, err
+	}
+	if verifier == nil {
+		logger.Warnw("Authentication disabled, every request runs as ADMIN", "env", config.Env)
+	}
+
+	var policyEngine *policy.Engine
+	if config.PolicyBundlePath != "" {
+		policyCtx, cancelPolicy := context.WithTimeout(context.Background(), 30*time.Second)
+		policyEngine, err = policy.NewEngine(policyCtx, config.PolicyBundlePath, config.PolicyQuery, config.PolicyReloadInterval, logger)
+		cancelPolicy()
+		if err != nil {
+			return nil, err
+		}
+		logger.Infow("Authorization policy loaded", "path", config.PolicyBundlePath, "revision", policyEngine.Revision())
+	}
+
+	db, dynamic, err := openDatabase(config, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := partitions.ApplyRetention(partitions.DefaultTables(), config.PartitionRetention)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	ledgerStore := ledger.NewStore(db)
+
+	var executor *payments.Executor
+	if config.ScheduledPaymentsEnabled {
+		if config.ScheduledPaymentsInterval <= 0 {
+			db.Close()
+			return nil, errors.New("SCHEDULED_PAYMENTS_INTERVAL_SECONDS must be positive")
+		}
+		calendar, err := payments.NewCalendar(strings.Split(config.BusinessHolidays, ","))
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("BUSINESS_HOLIDAYS: %w", err)
+		}
+		executor = payments.NewExecutor(db, ledgerStore, calendar, payments.Config{
+			Interval:            config.ScheduledPaymentsInterval,
+			MaxRetries:          config.ScheduledPaymentsRetries,
+			RetryDelay:          config.ScheduledPaymentsBackoff,
+			MaxAttempts:         config.ScheduledPaymentsAttempts,
+			FeeAccountID:        config.PaymentsFeeAccountID,
+			SettlementAccountID: config.PaymentsSettlementAccount,
+		}, logger)
+	}
+	eventStore := eventstore.NewStore(db, config.EventSnapshotEvery)
+	balanceStore := balances.NewStore(db)
+
+	// O cache é opcional: sem Redis, as leituras vão direto ao PostgreSQL
+	redisClient, balanceCache := newBalanceCache(config, logger)
+	var balanceRead *cache.Reader
+	if balanceCache != nil {
+		balanceRead = cache.NewReader(balanceCache, balanceStore, logger)
+	}
+
+	// Sem o banco a instância não atende; Redis e Kafka degradam (cache e outbox têm fallback)
+	checks := []health.Check{{Name: "database", Critical: true, Probe: health.PostgresProbe(db)}}
+	if redisClient != nil {
+		checks = append(checks, health.Check{Name: "redis", Probe: health.RedisProbe(redisClient)})
+	}
+	if config.OutboxRelayEnabled {
+		checks = append(checks, health.Check{Name: "kafka", Probe: health.KafkaProbe(strings.Split(config.KafkaBrokers, ","))})
+	}
+
+	return &App{
+		config:      config,
+		db:          db,
+		ledger:      ledgerStore,
+		accounts:    accounts.NewStore(db, eventStore),
+		events:      eventStore,
+		balances:    balanceStore,
+		holds:       holds.NewStore(db, ledgerStore),
+		idempotency: idempotency.NewStore(db),
+		integrity:   integrity.NewChecker(db),
+		statements:  statements.NewStore(db),
+		limits:      limits.NewStore(db),
+		redis:       redisClient,
+		cache:       balanceCache,
+		balanceRead: balanceRead,
+		health:      health.NewChecker(checks...),
+		credentials: dynamic,
+		verifier:    verifier,
+		policy:      policyEngine,
+		partitions: partitions.NewManager(db, partitions.Config{
+			Tables:        tables,
+			MonthsAhead:   config.PartitionMonthsAhead,
+			ArchiveSchema: config.PartitionArchive,
+			Interval:      config.PartitionInterval,
+		}, logger),
+		payments: executor,
+		logger:   logger,
+	}, nil
+}
+
+func (a *App) Close() error {
+	if a.redis != nil {
+		a.redis.Close()
+	}
+	return a.db.Close()
+}
+
+// currentBalance lê o saldo atual pelo cache quando habilitado
+func (a *App) currentBalance(ctx context.Context, accountID string) (*balances.Balance, error) {
+	if a.balanceRead != nil {
+		return a.balanceRead.Current(ctx, accountID)
+	}
+	return a.balances.Current(ctx, accountID)
+}
+
+// refreshBalances atualiza o cache das contas afetadas após o commit
+func (a *App) refreshBalances(ctx context.Context, accountIDs ...string) {
+	if a.balanceRead != nil && len(accountIDs) > 0 {
+		a.balanceRead.Refresh(ctx, accountIDs...)
+	}
+}
+
+func entryAccounts(txn *ledger.Transaction) []string {
+	ids := make([]string, 0, len(txn.Entries))
+	for _, e := range txn.Entries {
+		ids = append(ids, e.AccountID)
+	}
+	return ids
+}
+
 func main() {
 	// Inicializar logger
 	logger, _ := zap.NewProduction()
@@ -101,6 +540,39 @@ func main() {
 	// Carregar configuração
 	cfg := loadConfig()
 
+	// Subcomandos: serve (padrão) e rebuild-projections. O rebuild abre só o
+	// que usa (banco, event store e cache), sem autenticação, políticas ou Kafka.
+	command := "serve"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	switch command {
+	case "serve":
+	case "rebuild-projections":
+		if err := runRebuildProjections(cfg, sugar, os.Args[2:], os.Stdout); err != nil {
+			sugar.Errorw("Projection rebuild failed", "error", err)
+			logger.Sync()
+			os.Exit(1)
+		}
+		return
+	default:
+		sugar.Fatalw("Unknown command", "command", command)
+	}
+
+	// Criar aplicação
+	app, err := NewApp(cfg, sugar)
+	if err != nil {
+		sugar.Fatalw("Failed to initialize application", "error", err)
+	}
+	defer app.Close()
+
+	// Garantir partições do mês corrente e dos próximos antes de aceitar lançamentos
+	maintainCtx, cancelMaintain := context.WithTimeout(context.Background(), time.Minute)
+	if err := app.partitions.Maintain(maintainCtx, time.Now()); err != nil {
+		sugar.Errorw("Partition maintenance failed", "error", err)
+	}
+	cancelMaintain()
+
 	// Configurar Gin
 	if cfg.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -112,41 +584,82 @@ func main() {
 	router.Use(gin.Recovery())
 	router.Use(RequestIDMiddleware())
 	router.Use(LoggingMiddleware(sugar))
-	router.Use(CORSMiddleware())
+	router.Use(CORSMiddleware(strings.Split(cfg.CORSAllowedOrigins, ",")))
 
 	// Health checks
+	// Liveness (/health, /live) não depende de nada externo; readiness testa as dependências
 	router.GET("/health", healthHandler)
-	router.GET("/ready", readyHandler)
+	router.GET("/live", healthHandler)
+	router.GET("/ready", app.readyHandler)
 
 	// Metrics
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API v1
+	// Todas as rotas exigem bearer token. Leituras de conta checam a posse no handler;
+	// abertura (plano de contas e limites), status, lançamentos, bloqueios e o
+	// ledger são operações de ADMIN. Com POLICY_BUNDLE_PATH, a política Rego
+	// também precisa permitir.
+	adminOnly := RequireRole(auth.RoleAdmin)
+	writers := RequireRole(auth.RoleAdmin, auth.RoleUser)
+
 	v1 := router.Group("/v1")
+	v1.Use(AuthMiddleware(app.verifier))
+	if app.policy != nil {
+		v1.Use(app.policyMiddleware())
+	}
 	{
 		// Accounts
-		accounts := v1.Group("/accounts")
+		accountRoutes := v1.Group("/accounts")
 		{
-			accounts.GET("", listAccountsHandler)
-			accounts.POST("", createAccountHandler)
-			accounts.GET("/:id", getAccountHandler)
-			accounts.GET("/:id/balance", getBalanceHandler)
-			accounts.GET("/:id/transactions", listTransactionsHandler)
+			accountRoutes.GET("", app.listAccountsHandler)
+			accountRoutes.POST("", adminOnly, app.createAccountHandler)
+			accountRoutes.GET("/:id", app.getAccountHandler)
+			accountRoutes.PATCH("/:id/status", adminOnly, app.updateAccountStatusHandler)
+			accountRoutes.GET("/:id/history", app.accountHistoryHandler)
+			accountRoutes.POST("/:id/activate", adminOnly, app.accountOperationHandler(accounts.OpActivate))
+			accountRoutes.POST("/:id/block", adminOnly, app.accountOperationHandler(accounts.OpBlock))
+			accountRoutes.POST("/:id/unblock", adminOnly, app.accountOperationHandler(accounts.OpUnblock))
+			accountRoutes.POST("/:id/freeze", adminOnly, app.accountOperationHandler(accounts.OpFreeze))
+			accountRoutes.POST("/:id/unfreeze", adminOnly, app.accountOperationHandler(accounts.OpUnfreeze))
+			accountRoutes.POST("/:id/close", adminOnly, app.accountOperationHandler(accounts.OpClose))
+			accountRoutes.GET("/:id/holds", app.listAccountHoldsHandler)
+			accountRoutes.POST("/:id/holds", adminOnly, app.placeHoldHandler)
+			accountRoutes.GET("/:id/balance", app.getBalanceHandler)
+			accountRoutes.GET("/:id/transactions", app.accountStatementHandler)
+			accountRoutes.GET("/:id/limits", app.getLimitsHandler)
+			accountRoutes.GET("/:id/limits/requests", app.listLimitRequestsHandler)
+			accountRoutes.POST("/:id/limits/requests", writers, app.requestLimitIncreaseHandler)
+		}
+
+		// Pedidos de aumento de limite
+		limitRequests := v1.Group("/limit-requests", adminOnly)
+		{
+			limitRequests.POST("/:id/approve", app.decideLimitRequestHandler(true))
+			limitRequests.POST("/:id/reject", app.decideLimitRequestHandler(false))
 		}
 
 		// Transactions
 		transactions := v1.Group("/transactions")
 		{
-			transactions.POST("", createTransactionHandler)
-			transactions.GET("/:id", getTransactionHandler)
-			transactions.POST("/:id/reverse", reverseTransactionHandler)
+			transactions.POST("", adminOnly, app.createTransactionHandler)
+			transactions.GET("/:id", app.getTransactionHandler)
+			transactions.POST("/:id/reverse", adminOnly, app.reverseTransactionHandler)
+		}
+
+		// Balance holds
+		holdRoutes := v1.Group("/holds")
+		{
+			holdRoutes.GET("/:id", app.getHoldHandler)
+			holdRoutes.POST("/:id/capture", adminOnly, app.captureHoldHandler)
+			holdRoutes.POST("/:id/release", adminOnly, app.releaseHoldHandler)
 		}
 
 		// Ledger entries
-		ledger := v1.Group("/ledger")
+		ledger := v1.Group("/ledger", adminOnly)
 		{
 			ledger.GET("/entries", listEntriesHandler)
-			ledger.GET("/balance-check", balanceCheckHandler)
+			ledger.GET("/balance-check", app.balanceCheckHandler)
 		}
 	}
 
@@ -159,6 +672,43 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Workers em background: bloqueios, integridade, snapshots de saldo, partições,
+	// pagamentos agendados e outbox
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	// Expirações e pagamentos agendados mudam saldos fora dos handlers
+	sweeper := holds.NewSweeper(app.holds, cfg.HoldSweepInterval, sugar)
+	sweeper.OnExpire(app.refreshBalances)
+	go sweeper.Run(workerCtx)
+	if cfg.SnapshotInterval > 0 {
+		go balances.NewSnapshotter(app.balances, cfg.SnapshotInterval, sugar).Run(workerCtx)
+	}
+	if cfg.PartitionInterval > 0 {
+		go app.partitions.Run(workerCtx)
+	}
+	if app.credentials != nil {
+		go app.credentials.Run(workerCtx)
+	}
+	if app.policy != nil && cfg.PolicyReloadInterval > 0 {
+		go app.policy.Run(workerCtx)
+	}
+	if app.payments != nil {
+		app.payments.OnPosted(app.refreshBalances)
+		go app.payments.Run(workerCtx)
+	}
+	if cfg.BalanceCheckInterval > 0 {
+		go integrity.NewMonitor(app.integrity, cfg.BalanceCheckInterval, sugar).Run(workerCtx)
+	}
+	if cfg.OutboxRelayEnabled {
+		publisher := outbox.NewKafkaPublisher(strings.Split(cfg.KafkaBrokers, ","))
+		defer publisher.Close()
+
+		relayConfig := outbox.DefaultRelayConfig()
+		relayConfig.PollInterval = cfg.OutboxPollInterval
+		relayConfig.DLQTopic = cfg.OutboxDLQTopic
+		go outbox.NewRelay(app.db, publisher, relayConfig, sugar).Run(workerCtx)
+	}
+
 	// Canal para shutdown graceful
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -174,6 +724,7 @@ func main() {
 	// Aguardar sinal de shutdown
 	<-quit
 	sugar.Info("Shutting down server...")
+	stopWorkers()
 
 	// Contexto com timeout para shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -224,11 +775,23 @@ func LoggingMiddleware(logger *zap.SugaredLogger) gin.HandlerFunc {
 	}
 }
 
-func CORSMiddleware() gin.HandlerFunc {
+// CORSMiddleware só devolve Access-Control-Allow-Origin para as origens permitidas
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			allowed[origin] = true
+		}
+	}
+
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Vary", "Origin")
+		if origin := c.GetHeader("Origin"); allowed[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID, X-Idempotency-Key")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID, X-Idempotency-Key, X-Correlation-ID")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -240,8 +803,64 @@ func CORSMiddleware() gin.HandlerFunc {
 	}
 }
 
+// principalKey guarda o principal autenticado no contexto do gin
+const principalKey = "principal"
+
+// developmentPrincipal atende as requisições com a autenticação desativada
+var developmentPrincipal = &auth.Principal{Subject: "development", Role: auth.RoleAdmin}
+
+// AuthMiddleware exige um bearer token válido e guarda o principal no contexto.
+// Com verifier nil (AUTH_DISABLED fora de produção), toda requisição é ADMIN.
+func AuthMiddleware(verifier *auth.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Set(principalKey, developmentPrincipal)
+			c.Next()
+			return
+		}
+
+		token, ok := auth.BearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			c.Header("WWW-Authenticate", `Bearer realm="ledger-service"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": auth.ErrUnauthenticated.Error()})
+			return
+		}
+		p, err := verifier.Verify(c.Request.Context(), token)
+		switch {
+		case errors.Is(err, auth.ErrUnknownRole):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.Header("WWW-Authenticate", `Bearer realm="ledger-service", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": auth.ErrUnauthenticated.Error()})
+			return
+		}
+		c.Set(principalKey, p)
+		c.Next()
+	}
+}
+
+// RequireRole recusa com 403 quem não tem um dos papéis
+func RequireRole(roles ...auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !principal(c).HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": auth.ErrForbidden.Error()})
+			return
+		}
+		c.Next()
+	}
+}
+
+// principal devolve quem fez a requisição; rotas fora de /v1 não têm principal
+func principal(c *gin.Context) *auth.Principal {
+	if p, ok := c.Get(principalKey); ok {
+		return p.(*auth.Principal)
+	}
+	return &auth.Principal{}
+}
+
 // ============================================================================
-// HANDLERS (Placeholder implementations)
+// HANDLERS
 // ============================================================================
 
 func healthHandler(c *gin.Context) {
@@ -252,90 +871,604 @@ func healthHandler(c *gin.Context) {
 	})
 }
 
-func readyHandler(c *gin.Context) {
-	// TODO: Verificar conexões com DB, Redis, Kafka
-	c.JSON(http.StatusOK, gin.H{
-		"status": "ready",
-		"checks": gin.H{
-			"database": "ok",
-			"redis":    "ok",
-			"kafka":    "ok",
-		},
+// readyHandler responde 503 quando uma dependência crítica está fora do ar
+func (a *App) readyHandler(c *gin.Context) {
+	report := a.health.Run(c.Request.Context())
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// listAccountsHandler lista contas com filtros por usuário, status e moeda.
+// A paginação usa o cursor opaco devolvido em next_cursor.
+// Fora do papel ADMIN, a lista fica restrita às contas do próprio usuário.
+func (a *App) listAccountsHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+		return
+	}
+
+	userID := c.Query("user_id")
+	if p := principal(c); !p.HasRole(auth.RoleAdmin) {
+		if !ledger.IsUUID(p.UserID) || (userID != "" && !p.CanAccessOwner(userID, false)) {
+			a.respondError(c, auth.ErrForbidden)
+			return
+		}
+		userID = p.UserID
+	}
+
+	page, err := a.accounts.List(c.Request.Context(), accounts.ListFilter{
+		UserID:   userID,
+		Status:   accounts.Status(strings.ToUpper(c.Query("status"))),
+		Currency: c.Query("currency"),
+		Limit:    limit,
+		Cursor:   c.Query("cursor"),
 	})
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (a *App) createAccountHandler(c *gin.Context) {
+	var req accounts.CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	account, err := a.accounts.Create(c.Request.Context(), req)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, account)
 }
 
-func listAccountsHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"accounts": []interface{}{}})
+func (a *App) getAccountHandler(c *gin.Context) {
+	account, err := a.authorizeAccount(c, c.Param("id"), false)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
 }
 
-func createAccountHandler(c *gin.Context) {
-	c.JSON(http.StatusCreated, gin.H{"message": "Account created"})
+func (a *App) accountHistoryHandler(c *gin.Context) {
+	if _, err := a.authorizeAccount(c, c.Param("id"), false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	events, err := a.accounts.History(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"account_id": c.Param("id"), "events": events})
 }
 
-func getAccountHandler(c *gin.Context) {
-	id := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{"id": id})
+func (a *App) updateAccountStatusHandler(c *gin.Context) {
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	op := accounts.SetStatusOperation(accounts.Status(strings.ToUpper(req.Status)))
+	account, err := a.accounts.Transition(c.Request.Context(), c.Param("id"), op, requestActor(c), req.Reason)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+// accountOperationHandler expõe uma operação de status (block, freeze, close...) como endpoint
+func (a *App) accountOperationHandler(op accounts.Operation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Reason string `json:"reason" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+			return
+		}
+
+		account, err := a.accounts.Transition(c.Request.Context(), c.Param("id"), op, requestActor(c), req.Reason)
+		if err != nil {
+			a.respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, account)
+	}
 }
 
-func getBalanceHandler(c *gin.Context) {
+// requestActor identifica quem fez a requisição para a trilha de auditoria
+func requestActor(c *gin.Context) accounts.Actor {
+	p := principal(c)
+	return accounts.Actor{
+		Type:          p.ActorType(),
+		ID:            p.UserID,
+		IP:            c.ClientIP(),
+		UserAgent:     c.Request.UserAgent(),
+		CorrelationID: c.GetHeader("X-Correlation-ID"),
+	}
+}
+
+// getBalanceHandler devolve o saldo atual ou, com as_of (RFC 3339 ou YYYY-MM-DD),
+// o saldo histórico reconstruído a partir dos snapshots e lançamentos.
+func (a *App) getBalanceHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	id := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{
-		"account_id":        id,
-		"balance":           0,
-		"available_balance": 0,
-		"currency":          "BRL",
-	})
+	if _, err := a.authorizeAccount(c, id, false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+
+	var balance *balances.Balance
+	var err error
+	if asOf := c.Query("as_of"); asOf != "" {
+		var t time.Time
+		if t, err = balances.ParseAsOf(asOf, time.Now()); err == nil {
+			balance, err = a.balances.AsOf(ctx, id, t)
+		}
+	} else {
+		balance, err = a.currentBalance(ctx, id)
+	}
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, balance)
+}
+
+// accountStatementHandler devolve o extrato da conta entre from e to (YYYY-MM-DD).
+// format=csv|ofx|pdf exporta o mesmo extrato como anexo; o padrão é JSON.
+func (a *App) accountStatementHandler(c *gin.Context) {
+	if _, err := a.authorizeAccount(c, c.Param("id"), false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	format, err := statements.ParseFormat(c.Query("format"))
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	from, to, err := statements.ParsePeriod(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+
+	st, err := a.statements.Build(c.Request.Context(), c.Param("id"), from, to)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+
+	var write func(io.Writer, *statements.Statement) error
+	switch format {
+	case statements.FormatCSV:
+		write = statements.WriteCSV
+	case statements.FormatOFX:
+		write = statements.WriteOFX
+	case statements.FormatPDF:
+		write = statements.WritePDF
+	default:
+		c.JSON(http.StatusOK, st)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := write(&buf, st); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, st.Filename(format)))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
 }
 
-func listTransactionsHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"transactions": []interface{}{}})
+// createTransactionHandler lança uma transação double-entry no ledger.
+// A X-Idempotency-Key vira o reference_id da transação; um retry com o mesmo
+// payload recebe a resposta original e um payload diferente recebe 422.
+func (a *App) createTransactionHandler(c *gin.Context) {
+	a.withIdempotency(c, c.FullPath(), func(body []byte, key string) (idempotentWrite, error) {
+		var req ledger.PostingRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		req.ReferenceID = key
+		return func(ctx context.Context, tx *sql.Tx) (*idempotency.Response, []string, error) {
+			txn, err := a.ledger.PostTx(ctx, tx, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			resp, err := jsonResponse(http.StatusCreated, txn.ID, txn)
+			return resp, entryAccounts(txn), err
+		}, nil
+	})
 }
 
-func createTransactionHandler(c *gin.Context) {
-	// Verificar idempotency key
-	idempotencyKey := c.GetHeader("X-Idempotency-Key")
-	if idempotencyKey == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Idempotency-Key header is required"})
+// getTransactionHandler devolve a transação; fora do papel ADMIN, ao menos
+// uma das contas lançadas precisa ser do usuário
+func (a *App) getTransactionHandler(c *gin.Context) {
+	txn, err := a.ledger.GetTransaction(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		a.respondError(c, err)
 		return
 	}
+	if !principal(c).HasRole(auth.RoleAdmin) {
+		if err := a.authorizeAnyAccount(c, entryAccounts(txn)); err != nil {
+			a.respondError(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, txn)
+}
 
-	c.JSON(http.StatusAccepted, gin.H{
-		"transaction_id":  generateRequestID(),
-		"idempotency_key": idempotencyKey,
-		"status":          "PENDING",
+// reverseTransactionHandler estorna total ou parcialmente uma transação COMPLETED.
+// Exige X-Idempotency-Key, usada como reference_id do estorno. A transação
+// original (no caminho) faz parte do fingerprint.
+func (a *App) reverseTransactionHandler(c *gin.Context) {
+	originalID := c.Param("id")
+	a.withIdempotency(c, c.Request.URL.Path, func(body []byte, key string) (idempotentWrite, error) {
+		var req ledger.ReversalRequest
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &req); err != nil {
+				return nil, err
+			}
+		}
+		req.ReferenceID = key
+		return func(ctx context.Context, tx *sql.Tx) (*idempotency.Response, []string, error) {
+			reversal, err := a.ledger.ReverseTx(ctx, tx, originalID, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			resp, err := jsonResponse(http.StatusCreated, reversal.ID, reversal)
+			return resp, entryAccounts(reversal), err
+		}, nil
 	})
 }
 
-func getTransactionHandler(c *gin.Context) {
-	id := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{"id": id, "status": "COMPLETED"})
+// placeHoldHandler reserva saldo disponível (autorização de cartão, PIX agendado)
+func (a *App) placeHoldHandler(c *gin.Context) {
+	var req holds.PlaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	req.AccountID = c.Param("id")
+
+	hold, err := a.holds.Place(c.Request.Context(), req)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	a.refreshBalances(c.Request.Context(), hold.AccountID)
+	c.JSON(http.StatusCreated, hold)
 }
 
-func reverseTransactionHandler(c *gin.Context) {
-	id := c.Param("id")
-	c.JSON(http.StatusAccepted, gin.H{
-		"original_transaction_id": id,
-		"reversal_transaction_id": generateRequestID(),
-		"status":                  "PENDING",
+func (a *App) listAccountHoldsHandler(c *gin.Context) {
+	if _, err := a.authorizeAccount(c, c.Param("id"), false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	status := holds.Status(strings.ToUpper(c.Query("status")))
+	list, err := a.holds.ListByAccount(c.Request.Context(), c.Param("id"), status)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"holds": list})
+}
+
+func (a *App) getHoldHandler(c *gin.Context) {
+	hold, err := a.holds.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	if _, err := a.authorizeAccount(c, hold.AccountID, false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, hold)
+}
+
+// captureHoldHandler converte o bloqueio em transação.
+// Exige X-Idempotency-Key, usada como reference_id da transação gerada.
+func (a *App) captureHoldHandler(c *gin.Context) {
+	holdID := c.Param("id")
+	a.withIdempotency(c, c.Request.URL.Path, func(body []byte, key string) (idempotentWrite, error) {
+		var req holds.CaptureRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, err
+		}
+		req.ReferenceID = key
+		return func(ctx context.Context, tx *sql.Tx) (*idempotency.Response, []string, error) {
+			result, err := a.holds.CaptureTx(ctx, tx, holdID, req)
+			if err != nil {
+				return nil, nil, err
+			}
+			resp, err := jsonResponse(http.StatusCreated, result.Transaction.ID, result)
+			return resp, entryAccounts(result.Transaction), err
+		}, nil
 	})
 }
 
+func (a *App) releaseHoldHandler(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	hold, err := a.holds.Release(c.Request.Context(), c.Param("id"), req.Reason)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	a.refreshBalances(c.Request.Context(), hold.AccountID)
+	c.JSON(http.StatusOK, hold)
+}
+
+// getLimitsHandler devolve limites, consumo nas últimas 24 horas e aumentos vigentes
+func (a *App) getLimitsHandler(c *gin.Context) {
+	if _, err := a.authorizeAccount(c, c.Param("id"), false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	usage, err := a.limits.Usage(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+func (a *App) listLimitRequestsHandler(c *gin.Context) {
+	if _, err := a.authorizeAccount(c, c.Param("id"), false); err != nil {
+		a.respondError(c, err)
+		return
+	}
+	list, err := a.limits.ListByAccount(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"requests": list})
+}
+
+// requestLimitIncreaseHandler registra um pedido de aumento temporário, que
+// só vale depois de aprovado por um ADMIN
+func (a *App) requestLimitIncreaseHandler(c *gin.Context) {
+	var req limits.IncreaseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	if _, err := a.authorizeAccount(c, c.Param("id"), true); err != nil {
+		a.respondError(c, err)
+		return
+	}
+
+	request, err := a.limits.RequestIncrease(c.Request.Context(), c.Param("id"), req, principal(c).UserID)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, request)
+}
+
+// decideLimitRequestHandler aprova ou rejeita um pedido de aumento de limite
+func (a *App) decideLimitRequestHandler(approve bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+			return
+		}
+
+		request, err := a.limits.Decide(c.Request.Context(), c.Param("id"), approve, principal(c).UserID, req.Reason)
+		if err != nil {
+			a.respondError(c, err)
+			return
+		}
+		a.logger.Infow("Limit request decided",
+			"request_id", c.GetString("request_id"),
+			"limit_request_id", request.ID,
+			"account_id", request.AccountID,
+			"status", request.Status,
+			"decided_by", principal(c).UserID,
+		)
+		c.JSON(http.StatusOK, request)
+	}
+}
+
 func listEntriesHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"entries": []interface{}{}})
 }
 
-func balanceCheckHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"balanced":     true,
-		"total_debits": 0,
-		"total_credits": 0,
-	})
+// balanceCheckHandler verifica débito = crédito e saldo = lançamentos.
+// Aceita from/to (YYYY-MM-DD) ou partition (YYYY-MM); sem escopo verifica o ledger inteiro.
+func (a *App) balanceCheckHandler(c *gin.Context) {
+	scope, err := integrity.ParseScope(c.Query("from"), c.Query("to"), c.Query("partition"))
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+
+	report, err := a.integrity.Check(c.Request.Context(), scope)
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	if !report.Balanced {
+		a.logger.Errorw("Ledger balance check found inconsistencies",
+			"request_id", c.GetString("request_id"),
+			"unbalanced_transactions", len(report.UnbalancedTransactions),
+			"account_drifts", len(report.AccountDrifts),
+		)
+	}
+	c.JSON(http.StatusOK, report)
 }
 
 // ============================================================================
 // HELPERS
 // ============================================================================
 
+// respondError traduz erros de negócio em respostas HTTP
+func (a *App) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ledger.ErrInvalidPosting), errors.Is(err, ledger.ErrUnbalanced),
+		errors.Is(err, ledger.ErrInvalidAmount), errors.Is(err, accounts.ErrInvalidRequest),
+		errors.Is(err, accounts.ErrInvalidCursor), errors.Is(err, accounts.ErrReasonRequired),
+		errors.Is(err, holds.ErrInvalidRequest), errors.Is(err, integrity.ErrInvalidScope),
+		errors.Is(err, statements.ErrInvalidPeriod), errors.Is(err, statements.ErrUnsupportedFormat),
+		errors.Is(err, balances.ErrInvalidAsOf), errors.Is(err, limits.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ledger.ErrNotFound), errors.Is(err, ledger.ErrAccountNotFound),
+		errors.Is(err, accounts.ErrNotFound), errors.Is(err, holds.ErrNotFound),
+		errors.Is(err, statements.ErrAccountNotFound), errors.Is(err, balances.ErrAccountNotFound),
+		errors.Is(err, limits.ErrNotFound), errors.Is(err, limits.ErrAccountNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, accounts.ErrChartNotFound), errors.Is(err, accounts.ErrChartNotPostable),
+		errors.Is(err, accounts.ErrUserNotFound), errors.Is(err, accounts.ErrCurrencyMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ledger.ErrDuplicateRef), errors.Is(err, ledger.ErrAlreadyReversed),
+		errors.Is(err, accounts.ErrInvalidTransition), errors.Is(err, accounts.ErrNonZeroBalance),
+		errors.Is(err, accounts.ErrActiveHolds), errors.Is(err, holds.ErrDuplicateReference),
+		errors.Is(err, holds.ErrNotActive), errors.Is(err, eventstore.ErrConcurrencyConflict),
+		errors.Is(err, limits.ErrNotPending), errors.Is(err, limits.ErrSelfApproval):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, auth.ErrForbidden), errors.Is(err, auth.ErrUnknownRole):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, idempotency.ErrFingerprintMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "IDEMPOTENCY_KEY_REUSED"})
+	case errors.Is(err, ledger.ErrInsufficientFunds), errors.Is(err, ledger.ErrCurrencyMismatch),
+		errors.Is(err, ledger.ErrNotReversible), errors.Is(err, ledger.ErrReversalExceeded),
+		errors.Is(err, ledger.ErrAccountNotActive), errors.Is(err, holds.ErrExpired),
+		errors.Is(err, holds.ErrCaptureExceeded), errors.Is(err, statements.ErrTooManyLines):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, ledger.ErrLimitExceeded):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "code": "LIMIT_EXCEEDED"})
+	default:
+		a.logger.Errorw("Ledger operation failed",
+			"request_id", c.GetString("request_id"),
+			"error", err,
+		)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
+
+// authorizeAccount carrega a conta e aplica a regra de posse do principal
+func (a *App) authorizeAccount(c *gin.Context, accountID string, write bool) (*accounts.Account, error) {
+	account, err := a.accounts.Get(c.Request.Context(), accountID)
+	if err != nil {
+		return nil, err
+	}
+	if !principal(c).CanAccessOwner(account.UserID, write) {
+		return nil, auth.ErrForbidden
+	}
+	return account, nil
+}
+
+// authorizeAnyAccount exige acesso de leitura a pelo menos uma das contas
+func (a *App) authorizeAnyAccount(c *gin.Context, accountIDs []string) error {
+	for _, id := range accountIDs {
+		_, err := a.authorizeAccount(c, id, false)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, auth.ErrForbidden), errors.Is(err, accounts.ErrNotFound):
+			continue
+		default:
+			return err
+		}
+	}
+	return auth.ErrForbidden
+}
+
+// idempotentWrite executa a escrita na transação da chave de idempotência e
+// devolve a resposta a gravar e as contas afetadas (refresh do cache)
+type idempotentWrite func(ctx context.Context, tx *sql.Tx) (*idempotency.Response, []string, error)
+
+// withIdempotency trata X-Idempotency-Key para uma escrita: valida a chave,
+// calcula o fingerprint (método, scope e corpo), executa a escrita com a
+// chave travada ou devolve a resposta original em retries. scope é a rota
+// (c.FullPath) ou o caminho concreto quando o recurso do caminho identifica a
+// operação. decode recebe o corpo e a chave (reference_id); um erro dele é 400.
+func (a *App) withIdempotency(c *gin.Context, scope string, decode func(body []byte, key string) (idempotentWrite, error)) {
+	idempotencyKey := c.GetHeader("X-Idempotency-Key")
+	if idempotencyKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Idempotency-Key header is required"})
+		return
+	}
+	if len(idempotencyKey) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Idempotency-Key must have at most 100 characters"})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+	fingerprint, err := idempotency.Fingerprint(c.Request.Method, scope, body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+	write, err := decode(body, idempotencyKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body", "details": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var affected []string
+	resp, replayed, err := a.idempotency.Execute(ctx, idempotencyKey, fingerprint,
+		func(tx *sql.Tx) (*idempotency.Response, error) {
+			resp, ids, err := write(ctx, tx)
+			affected = ids
+			return resp, err
+		})
+	if err != nil {
+		a.respondError(c, err)
+		return
+	}
+	a.refreshBalances(ctx, affected...)
+
+	writeIdempotentResponse(c, resp, replayed)
+}
+
+// jsonResponse serializa a resposta que será gravada junto à chave de idempotência
+func jsonResponse(status int, transactionID string, payload interface{}) (*idempotency.Response, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encode response: %w", err)
+	}
+	return &idempotency.Response{StatusCode: status, Body: body, TransactionID: transactionID}, nil
+}
+
+// writeIdempotentResponse devolve os bytes gravados sem re-serializar
+func writeIdempotentResponse(c *gin.Context, resp *idempotency.Response, replayed bool) {
+	if replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.Data(resp.StatusCode, "application/json; charset=utf-8", resp.Body)
+}
+
 func generateRequestID() string {
 	return fmt.Sprintf("%d", time.Now().UnixNano())
 }
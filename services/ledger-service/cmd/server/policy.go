@@ -0,0 +1,210 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Middleware de autorização por política (OPA/Rego)
+// ============================================================================
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/kaminoclone/ledger-service/internal/accounts"
+	"github.com/kaminoclone/ledger-service/internal/auth"
+	"github.com/kaminoclone/ledger-service/internal/holds"
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+	"github.com/kaminoclone/ledger-service/internal/limits"
+	"github.com/kaminoclone/ledger-service/internal/policy"
+)
+
+// policyMiddleware avalia a política com rota, usuário, contas envolvidas,
+// seus donos e o valor da operação. Roda depois da autenticação e se soma às
+// regras fixas de papel e posse: as duas precisam permitir.
+func (a *App) policyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resource, err := a.policyResource(c)
+		if err != nil {
+			c.Abort()
+			a.respondError(c, err)
+			return
+		}
+
+		p := principal(c)
+		action := "write"
+		if c.Request.Method == http.MethodGet {
+			action = "read"
+		}
+		// IDs vão em minúsculas: a política compara com igualdade exata e os
+		// handlers aceitam o dono sem diferenciar maiúsculas (EqualFold)
+		decision, err := a.policy.Evaluate(c.Request.Context(), policy.Input{
+			Route: policy.Route{Method: c.Request.Method, Path: c.FullPath(), Action: action},
+			User: policy.User{
+				ID:        strings.ToLower(p.UserID),
+				Subject:   p.Subject,
+				CompanyID: p.CompanyID,
+				Role:      string(p.Role),
+			},
+			Resource: resource,
+			Request: policy.Request{
+				ID:        c.GetString("request_id"),
+				IP:        c.ClientIP(),
+				UserAgent: c.Request.UserAgent(),
+			},
+		})
+		if err != nil {
+			c.Abort()
+			a.respondError(c, err)
+			return
+		}
+		if !decision.Allow {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":       policy.ErrDenied.Error(),
+				"reasons":     decision.Reasons,
+				"decision_id": decision.ID,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// policyResource monta o recurso a partir da rota. Corpos inválidos e
+// registros inexistentes geram um recurso parcial: o handler devolve o 400/404.
+func (a *App) policyResource(c *gin.Context) (policy.Resource, error) {
+	ctx := c.Request.Context()
+	path := c.FullPath()
+	res := policy.Resource{ID: c.Param("id")}
+
+	switch {
+	case path == "/v1/accounts":
+		res.Type = "account"
+		if c.Request.Method == http.MethodPost {
+			var req accounts.CreateRequest
+			peekJSON(c, &req)
+			res.OwnerIDs = []string{strings.ToLower(strings.TrimSpace(req.UserID))}
+			break
+		}
+		// Fora do papel ADMIN, a listagem é restrita ao próprio usuário
+		owner := c.Query("user_id")
+		if p := principal(c); !p.HasRole(auth.RoleAdmin) {
+			owner = p.UserID
+		}
+		if owner != "" {
+			res.OwnerIDs = []string{strings.ToLower(owner)}
+		}
+		return res, nil
+
+	case path == "/v1/accounts/:id/holds" && c.Request.Method == http.MethodPost:
+		// O bloqueio ainda não existe: o recurso é a conta e o valor pedido
+		var req holds.PlaceRequest
+		peekJSON(c, &req)
+		res = policy.Resource{Type: "hold", Amount: int64(req.Amount), AccountIDs: []string{c.Param("id")}}
+
+	case strings.HasPrefix(path, "/v1/accounts/:id"):
+		res.Type = "account"
+		res.AccountIDs = []string{c.Param("id")}
+
+	case path == "/v1/transactions":
+		res.Type = "transaction"
+		var req ledger.PostingRequest
+		peekJSON(c, &req)
+		res.Currency = req.Currency
+		for _, leg := range req.Legs {
+			res.AccountIDs = appendUnique(res.AccountIDs, leg.AccountID)
+			if strings.EqualFold(string(leg.EntryType), string(ledger.EntryDebit)) {
+				res.Amount += int64(leg.Amount)
+			}
+		}
+
+	case strings.HasPrefix(path, "/v1/transactions/:id"):
+		res.Type = "transaction"
+		txn, err := a.ledger.GetTransaction(ctx, res.ID)
+		if err != nil && !errors.Is(err, ledger.ErrNotFound) {
+			return res, err
+		}
+		if txn != nil {
+			res.Amount, res.Currency = int64(txn.Amount), txn.Currency
+			res.AccountIDs = entryAccounts(txn)
+		}
+		var req ledger.ReversalRequest
+		if peekJSON(c, &req) && req.Amount != nil {
+			res.Amount = int64(*req.Amount)
+		}
+
+	case strings.HasPrefix(path, "/v1/holds/:id"):
+		res.Type = "hold"
+		hold, err := a.holds.Get(ctx, res.ID)
+		if err != nil && !errors.Is(err, holds.ErrNotFound) {
+			return res, err
+		}
+		if hold != nil {
+			res.Amount = int64(hold.Amount)
+			res.AccountIDs = []string{hold.AccountID}
+		}
+		var req holds.CaptureRequest
+		if peekJSON(c, &req) && req.Amount != nil {
+			res.Amount = int64(*req.Amount)
+		}
+		if req.DestinationAccountID != "" {
+			res.AccountIDs = appendUnique(res.AccountIDs, req.DestinationAccountID)
+		}
+
+	case strings.HasPrefix(path, "/v1/limit-requests/:id"):
+		res.Type = "limit_request"
+		request, err := a.limits.Get(ctx, res.ID)
+		if err != nil && !errors.Is(err, limits.ErrNotFound) {
+			return res, err
+		}
+		if request != nil {
+			res.Amount = int64(request.RequestedLimit)
+			res.AccountIDs = []string{request.AccountID}
+		}
+
+	case strings.HasPrefix(path, "/v1/ledger"):
+		res.Type = "ledger"
+		return res, nil
+	}
+
+	for _, id := range res.AccountIDs {
+		account, err := a.accounts.Get(ctx, id)
+		switch {
+		case err == nil:
+			res.OwnerIDs = appendUnique(res.OwnerIDs, strings.ToLower(account.UserID))
+		case errors.Is(err, accounts.ErrNotFound):
+		default:
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// peekJSON decodifica o corpo sem consumi-lo para o handler
+func peekJSON(c *gin.Context, v interface{}) bool {
+	if c.Request.Body == nil {
+		return false
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil || len(body) == 0 {
+		return false
+	}
+	return json.Unmarshal(body, v) == nil
+}
+
+func appendUnique(list []string, v string) []string {
+	if v == "" {
+		return list
+	}
+	for _, existing := range list {
+		if existing == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
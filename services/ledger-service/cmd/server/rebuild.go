@@ -0,0 +1,73 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Subcomando rebuild-projections
+// ============================================================================
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os/signal"
+	"syscall"
+
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+	"github.com/kaminoclone/ledger-service/internal/projections"
+	"go.uber.org/zap"
+)
+
+// runRebuildProjections reconstrói core.accounts a partir do ledger e do event store.
+//
+//	ledger-service rebuild-projections [-dry-run] [-resume] [-batch-size N]
+//
+// Com -dry-run, imprime as diferenças (uma por linha, em JSON) sem gravar nada.
+func runRebuildProjections(config *Config, logger *zap.SugaredLogger, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("rebuild-projections", flag.ContinueOnError)
+	fs.SetOutput(out)
+	dryRun := fs.Bool("dry-run", false, "report differences without writing")
+	resume := fs.Bool("resume", false, "continue after the last checkpoint of an interrupted run")
+	batchSize := fs.Int("batch-size", projections.DefaultBatchSize, "accounts per transaction")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Interrupções param entre lotes; o checkpoint permite retomar com -resume
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, credentials, err := openDatabase(config, logger)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// Um rebuild longo passa do lease das credenciais dinâmicas do Vault
+	if credentials != nil {
+		go credentials.Run(ctx)
+	}
+
+	// Com o cache habilitado, as contas percorridas são regravadas no Redis
+	// quando a versão do saldo for mais nova que a em cache
+	var balanceCache projections.BalanceCache
+	if redisClient, c := newBalanceCache(config, logger); c != nil {
+		defer redisClient.Close()
+		balanceCache = c
+	}
+
+	enc := json.NewEncoder(out)
+	events := eventstore.NewStore(db, config.EventSnapshotEvery)
+	rebuilder := projections.NewRebuilder(db, events, balanceCache, logger)
+	result, err := rebuilder.Rebuild(ctx, projections.Options{
+		DryRun:    *dryRun,
+		Resume:    *resume,
+		BatchSize: *batchSize,
+	}, func(d projections.Diff) {
+		enc.Encode(d)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Encode(result)
+}
@@ -0,0 +1,148 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Gestão de contas (core.accounts) vinculadas ao plano de contas
+// ============================================================================
+
+package accounts
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// Status espelha core.account_status
+type Status string
+
+const (
+	StatusPendingActivation Status = "PENDING_ACTIVATION"
+	StatusActive            Status = "ACTIVE"
+	StatusBlocked           Status = "BLOCKED"
+	StatusFrozen            Status = "FROZEN"
+	StatusClosed            Status = "CLOSED"
+)
+
+// Valid informa se o status existe no enum do banco
+func (s Status) Valid() bool {
+	switch s {
+	case StatusPendingActivation, StatusActive, StatusBlocked, StatusFrozen, StatusClosed:
+		return true
+	}
+	return false
+}
+
+// Erros de contas
+var (
+	ErrNotFound         = errors.New("account not found")
+	ErrInvalidRequest   = errors.New("invalid account request")
+	ErrChartNotFound    = errors.New("chart of accounts node not found")
+	ErrChartNotPostable = errors.New("chart of accounts node does not accept accounts")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrInvalidCursor    = errors.New("invalid pagination cursor")
+	ErrCurrencyMismatch = errors.New("account currency differs from chart of accounts node")
+)
+
+// Account representa uma linha de core.accounts
+type Account struct {
+	ID               string                 `json:"id"`
+	UserID           string                 `json:"user_id"`
+	ChartAccountID   string                 `json:"chart_account_id"`
+	ChartCode        string                 `json:"chart_code"`
+	AccountNumber    string                 `json:"account_number"`
+	Name             string                 `json:"name"`
+	Balance          ledger.Money           `json:"balance"`
+	AvailableBalance ledger.Money           `json:"available_balance"`
+	BlockedBalance   ledger.Money           `json:"blocked_balance"`
+	Currency         string                 `json:"currency"`
+	Status           Status                 `json:"status"`
+	DailyLimit       *ledger.Money          `json:"daily_limit,omitempty"`
+	TransactionLimit *ledger.Money          `json:"transaction_limit,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	ClosedAt         *time.Time             `json:"closed_at,omitempty"`
+}
+
+// CreateRequest descreve a abertura de uma conta.
+// O nó do plano de contas pode ser informado por ID ou por código ("2.1.01.001").
+type CreateRequest struct {
+	UserID           string                 `json:"user_id"`
+	ChartAccountID   string                 `json:"chart_account_id,omitempty"`
+	ChartCode        string                 `json:"chart_code,omitempty"`
+	Name             string                 `json:"name"`
+	Currency         string                 `json:"currency"`
+	DailyLimit       *ledger.Money          `json:"daily_limit,omitempty"`
+	TransactionLimit *ledger.Money          `json:"transaction_limit,omitempty"`
+	Metadata         map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Validate normaliza e valida a requisição de abertura
+func (r *CreateRequest) Validate() error {
+	r.UserID = strings.ToLower(strings.TrimSpace(r.UserID))
+	r.ChartAccountID = strings.ToLower(strings.TrimSpace(r.ChartAccountID))
+	r.ChartCode = strings.TrimSpace(r.ChartCode)
+	r.Name = strings.TrimSpace(r.Name)
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if r.Currency == "" {
+		r.Currency = "BRL"
+	}
+
+	switch {
+	case !ledger.IsUUID(r.UserID):
+		return fmt.Errorf("%w: user_id must be a UUID", ErrInvalidRequest)
+	case r.ChartAccountID == "" && r.ChartCode == "":
+		return fmt.Errorf("%w: chart_account_id or chart_code is required", ErrInvalidRequest)
+	case r.ChartAccountID != "" && !ledger.IsUUID(r.ChartAccountID):
+		return fmt.Errorf("%w: chart_account_id must be a UUID", ErrInvalidRequest)
+	case r.Name == "" || len(r.Name) > 255:
+		return fmt.Errorf("%w: name must have 1-255 characters", ErrInvalidRequest)
+	case len(r.Currency) != 3:
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidRequest)
+	case r.DailyLimit != nil && *r.DailyLimit < 0:
+		return fmt.Errorf("%w: daily_limit must not be negative", ErrInvalidRequest)
+	case r.TransactionLimit != nil && *r.TransactionLimit < 0:
+		return fmt.Errorf("%w: transaction_limit must not be negative", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// ListFilter filtra e pagina a listagem de contas
+type ListFilter struct {
+	UserID   string
+	Status   Status
+	Currency string
+	Limit    int
+	Cursor   string
+}
+
+// Page é uma página da listagem; NextCursor vazio indica o fim
+type Page struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// CheckDigit calcula o dígito verificador módulo 11 (pesos 2..9 da direita para a esquerda)
+func CheckDigit(digits string) int {
+	sum, weight := 0, 2
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * weight
+		weight++
+		if weight > 9 {
+			weight = 2
+		}
+	}
+	dv := 11 - sum%11
+	if dv >= 10 {
+		return 0
+	}
+	return dv
+}
+
+// FormatAccountNumber formata o número sequencial como "000000123-4"
+func FormatAccountNumber(seq int64) string {
+	base := fmt.Sprintf("%09d", seq)
+	return fmt.Sprintf("%s-%d", base, CheckDigit(base))
+}
@@ -0,0 +1,99 @@
+package accounts
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+)
+
+func TestFormatAccountNumber(t *testing.T) {
+	if got := FormatAccountNumber(123); got != "000000123-6" {
+		t.Fatalf("FormatAccountNumber(123) = %s", got)
+	}
+	if got := FormatAccountNumber(7); got != "000000007-8" {
+		t.Fatalf("FormatAccountNumber(7) = %s", got)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	id := "7d1c1b8e-3f4a-4c55-9b1e-2f7c3a9d0e11"
+
+	gotTime, gotID, err := decodeCursor(encodeCursor(createdAt, id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotTime.Equal(createdAt) || gotID != id {
+		t.Fatalf("round trip mismatch: %v %s", gotTime, gotID)
+	}
+
+	if _, _, err := decodeCursor("not-a-cursor"); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestOperationAllows(t *testing.T) {
+	cases := []struct {
+		op   Operation
+		from Status
+		want bool
+	}{
+		{OpActivate, StatusPendingActivation, true},
+		{OpBlock, StatusActive, true},
+		{OpBlock, StatusFrozen, false},
+		{OpUnfreeze, StatusFrozen, true},
+		{OpUnfreeze, StatusBlocked, false},
+		{OpFreeze, StatusBlocked, false},
+		{OpClose, StatusFrozen, true},
+		{OpClose, StatusClosed, false},
+		{SetStatusOperation(StatusActive), StatusClosed, false},
+		{SetStatusOperation(StatusActive), StatusBlocked, true},
+	}
+
+	for _, tc := range cases {
+		if got := tc.op.allows(tc.from); got != tc.want {
+			t.Errorf("%s from %s = %v, want %v", tc.op.Name, tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestAggregateReplay(t *testing.T) {
+	opened := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+	event := func(version int64, typ string, payload string, at time.Time) eventstore.Event {
+		return eventstore.Event{
+			AggregateType: AggregateType,
+			AggregateID:   "7d1c1b8e-3f4a-4c55-9b1e-2f7c3a9d0e11",
+			Type:          typ,
+			Version:       version,
+			Payload:       json.RawMessage(payload),
+			CreatedAt:     at,
+		}
+	}
+
+	agg := &Aggregate{}
+	history := []eventstore.Event{
+		event(1, EventAccountOpened, `{"account_number":"000000001-9","currency":"BRL","status":"PENDING_ACTIVATION"}`, opened),
+		event(2, EventAccountStatusChanged, `{"from":"PENDING_ACTIVATION","to":"ACTIVE","operation":"activate"}`, opened),
+		event(3, EventAccountStatusChanged, `{"from":"ACTIVE","to":"CLOSED","operation":"close"}`, opened.Add(time.Hour)),
+	}
+	for _, e := range history {
+		if err := agg.Apply(e); err != nil {
+			t.Fatalf("apply v%d: %v", e.Version, err)
+		}
+	}
+	if agg.Status != StatusClosed || agg.ClosedAt == nil || !agg.ClosedAt.Equal(opened.Add(time.Hour)) {
+		t.Fatalf("unexpected state after replay: %+v", agg)
+	}
+
+	if _, err := agg.ChangeStatus(OpActivate, "reopen"); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	out := event(4, EventAccountStatusChanged, `{"from":"ACTIVE","to":"BLOCKED","operation":"block"}`, opened)
+	if err := agg.Apply(out); err == nil {
+		t.Fatal("expected error applying a change from a stale status")
+	}
+}
@@ -0,0 +1,186 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Conta como agregado event-sourced (event_sourcing.event_store)
+// ============================================================================
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// AggregateType identifica contas no event store
+const AggregateType = "Account"
+
+// Tipos de evento do agregado Account
+const (
+	EventAccountOpened        = "AccountOpened"
+	EventAccountStatusChanged = "AccountStatusChanged"
+)
+
+// AccountOpened registra a abertura da conta (ou a importação de uma conta
+// que já existia em core.accounts antes do event store, com Imported = true)
+type AccountOpened struct {
+	UserID           string        `json:"user_id"`
+	ChartAccountID   string        `json:"chart_account_id"`
+	AccountNumber    string        `json:"account_number"`
+	Name             string        `json:"name"`
+	Currency         string        `json:"currency"`
+	Status           Status        `json:"status"`
+	DailyLimit       *ledger.Money `json:"daily_limit,omitempty"`
+	TransactionLimit *ledger.Money `json:"transaction_limit,omitempty"`
+	Imported         bool          `json:"imported,omitempty"`
+}
+
+// AccountStatusChanged registra uma transição da máquina de estados
+type AccountStatusChanged struct {
+	From      Status `json:"from"`
+	To        Status `json:"to"`
+	Operation string `json:"operation"`
+	Reason    string `json:"reason"`
+}
+
+// Aggregate é o estado da conta reconstruído a partir dos eventos.
+// Saldos não fazem parte do agregado: sua história é core.ledger_entries.
+type Aggregate struct {
+	ID               string        `json:"id"`
+	UserID           string        `json:"user_id"`
+	ChartAccountID   string        `json:"chart_account_id"`
+	AccountNumber    string        `json:"account_number"`
+	Name             string        `json:"name"`
+	Currency         string        `json:"currency"`
+	Status           Status        `json:"status"`
+	DailyLimit       *ledger.Money `json:"daily_limit,omitempty"`
+	TransactionLimit *ledger.Money `json:"transaction_limit,omitempty"`
+	OpenedAt         time.Time     `json:"opened_at"`
+	ClosedAt         *time.Time    `json:"closed_at,omitempty"`
+}
+
+// Apply implementa eventstore.Aggregate
+func (a *Aggregate) Apply(e eventstore.Event) error {
+	switch e.Type {
+	case EventAccountOpened:
+		var p AccountOpened
+		if err := e.Decode(&p); err != nil {
+			return err
+		}
+		*a = Aggregate{
+			ID:               e.AggregateID,
+			UserID:           p.UserID,
+			ChartAccountID:   p.ChartAccountID,
+			AccountNumber:    p.AccountNumber,
+			Name:             p.Name,
+			Currency:         p.Currency,
+			Status:           p.Status,
+			DailyLimit:       p.DailyLimit,
+			TransactionLimit: p.TransactionLimit,
+			OpenedAt:         e.CreatedAt,
+		}
+
+	case EventAccountStatusChanged:
+		var p AccountStatusChanged
+		if err := e.Decode(&p); err != nil {
+			return err
+		}
+		if p.From != a.Status {
+			return fmt.Errorf("status change from %s applied to account in %s", p.From, a.Status)
+		}
+		a.Status = p.To
+		if p.To == StatusClosed {
+			closedAt := e.CreatedAt
+			a.ClosedAt = &closedAt
+		}
+
+	default:
+		return fmt.Errorf("unknown account event %s", e.Type)
+	}
+	return nil
+}
+
+// ChangeStatus decide a transição e devolve o evento correspondente
+func (a *Aggregate) ChangeStatus(op Operation, reason string) (AccountStatusChanged, error) {
+	if !op.allows(a.Status) {
+		return AccountStatusChanged{}, fmt.Errorf("%w: %s -> %s (%s)", ErrInvalidTransition, a.Status, op.To, op.Name)
+	}
+	return AccountStatusChanged{From: a.Status, To: op.To, Operation: op.Name, Reason: reason}, nil
+}
+
+func accountStream(id string) eventstore.Stream {
+	return eventstore.Stream{Type: AggregateType, ID: id}
+}
+
+func openedEvent(account *Account, imported bool) eventstore.EventData {
+	return eventstore.EventData{
+		Type: EventAccountOpened,
+		Payload: AccountOpened{
+			UserID:           account.UserID,
+			ChartAccountID:   account.ChartAccountID,
+			AccountNumber:    account.AccountNumber,
+			Name:             account.Name,
+			Currency:         account.Currency,
+			Status:           account.Status,
+			DailyLimit:       account.DailyLimit,
+			TransactionLimit: account.TransactionLimit,
+			Imported:         imported,
+		},
+	}
+}
+
+// actorMetadata grava o ator junto com o evento
+func actorMetadata(actor Actor) map[string]interface{} {
+	metadata := map[string]interface{}{}
+	if actor.Type != "" {
+		metadata["actor_type"] = actor.Type
+	}
+	if actor.ID != "" {
+		metadata["actor_id"] = actor.ID
+	}
+	if actor.CorrelationID != "" {
+		metadata["correlation_id"] = actor.CorrelationID
+	}
+	return metadata
+}
+
+// loadAggregate reconstrói a conta dentro da transação. Contas anteriores ao
+// event store recebem um AccountOpened importado a partir da linha atual.
+func (s *Store) loadAggregate(ctx context.Context, tx *sql.Tx, id string) (*Aggregate, int64, error) {
+	agg := &Aggregate{}
+	version, err := s.events.LoadAggregate(ctx, tx, accountStream(id), agg)
+	if err != nil {
+		return nil, 0, err
+	}
+	if version > 0 {
+		return agg, version, nil
+	}
+
+	account, err := getAccount(ctx, tx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	version, err = s.events.Save(ctx, tx, accountStream(id), agg, 0, openedEvent(account, true))
+	if err != nil {
+		return nil, 0, err
+	}
+	return agg, version, nil
+}
+
+// History devolve todos os eventos da conta em ordem de versão
+func (s *Store) History(ctx context.Context, id string) ([]eventstore.Event, error) {
+	if _, err := getAccount(ctx, s.db, id); err != nil {
+		return nil, err
+	}
+	events, err := s.events.History(ctx, accountStream(id))
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []eventstore.Event{}
+	}
+	return events, nil
+}
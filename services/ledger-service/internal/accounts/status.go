@@ -0,0 +1,231 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Máquina de estados de contas (ACTIVE <-> BLOCKED/FROZEN -> CLOSED)
+// ============================================================================
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// Erros de transição de status
+var (
+	ErrInvalidTransition = errors.New("account status transition not allowed")
+	ErrReasonRequired    = errors.New("a reason is required for status changes")
+	ErrNonZeroBalance    = errors.New("account balance must be zero to close")
+	ErrActiveHolds       = errors.New("account has active balance holds")
+)
+
+// transitions define os destinos permitidos a partir de cada status.
+// BLOCKED é um bloqueio operacional (fraude, KYC); FROZEN é um bloqueio
+// judicial/compliance. CLOSED é terminal.
+var transitions = map[Status][]Status{
+	StatusPendingActivation: {StatusActive, StatusClosed},
+	StatusActive:            {StatusBlocked, StatusFrozen, StatusClosed},
+	StatusBlocked:           {StatusActive, StatusClosed},
+	StatusFrozen:            {StatusActive, StatusClosed},
+}
+
+// CanTransition informa se a mudança from -> to é permitida
+func CanTransition(from, to Status) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Operation é uma operação nomeada sobre o status da conta
+type Operation struct {
+	Name string
+	From []Status // vazio = qualquer origem permitida pela máquina de estados
+	To   Status
+}
+
+// Operações expostas como endpoints explícitos
+var (
+	OpActivate = Operation{Name: "activate", From: []Status{StatusPendingActivation}, To: StatusActive}
+	OpBlock    = Operation{Name: "block", From: []Status{StatusActive}, To: StatusBlocked}
+	OpUnblock  = Operation{Name: "unblock", From: []Status{StatusBlocked}, To: StatusActive}
+	OpFreeze   = Operation{Name: "freeze", From: []Status{StatusActive}, To: StatusFrozen}
+	OpUnfreeze = Operation{Name: "unfreeze", From: []Status{StatusFrozen}, To: StatusActive}
+	OpClose    = Operation{Name: "close", To: StatusClosed}
+)
+
+// SetStatusOperation é a operação genérica usada por PATCH /accounts/:id/status
+func SetStatusOperation(to Status) Operation {
+	return Operation{Name: "set_status", To: to}
+}
+
+func (op Operation) allows(from Status) bool {
+	if !CanTransition(from, op.To) {
+		return false
+	}
+	if len(op.From) == 0 {
+		return true
+	}
+	for _, s := range op.From {
+		if s == from {
+			return true
+		}
+	}
+	return false
+}
+
+// Actor identifica quem executou a operação (gravado em audit.audit_log)
+type Actor struct {
+	Type          string // 'USER', 'SYSTEM', 'ADMIN', 'API'
+	ID            string
+	IP            string
+	UserAgent     string
+	CorrelationID string
+}
+
+// Transition aplica a operação de forma atômica e registra a trilha de auditoria
+func (s *Store) Transition(ctx context.Context, id string, op Operation, actor Actor, reason string) (*Account, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	if !op.To.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %s", ErrInvalidRequest, op.To)
+	}
+	if !ledger.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// O lock na projeção serializa a transição com lançamentos e bloqueios de saldo;
+	// a versão esperada protege o fluxo de eventos contra outros escritores.
+	var balance ledger.Money
+	err = tx.QueryRowContext(ctx, `
+		SELECT balance FROM core.accounts WHERE id = $1 FOR UPDATE
+	`, id).Scan(&balance)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock account: %w", err)
+	}
+
+	agg, version, err := s.loadAggregate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	change, err := agg.ChangeStatus(op, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if op.To == StatusClosed {
+		if balance != 0 {
+			return nil, fmt.Errorf("%w: balance is %s", ErrNonZeroBalance, balance)
+		}
+		var holds int
+		err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM core.balance_holds WHERE account_id = $1 AND status = 'ACTIVE'
+		`, id).Scan(&holds)
+		if err != nil {
+			return nil, fmt.Errorf("count active holds: %w", err)
+		}
+		if holds > 0 {
+			return nil, fmt.Errorf("%w: %d active", ErrActiveHolds, holds)
+		}
+	}
+
+	_, err = s.events.Save(ctx, tx, accountStream(id), agg, version, eventstore.EventData{
+		Type:     EventAccountStatusChanged,
+		Payload:  change,
+		Metadata: actorMetadata(actor),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE core.accounts
+		SET status = $2,
+		    closed_at = CASE WHEN $2 = 'CLOSED' THEN NOW() ELSE closed_at END
+		WHERE id = $1
+	`, id, string(agg.Status))
+	if err != nil {
+		return nil, fmt.Errorf("update account status: %w", err)
+	}
+
+	if err := insertStatusAudit(ctx, tx, id, change.From, op, actor, reason); err != nil {
+		return nil, err
+	}
+
+	account, err := getAccount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return account, nil
+}
+
+// insertStatusAudit grava a transição em audit.audit_log com ator e motivo
+func insertStatusAudit(ctx context.Context, tx *sql.Tx, id string, from Status, op Operation, actor Actor, reason string) error {
+	oldData, _ := json.Marshal(map[string]interface{}{"status": from})
+	newData, _ := json.Marshal(map[string]interface{}{
+		"status":    op.To,
+		"operation": op.Name,
+		"reason":    reason,
+	})
+
+	actorType := actor.Type
+	if actorType == "" {
+		actorType = "API"
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO audit.audit_log (
+			schema_name, table_name, record_id, operation,
+			old_data, new_data, changed_fields,
+			actor_type, actor_id, correlation_id, ip_address, user_agent
+		) VALUES (
+			'core', 'accounts', $1, 'UPDATE',
+			$2, $3, ARRAY['status'],
+			$4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid, NULLIF($7, '')::inet, NULLIF($8, '')
+		)
+	`,
+		id, oldData, newData,
+		actorType, uuidOrEmpty(actor.ID), uuidOrEmpty(actor.CorrelationID), ipOrEmpty(actor.IP), actor.UserAgent,
+	)
+	if err != nil {
+		return fmt.Errorf("insert audit log: %w", err)
+	}
+	return nil
+}
+
+func uuidOrEmpty(s string) string {
+	if ledger.IsUUID(s) {
+		return s
+	}
+	return ""
+}
+
+func ipOrEmpty(s string) string {
+	if net.ParseIP(s) != nil {
+		return s
+	}
+	return ""
+}
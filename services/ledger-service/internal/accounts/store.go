@@ -0,0 +1,304 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Persistência de contas em PostgreSQL
+// ============================================================================
+
+package accounts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// Store acessa core.accounts e core.chart_of_accounts.
+// core.accounts é a projeção do agregado Account gravado no event store.
+type Store struct {
+	db     *sql.DB
+	events *eventstore.Store
+}
+
+// NewStore cria um Store sobre o pool de conexões e o event store informados
+func NewStore(db *sql.DB, events *eventstore.Store) *Store {
+	return &Store{db: db, events: events}
+}
+
+const selectAccount = `
+	SELECT a.id, a.user_id, a.chart_account_id, c.code, a.account_number, a.name,
+	       a.balance, a.available_balance, a.blocked_balance, a.currency, a.status,
+	       a.daily_limit, a.transaction_limit, COALESCE(a.metadata, '{}'),
+	       a.created_at, a.updated_at, a.closed_at
+	FROM core.accounts a
+	JOIN core.chart_of_accounts c ON c.id = a.chart_account_id
+`
+
+// Create abre uma conta vinculada a um nó analítico do plano de contas
+func (s *Store) Create(ctx context.Context, req CreateRequest) (*Account, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	metadata := req.Metadata
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidRequest, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var chartID, chartCurrency string
+	var synthetic, active bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, COALESCE(currency, 'BRL'), COALESCE(is_synthetic, FALSE), COALESCE(is_active, TRUE)
+		FROM core.chart_of_accounts
+		WHERE ($1 <> '' AND id = NULLIF($1, '')::uuid) OR ($1 = '' AND code = $2)
+	`, req.ChartAccountID, req.ChartCode).Scan(&chartID, &chartCurrency, &synthetic, &active)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChartNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load chart of accounts: %w", err)
+	}
+	if synthetic || !active {
+		return nil, ErrChartNotPostable
+	}
+	if chartCurrency != req.Currency {
+		return nil, fmt.Errorf("%w: chart is %s", ErrCurrencyMismatch, chartCurrency)
+	}
+
+	var seq int64
+	if err := tx.QueryRowContext(ctx, `SELECT nextval('core.account_number_seq')`).Scan(&seq); err != nil {
+		return nil, fmt.Errorf("generate account number: %w", err)
+	}
+
+	var id string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO core.accounts (
+			user_id, chart_account_id, account_number, name, currency,
+			daily_limit, transaction_limit, metadata
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`,
+		req.UserID, chartID, FormatAccountNumber(seq), req.Name, req.Currency,
+		nullMoney(req.DailyLimit), nullMoney(req.TransactionLimit), metadataJSON,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("insert account: %w", err)
+	}
+
+	account, err := getAccount(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.events.Save(ctx, tx, accountStream(id), &Aggregate{}, 0, openedEvent(account, false)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return account, nil
+}
+
+// Get carrega uma conta pelo ID
+func (s *Store) Get(ctx context.Context, id string) (*Account, error) {
+	return getAccount(ctx, s.db, id)
+}
+
+// List retorna contas filtradas, ordenadas da mais recente para a mais antiga,
+// com paginação por cursor (created_at, id).
+func (s *Store) List(ctx context.Context, filter ListFilter) (*Page, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserID != "" {
+		if !ledger.IsUUID(filter.UserID) {
+			return nil, fmt.Errorf("%w: user_id must be a UUID", ErrInvalidRequest)
+		}
+		conditions = append(conditions, "a.user_id = "+addArg(filter.UserID))
+	}
+	if filter.Status != "" {
+		if !filter.Status.Valid() {
+			return nil, fmt.Errorf("%w: unknown status %s", ErrInvalidRequest, filter.Status)
+		}
+		conditions = append(conditions, "a.status = "+addArg(string(filter.Status)))
+	}
+	if filter.Currency != "" {
+		conditions = append(conditions, "a.currency = "+addArg(strings.ToUpper(filter.Currency)))
+	}
+	if filter.Cursor != "" {
+		createdAt, id, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions,
+			fmt.Sprintf("(a.created_at, a.id) < (%s, %s)", addArg(createdAt), addArg(id)))
+	}
+
+	query := selectAccount
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY a.created_at DESC, a.id DESC LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	page := &Page{Accounts: []Account{}}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Accounts = append(page.Accounts, *account)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+
+	if len(page.Accounts) > limit {
+		page.Accounts = page.Accounts[:limit]
+		last := page.Accounts[limit-1]
+		page.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return page, nil
+}
+
+// queryer abstrai *sql.DB e *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func getAccount(ctx context.Context, q queryer, id string) (*Account, error) {
+	if !ledger.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+	account, err := scanAccount(q.QueryRowContext(ctx, selectAccount+" WHERE a.id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return account, err
+}
+
+func scanAccount(row scanner) (*Account, error) {
+	var a Account
+	var dailyLimit, transactionLimit sql.NullString
+	var metadata []byte
+	var closedAt sql.NullTime
+
+	err := row.Scan(
+		&a.ID, &a.UserID, &a.ChartAccountID, &a.ChartCode, &a.AccountNumber, &a.Name,
+		&a.Balance, &a.AvailableBalance, &a.BlockedBalance, &a.Currency, &a.Status,
+		&dailyLimit, &transactionLimit, &metadata,
+		&a.CreatedAt, &a.UpdatedAt, &closedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan account: %w", err)
+	}
+
+	if a.DailyLimit, err = parseNullMoney(dailyLimit); err != nil {
+		return nil, err
+	}
+	if a.TransactionLimit, err = parseNullMoney(transactionLimit); err != nil {
+		return nil, err
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &a.Metadata); err != nil {
+			return nil, fmt.Errorf("decode metadata: %w", err)
+		}
+		if len(a.Metadata) == 0 {
+			a.Metadata = nil
+		}
+	}
+	if closedAt.Valid {
+		a.ClosedAt = &closedAt.Time
+	}
+	return &a, nil
+}
+
+func parseNullMoney(v sql.NullString) (*ledger.Money, error) {
+	if !v.Valid {
+		return nil, nil
+	}
+	m, err := ledger.ParseMoney(v.String)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func nullMoney(m *ledger.Money) interface{} {
+	if m == nil {
+		return nil
+	}
+	return m.String()
+}
+
+// encodeCursor serializa a posição (created_at, id) do último item da página
+func encodeCursor(createdAt time.Time, id string) string {
+	raw := createdAt.UTC().Format(time.RFC3339Nano) + "|" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok || !ledger.IsUUID(id) {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
@@ -0,0 +1,193 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Autenticação por bearer token (JWT) e regras de autorização por papel
+// ============================================================================
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid bearer token")
+	ErrForbidden       = errors.New("not allowed to perform this operation")
+	ErrUnknownRole     = errors.New("token has no recognized role")
+	ErrKeyNotFound     = errors.New("signing key not found")
+)
+
+// Role espelha o enum UserRole do Prisma
+type Role string
+
+const (
+	RoleAdmin  Role = "ADMIN"
+	RoleUser   Role = "USER"
+	RoleViewer Role = "VIEWER"
+)
+
+// rank ordena os papéis; com vários papéis no token vale o maior
+func (r Role) rank() int {
+	switch r {
+	case RoleAdmin:
+		return 3
+	case RoleUser:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// ParseRole normaliza o papel do token (admin, USER...) para o enum
+func ParseRole(s string) (Role, bool) {
+	r := Role(strings.ToUpper(strings.TrimSpace(s)))
+	return r, r.rank() > 0
+}
+
+// Principal é quem fez a requisição, extraído de um token válido
+type Principal struct {
+	Subject   string `json:"subject"`
+	UserID    string `json:"user_id"`
+	CompanyID string `json:"company_id,omitempty"`
+	Role      Role   `json:"role"`
+}
+
+// HasRole informa se o principal tem um dos papéis
+func (p *Principal) HasRole(roles ...Role) bool {
+	for _, r := range roles {
+		if p.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessOwner aplica a regra de posse: ADMIN acessa qualquer conta,
+// USER lê e altera as próprias e VIEWER só lê as próprias.
+func (p *Principal) CanAccessOwner(ownerID string, write bool) bool {
+	switch p.Role {
+	case RoleAdmin:
+		return true
+	case RoleUser:
+		return p.owns(ownerID)
+	case RoleViewer:
+		return !write && p.owns(ownerID)
+	}
+	return false
+}
+
+func (p *Principal) owns(ownerID string) bool {
+	return p.UserID != "" && strings.EqualFold(p.UserID, ownerID)
+}
+
+// ActorType é o actor_type gravado em audit.audit_log
+func (p *Principal) ActorType() string {
+	if p.Role == RoleAdmin {
+		return "ADMIN"
+	}
+	return "USER"
+}
+
+// Claims aceita tanto o token do frontend (role, companyId) quanto o do
+// provedor OAuth2 (uid, roles, company_id)
+type Claims struct {
+	jwt.RegisteredClaims
+	UserID       string   `json:"uid,omitempty"`
+	Role         string   `json:"role,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	CompanyID    string   `json:"company_id,omitempty"`
+	CompanyIDAlt string   `json:"companyId,omitempty"`
+}
+
+// principal converte as claims; sem uid, o subject identifica o usuário
+func (c *Claims) principal() (*Principal, error) {
+	p := &Principal{
+		Subject:   c.Subject,
+		UserID:    c.UserID,
+		CompanyID: c.CompanyID,
+	}
+	if p.UserID == "" {
+		p.UserID = c.Subject
+	}
+	if p.CompanyID == "" {
+		p.CompanyID = c.CompanyIDAlt
+	}
+	if p.UserID == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+
+	for _, s := range append([]string{c.Role}, c.Roles...) {
+		if r, ok := ParseRole(s); ok && r.rank() > p.Role.rank() {
+			p.Role = r
+		}
+	}
+	if p.Role == "" {
+		return nil, ErrUnknownRole
+	}
+	return p, nil
+}
+
+// KeySource devolve a chave pública que verifica tokens assinados com kid
+type KeySource interface {
+	Key(ctx context.Context, kid string) (interface{}, error)
+}
+
+// signingMethods são os algoritmos assimétricos aceitos; HS* e none ficam de fora
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Verifier valida bearer tokens
+type Verifier struct {
+	keys   KeySource
+	parser *jwt.Parser
+}
+
+// NewVerifier cria um verificador; issuer e audience vazios não são verificados
+// (em produção, o serviço recusa subir sem eles)
+func NewVerifier(keys KeySource, issuer, audience string, leeway time.Duration) *Verifier {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if issuer != "" {
+		opts = append(opts, jwt.WithIssuer(issuer))
+	}
+	if audience != "" {
+		opts = append(opts, jwt.WithAudience(audience))
+	}
+	return &Verifier{keys: keys, parser: jwt.NewParser(opts...)}
+}
+
+// Verify valida assinatura, expiração, emissor e audiência e extrai o principal
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	var claims Claims
+	_, err := v.parser.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return claims.principal()
+}
+
+// BearerToken extrai o token do header Authorization
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
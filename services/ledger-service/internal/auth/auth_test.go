@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const ownerID = "7c0b1f4e-2a64-4d1a-9a39-0d2f6f3b8e11"
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func claims(role string, exp time.Time) *Claims {
+	return &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   ownerID,
+			Issuer:    "https://auth.kamino.local",
+			Audience:  jwt.ClaimStrings{"ledger-service"},
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+		Role:         role,
+		CompanyIDAlt: "company-1",
+	}
+}
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(&StaticKey{key: &key.PublicKey}, "https://auth.kamino.local", "ledger-service", 0)
+	ctx := context.Background()
+
+	p, err := v.Verify(ctx, sign(t, key, "k1", claims("user", time.Now().Add(time.Hour))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Role != RoleUser || p.UserID != ownerID || p.CompanyID != "company-1" {
+		t.Errorf("principal = %+v", p)
+	}
+
+	wrongAudience := claims("ADMIN", time.Now().Add(time.Hour))
+	wrongAudience.Audience = jwt.ClaimStrings{"other-service"}
+	noExpiry := claims("ADMIN", time.Time{})
+	noExpiry.ExpiresAt = nil
+
+	cases := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", sign(t, key, "k1", claims("ADMIN", time.Now().Add(-time.Minute))), ErrUnauthenticated},
+		{"wrong key", sign(t, other, "k1", claims("ADMIN", time.Now().Add(time.Hour))), ErrUnauthenticated},
+		{"wrong audience", sign(t, key, "k1", wrongAudience), ErrUnauthenticated},
+		{"no expiry", sign(t, key, "k1", noExpiry), ErrUnauthenticated},
+		{"unknown role", sign(t, key, "k1", claims("OWNER", time.Now().Add(time.Hour))), ErrUnknownRole},
+		{"malformed", "not-a-jwt", ErrUnauthenticated},
+	}
+	for _, tc := range cases {
+		if _, err := v.Verify(ctx, tc.token); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	// HS256 assinado com a chave pública (ataque de confusão de algoritmo)
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, claims("ADMIN", time.Now().Add(time.Hour)))
+	s, _ := hs.SignedString([]byte("secret"))
+	if _, err := v.Verify(ctx, s); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("HS256 token accepted: %v", err)
+	}
+}
+
+func TestRolesClaimUsesHighestRole(t *testing.T) {
+	c := &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: ownerID}, Roles: []string{"viewer", "admin", "billing"}}
+	p, err := c.principal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Role != RoleAdmin {
+		t.Errorf("role = %s, want ADMIN", p.Role)
+	}
+}
+
+func TestCanAccessOwner(t *testing.T) {
+	other := "0e3c1a55-5d0c-4bb6-8a57-6b9d3b0c2f40"
+	cases := []struct {
+		role  Role
+		owner string
+		write bool
+		want  bool
+	}{
+		{RoleAdmin, other, true, true},
+		{RoleUser, ownerID, true, true},
+		{RoleUser, other, false, false},
+		{RoleViewer, ownerID, false, true},
+		{RoleViewer, ownerID, true, false},
+		{RoleViewer, other, false, false},
+	}
+	for _, tc := range cases {
+		p := &Principal{UserID: ownerID, Role: tc.role}
+		if got := p.CanAccessOwner(tc.owner, tc.write); got != tc.want {
+			t.Errorf("%s owner=%s write=%v: got %v, want %v", tc.role, tc.owner, tc.write, got, tc.want)
+		}
+	}
+}
+
+func TestJWKSRefreshesOnUnknownKid(t *testing.T) {
+	k1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	k2, _ := rsa.GenerateKey(rand.Reader, 2048)
+	current := []*rsa.PrivateKey{k1}
+	fetches := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		var keys []map[string]string
+		for i, k := range current {
+			keys = append(keys, map[string]string{
+				"kid": []string{"k1", "k2"}[i],
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+	jwks.minRefresh = 0
+	v := NewVerifier(jwks, "", "", 0)
+	ctx := context.Background()
+
+	if _, err := v.Verify(ctx, sign(t, k1, "k1", claims("USER", time.Now().Add(time.Hour)))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(ctx, sign(t, k1, "k1", claims("USER", time.Now().Add(time.Hour)))); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want 1 (cached)", fetches)
+	}
+
+	// Rotação no provedor
+	current = []*rsa.PrivateKey{k1, k2}
+	if _, err := v.Verify(ctx, sign(t, k2, "k2", claims("USER", time.Now().Add(time.Hour)))); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 2 {
+		t.Errorf("fetches = %d, want 2", fetches)
+	}
+}
+
+func TestJWKSFetchesOutsideLock(t *testing.T) {
+	k1, _ := rsa.GenerateKey(rand.Reader, 2048)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A primeira busca responde na hora; as seguintes esperam release
+		if fetches.Add(1) > 1 {
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(k1.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k1.E)).Bytes()),
+		}}})
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	jwks := NewJWKS(srv.URL, time.Hour)
+	jwks.minRefresh = 0
+	ctx := context.Background()
+	if _, err := jwks.Key(ctx, "k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Vários kids desconhecidos ao mesmo tempo: uma única busca, presa no provedor
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jwks.Key(ctx, "unknown")
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// A chave em cache continua disponível enquanto a busca não termina
+	done := make(chan error, 1)
+	go func() {
+		_, err := jwks.Key(ctx, "k1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached key lookup blocked by the in-flight JWKS fetch")
+	}
+
+	release <- struct{}{}
+	wg.Wait()
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2 (one shared refresh)", n)
+	}
+}
+
+func TestParseJWKsSkipsUnusableKeys(t *testing.T) {
+	k, _ := rsa.GenerateKey(rand.Reader, 2048)
+	good := jwk{
+		Kid: "good", Kty: "RSA", Use: "sig",
+		N: base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+	}
+	set := []jwk{
+		good,
+		{Kid: "curve", Kty: "EC", Crv: "secp256k1", X: "AQ", Y: "AQ"},
+		{Kid: "okp", Kty: "OKP", Crv: "Ed25519", X: "not base64!"},
+		{Kid: "pq", Kty: "AKP"},
+		{Kid: "enc", Kty: "RSA", Use: "enc"},
+	}
+
+	var skipped []string
+	keys, err := parseJWKs(set, func(kid string, err error) { skipped = append(skipped, kid) })
+	if err != nil {
+		t.Fatalf("parseJWKs: %v", err)
+	}
+	if len(keys) != 1 || keys["good"] == nil {
+		t.Errorf("keys = %v, want only the RSA key", keys)
+	}
+	if strings.Join(skipped, ",") != "curve,okp,pq" {
+		t.Errorf("skipped = %v", skipped)
+	}
+
+	// Sem nenhuma chave utilizável o conjunto é rejeitado
+	if _, err := parseJWKs(set[1:], func(string, error) {}); err == nil {
+		t.Error("expected error for a set without usable keys")
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	if tok, ok := BearerToken("bearer abc.def.ghi"); !ok || tok != "abc.def.ghi" {
+		t.Errorf("BearerToken = %q, %v", tok, ok)
+	}
+	for _, h := range []string{"", "Basic dXNlcjpwYXNz", "Bearer "} {
+		if _, ok := BearerToken(h); ok {
+			t.Errorf("BearerToken(%q) accepted", h)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Chaves de verificação: JWKS remoto ou chave pública local (PEM)
+// ============================================================================
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// StaticKey verifica todos os tokens com uma única chave, qualquer que seja o kid
+type StaticKey struct {
+	key interface{}
+}
+
+// LoadKeyFile lê uma chave pública PEM (PUBLIC KEY, RSA PUBLIC KEY ou CERTIFICATE)
+func LoadKeyFile(path string) (*StaticKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+	key, err := ParsePublicKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &StaticKey{key: key}, nil
+}
+
+// ParsePublicKeyPEM decodifica o primeiro bloco PEM
+func ParsePublicKeyPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+}
+
+// Key implementa KeySource
+func (s *StaticKey) Key(ctx context.Context, kid string) (interface{}, error) {
+	return s.key, nil
+}
+
+// JWKS busca as chaves do provedor e as mantém em memória.
+// Um kid desconhecido (rotação no provedor) força nova busca, limitada a uma
+// por minRefresh para que tokens forjados não sobrecarreguem o provedor.
+// A busca roda fora do lock: requisições com chave em cache não esperam por
+// ela, e as que esperam compartilham uma única busca em andamento.
+type JWKS struct {
+	url        string
+	client     *http.Client
+	ttl        time.Duration
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+	inflight  *jwksFetch // busca em andamento; nil fora dela
+	onSkipped []func(kid string, err error)
+}
+
+// jwksFetch é uma busca compartilhada; done fecha quando ela termina
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKS cria uma fonte de chaves para a URL informada
+func NewJWKS(url string, ttl time.Duration) *JWKS {
+	return &JWKS{
+		url:        url,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		ttl:        ttl,
+		minRefresh: time.Minute,
+	}
+}
+
+// OnSkippedKey registra um observador chamado para cada chave do conjunto
+// descartada por tipo, curva ou material inválido. Deve ser chamado antes do
+// primeiro Key.
+func (j *JWKS) OnSkippedKey(fn func(kid string, err error)) {
+	j.onSkipped = append(j.onSkipped, fn)
+}
+
+// Key implementa KeySource
+func (j *JWKS) Key(ctx context.Context, kid string) (interface{}, error) {
+	j.mu.Lock()
+	key, found := j.lookup(kid)
+	stale := time.Since(j.fetchedAt) > j.ttl
+	if found && !stale {
+		j.mu.Unlock()
+		return key, nil
+	}
+	fetch := j.inflight
+	if fetch == nil {
+		if !stale && time.Since(j.fetchedAt) < j.minRefresh {
+			j.mu.Unlock()
+			return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+		}
+		// Marcar antes da busca: uma falha também respeita minRefresh
+		j.fetchedAt = time.Now()
+		fetch = &jwksFetch{done: make(chan struct{})}
+		j.inflight = fetch
+		// Contexto próprio: o cancelamento de uma requisição não derruba a
+		// busca das outras (o client limita a duração)
+		go j.refresh(context.Background(), fetch)
+	}
+	j.mu.Unlock()
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		if found {
+			return key, nil
+		}
+		return nil, ctx.Err()
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if key, found = j.lookup(kid); found {
+		// Provedor fora do ar: a chave em cache continua válida
+		return key, nil
+	}
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrKeyNotFound, kid)
+}
+
+// lookup aceita token sem kid quando o conjunto tem uma única chave
+func (j *JWKS) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(j.keys) == 1 {
+		for _, k := range j.keys {
+			return k, true
+		}
+	}
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// refresh busca o conjunto sem o lock e só o pega para trocar as chaves
+func (j *JWKS) refresh(ctx context.Context, fetch *jwksFetch) {
+	keys, err := j.fetch(ctx)
+
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+	}
+	fetch.err = err
+	j.inflight = nil
+	j.mu.Unlock()
+	close(fetch.done)
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build JWKS request: %w", err)
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode JWKS: %w", err)
+	}
+	return parseJWKs(set.Keys, func(kid string, err error) {
+		for _, fn := range j.onSkipped {
+			fn(kid, err)
+		}
+	})
+}
+
+// jwk é uma chave pública no formato da RFC 7517
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKs ignora chaves de criptografia; chaves de tipo ou curva não
+// suportados e material inválido são repassadas a skip e também ignoradas,
+// para que uma chave nova do provedor não invalide as demais
+func parseJWKs(set []jwk, skip func(kid string, err error)) (map[string]interface{}, error) {
+	keys := make(map[string]interface{}, len(set))
+	for _, k := range set {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			skip(k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
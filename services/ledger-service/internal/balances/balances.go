@@ -0,0 +1,189 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Saldos atuais e históricos ("saldo em") com snapshots diários
+// ============================================================================
+
+package balances
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// Erros de consulta de saldo
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrInvalidAsOf     = errors.New("invalid as_of")
+)
+
+// Source indica de onde o saldo foi obtido
+type Source string
+
+const (
+	SourceCurrent  Source = "current"  // core.accounts
+	SourceCache    Source = "cache"    // cache Redis, alimentado a partir de core.accounts
+	SourceEntries  Source = "entries"  // último lançamento até as_of
+	SourceSnapshot Source = "snapshot" // core.balance_snapshots, sem lançamentos depois
+	SourceEmpty    Source = "empty"    // nenhum lançamento até as_of
+)
+
+// Balance é o saldo de uma conta em um instante
+type Balance struct {
+	AccountID          string       `json:"account_id"`
+	Currency           string       `json:"currency"`
+	AsOf               time.Time    `json:"as_of"`
+	Balance            ledger.Money `json:"balance"`
+	HeldAmount         ledger.Money `json:"held_amount"`
+	AvailableBalance   ledger.Money `json:"available_balance"`
+	LastSequenceNumber int64        `json:"last_sequence_number,omitempty"`
+	Version            int64        `json:"-"` // core.accounts.balance_version (ordem do cache)
+	Source             Source       `json:"source"`
+}
+
+// ParseAsOf aceita RFC 3339 ou uma data (YYYY-MM-DD = final do dia, UTC)
+func ParseAsOf(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		if t.After(now) {
+			return time.Time{}, fmt.Errorf("%w: must not be in the future", ErrInvalidAsOf)
+		}
+		return t, nil
+	}
+	day, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: use RFC 3339 or YYYY-MM-DD", ErrInvalidAsOf)
+	}
+	t := day.Add(24*time.Hour - time.Nanosecond)
+	if day.After(now) {
+		return time.Time{}, fmt.Errorf("%w: must not be in the future", ErrInvalidAsOf)
+	}
+	if t.After(now) {
+		t = now
+	}
+	return t, nil
+}
+
+// Store consulta saldos e mantém os snapshots
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre o pool de conexões informado
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Current devolve o saldo mantido pelo trigger em core.accounts, com a
+// sequência do último lançamento aplicado e a versão do saldo (usada pelo
+// cache para descartar gravações fora de ordem)
+func (s *Store) Current(ctx context.Context, accountID string) (*Balance, error) {
+	if !ledger.IsUUID(accountID) {
+		return nil, ErrAccountNotFound
+	}
+
+	b := &Balance{AccountID: accountID, Source: SourceCurrent}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT a.currency, a.balance, a.blocked_balance, a.available_balance, a.balance_version, NOW(),
+		       COALESCE((
+		           SELECT e.sequence_number FROM core.ledger_entries e
+		           WHERE e.account_id = a.id
+		           ORDER BY e.sequence_number DESC
+		           LIMIT 1
+		       ), 0)
+		FROM core.accounts a
+		WHERE a.id = $1
+	`, accountID).Scan(&b.Currency, &b.Balance, &b.HeldAmount, &b.AvailableBalance, &b.Version, &b.AsOf, &b.LastSequenceNumber)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load balance: %w", err)
+	}
+	return b, nil
+}
+
+// AsOf reconstrói o saldo no instante informado.
+//
+// Os lançamentos de uma conta são gravados com a conta bloqueada, então
+// sequence_number segue a ordem de aplicação e balance_after do último
+// lançamento até as_of é o saldo naquele instante. O snapshot mais recente
+// anterior ao dia de as_of limita a busca às partições seguintes.
+func (s *Store) AsOf(ctx context.Context, accountID string, asOf time.Time) (*Balance, error) {
+	if !ledger.IsUUID(accountID) {
+		return nil, ErrAccountNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	b := &Balance{AccountID: accountID, AsOf: asOf, Source: SourceEmpty}
+	err = tx.QueryRowContext(ctx, `SELECT currency FROM core.accounts WHERE id = $1`, accountID).Scan(&b.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load account: %w", err)
+	}
+
+	var snapshotDate sql.NullTime
+	var snapshotBalance ledger.Money
+	var snapshotSeq int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT snapshot_date, balance, last_sequence_number
+		FROM core.balance_snapshots
+		WHERE account_id = $1 AND snapshot_date < $2::timestamptz::date
+		ORDER BY snapshot_date DESC
+		LIMIT 1
+	`, accountID, asOf).Scan(&snapshotDate, &snapshotBalance, &snapshotSeq)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("load snapshot: %w", err)
+	}
+	if snapshotDate.Valid {
+		b.Balance, b.LastSequenceNumber, b.Source = snapshotBalance, snapshotSeq, SourceSnapshot
+	}
+
+	var after sql.NullTime
+	if snapshotDate.Valid {
+		after = snapshotDate
+	}
+	var balance ledger.Money
+	var seq int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT balance_after, sequence_number
+		FROM core.ledger_entries
+		WHERE account_id = $1
+		  AND ($2::date IS NULL OR partition_date > $2::date)
+		  AND partition_date <= $3::timestamptz::date
+		  AND created_at <= $3
+		ORDER BY sequence_number DESC
+		LIMIT 1
+	`, accountID, after, asOf).Scan(&balance, &seq)
+	switch {
+	case err == nil:
+		b.Balance, b.LastSequenceNumber, b.Source = balance, seq, SourceEntries
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, fmt.Errorf("load last entry: %w", err)
+	}
+
+	// Bloqueios vigentes em as_of: criados antes e ainda não resolvidos naquele instante
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM core.balance_holds
+		WHERE account_id = $1
+		  AND created_at <= $2
+		  AND (released_at IS NULL OR released_at > $2)
+	`, accountID, asOf).Scan(&b.HeldAmount)
+	if err != nil {
+		return nil, fmt.Errorf("sum holds: %w", err)
+	}
+
+	b.AvailableBalance = b.Balance - b.HeldAmount
+	return b, nil
+}
@@ -0,0 +1,34 @@
+package balances
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	now := time.Date(2026, 6, 15, 14, 30, 0, 0, time.UTC)
+
+	got, err := ParseAsOf("2026-05-31", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 5, 31, 23, 59, 59, 999999999, time.UTC); !got.Equal(want) {
+		t.Fatalf("date as_of = %v, want end of day %v", got, want)
+	}
+
+	// O dia corrente é limitado ao instante atual
+	if got, _ := ParseAsOf("2026-06-15", now); !got.Equal(now) {
+		t.Fatalf("today as_of = %v, want %v", got, now)
+	}
+
+	if got, err := ParseAsOf("2026-06-01T12:00:00-03:00", now); err != nil || got.UTC().Hour() != 15 {
+		t.Fatalf("RFC 3339 as_of = %v, %v", got, err)
+	}
+
+	for _, in := range []string{"2026-07-01", "2026-06-16T00:00:00Z", "31/05/2026"} {
+		if _, err := ParseAsOf(in, now); !errors.Is(err, ErrInvalidAsOf) {
+			t.Errorf("ParseAsOf(%q) expected ErrInvalidAsOf, got %v", in, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Geração de snapshots diários de saldo
+// ============================================================================
+
+package balances
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SnapshotDay grava o saldo de fechamento do dia para cada conta movimentada nele.
+// Contas sem movimento continuam representadas pelo snapshot anterior. O dia
+// é registrado como processado na mesma transação, mesmo sem lançamentos.
+func (s *Store) SnapshotDay(ctx context.Context, day time.Time) (int64, error) {
+	date := day.Format("2006-01-02")
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO core.balance_snapshots (account_id, snapshot_date, balance, last_sequence_number)
+		SELECT DISTINCT ON (account_id) account_id, $1::date, balance_after, sequence_number
+		FROM core.ledger_entries
+		WHERE partition_date = $1::date
+		ORDER BY account_id, sequence_number DESC
+		ON CONFLICT (account_id, snapshot_date) DO NOTHING
+	`, date)
+	if err != nil {
+		return 0, fmt.Errorf("snapshot %s: %w", date, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("snapshot %s: %w", date, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO core.balance_snapshot_progress (id, last_snapshot_date, updated_at)
+		VALUES (TRUE, $1::date, NOW())
+		ON CONFLICT (id) DO UPDATE
+		SET last_snapshot_date = GREATEST(core.balance_snapshot_progress.last_snapshot_date, EXCLUDED.last_snapshot_date),
+		    updated_at = NOW()
+	`, date)
+	if err != nil {
+		return 0, fmt.Errorf("save snapshot progress %s: %w", date, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return n, nil
+}
+
+// pendingDays devolve os dias fechados (anteriores a hoje no banco) ainda não
+// processados. O progresso cobre dias sem lançamentos; MAX(snapshot_date)
+// vale para bancos anteriores à tabela de progresso.
+func (s *Store) pendingDays(ctx context.Context) ([]time.Time, error) {
+	var last, first sql.NullTime
+	var today time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT GREATEST(
+		           (SELECT last_snapshot_date FROM core.balance_snapshot_progress),
+		           (SELECT MAX(snapshot_date) FROM core.balance_snapshots)
+		       ),
+		       (SELECT MIN(partition_date) FROM core.ledger_entries),
+		       CURRENT_DATE
+	`).Scan(&last, &first, &today)
+	if err != nil {
+		return nil, fmt.Errorf("load snapshot progress: %w", err)
+	}
+
+	var start time.Time
+	switch {
+	case last.Valid:
+		start = last.Time.AddDate(0, 0, 1)
+	case first.Valid:
+		start = first.Time
+	default:
+		return nil, nil
+	}
+
+	var days []time.Time
+	for d := start; d.Before(today); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// Snapshotter mantém core.balance_snapshots em dia
+type Snapshotter struct {
+	store    *Store
+	interval time.Duration
+	logger   *zap.SugaredLogger
+}
+
+// NewSnapshotter cria um job que verifica dias pendentes a cada interval
+func NewSnapshotter(store *Store, interval time.Duration, logger *zap.SugaredLogger) *Snapshotter {
+	return &Snapshotter{store: store, interval: interval, logger: logger}
+}
+
+// Run executa até o contexto ser cancelado
+func (s *Snapshotter) Run(ctx context.Context) {
+	s.catchUp(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.catchUp(ctx)
+		}
+	}
+}
+
+func (s *Snapshotter) catchUp(ctx context.Context) {
+	days, err := s.store.pendingDays(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Errorw("Failed to load pending balance snapshots", "error", err)
+		}
+		return
+	}
+
+	for _, day := range days {
+		n, err := s.store.SnapshotDay(ctx, day)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Errorw("Failed to snapshot balances", "day", day.Format("2006-01-02"), "error", err)
+			}
+			return
+		}
+		s.logger.Infow("Balance snapshot created", "day", day.Format("2006-01-02"), "accounts", n)
+	}
+}
@@ -0,0 +1,150 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Cache de saldos em Redis com leitura de fallback no PostgreSQL
+// ============================================================================
+
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/kaminoclone/ledger-service/internal/balances"
+)
+
+var cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ledger_balance_cache_requests_total",
+	Help: "Balance reads by cache result (hit, miss, error)",
+}, []string{"result"})
+
+const keyPrefix = "ledger:balance:"
+
+// setIfNewer grava o saldo somente se balance_version for maior que a já
+// armazenada. Refreshes concorrentes podem terminar fora de ordem; o de versão
+// menor leu um saldo anterior e é descartado. A versão muda também com
+// bloqueios, que alteram o disponível sem gerar lançamento; versões iguais
+// são o mesmo saldo.
+var setIfNewer = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'ver')
+if current and tonumber(current) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'ver', ARGV[1], 'data', ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// BalanceCache guarda o último saldo conhecido de cada conta
+type BalanceCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewBalanceCache cria o cache; ttl limita quanto tempo um saldo sobrevive sem refresh
+func NewBalanceCache(client *redis.Client, ttl time.Duration) *BalanceCache {
+	return &BalanceCache{client: client, ttl: ttl}
+}
+
+func key(accountID string) string {
+	return keyPrefix + accountID
+}
+
+// Get devolve o saldo em cache ou nil se ausente
+func (c *BalanceCache) Get(ctx context.Context, accountID string) (*balances.Balance, error) {
+	data, err := c.client.HGet(ctx, key(accountID), "data").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cached balance: %w", err)
+	}
+	var b balances.Balance
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("decode cached balance: %w", err)
+	}
+	return &b, nil
+}
+
+// Set grava o saldo se não houver um igual ou mais novo (por versão) em cache
+func (c *BalanceCache) Set(ctx context.Context, b balances.Balance) (bool, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return false, fmt.Errorf("encode balance: %w", err)
+	}
+	stored, err := setIfNewer.Run(ctx, c.client, []string{key(b.AccountID)},
+		strconv.FormatInt(b.Version, 10), data, c.ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, fmt.Errorf("set cached balance: %w", err)
+	}
+	return stored == 1, nil
+}
+
+// Reader lê saldos atuais do cache e recorre ao PostgreSQL em falta ou erro.
+// Lançamentos e bloqueios feitos pela API, a expiração de bloqueios e os
+// pagamentos agendados chamam Refresh após o commit; demais mudanças
+// (ex.: ajustes manuais no banco) aparecem em até ttl.
+type Reader struct {
+	cache  *BalanceCache
+	store  *balances.Store
+	logger *zap.SugaredLogger
+}
+
+// NewReader cria o caminho de leitura sobre o cache e o store
+func NewReader(cache *BalanceCache, store *balances.Store, logger *zap.SugaredLogger) *Reader {
+	return &Reader{cache: cache, store: store, logger: logger}
+}
+
+// Current devolve o saldo atual, preferindo o cache
+func (r *Reader) Current(ctx context.Context, accountID string) (*balances.Balance, error) {
+	cached, err := r.cache.Get(ctx, accountID)
+	switch {
+	case err != nil:
+		cacheRequests.WithLabelValues("error").Inc()
+		r.logger.Warnw("Balance cache read failed, falling back to database", "account_id", accountID, "error", err)
+	case cached != nil:
+		cacheRequests.WithLabelValues("hit").Inc()
+		cached.Source = balances.SourceCache
+		return cached, nil
+	default:
+		cacheRequests.WithLabelValues("miss").Inc()
+	}
+
+	b, err := r.store.Current(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.cache.Set(ctx, *b); err != nil {
+		r.logger.Warnw("Failed to populate balance cache", "account_id", accountID, "error", err)
+	}
+	return b, nil
+}
+
+// Refresh relê os saldos no PostgreSQL e atualiza o cache. Falhas são apenas
+// registradas: o lançamento já foi confirmado e o cache expira sozinho.
+func (r *Reader) Refresh(ctx context.Context, accountIDs ...string) {
+	seen := make(map[string]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		b, err := r.store.Current(ctx, id)
+		if err != nil {
+			r.logger.Warnw("Failed to load balance for cache refresh", "account_id", id, "error", err)
+			continue
+		}
+		if _, err := r.cache.Set(ctx, *b); err != nil {
+			r.logger.Warnw("Failed to refresh balance cache", "account_id", id, "error", err)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/kaminoclone/ledger-service/internal/balances"
+)
+
+func TestBalanceCacheSetIfNewer(t *testing.T) {
+	srv := miniredis.RunT(t)
+	c := NewBalanceCache(redis.NewClient(&redis.Options{Addr: srv.Addr()}), time.Minute)
+	ctx := context.Background()
+	id := "7d1c1b8e-3f4a-4c55-9b1e-2f7c3a9d0e11"
+
+	if b, err := c.Get(ctx, id); err != nil || b != nil {
+		t.Fatalf("expected miss, got %+v, %v", b, err)
+	}
+
+	newer := balances.Balance{AccountID: id, Balance: 5000, AvailableBalance: 5000, LastSequenceNumber: 42, Version: 7}
+	if ok, err := c.Set(ctx, newer); err != nil || !ok {
+		t.Fatalf("Set(newer) = %v, %v", ok, err)
+	}
+
+	older := balances.Balance{AccountID: id, Balance: 1000, AvailableBalance: 1000, LastSequenceNumber: 41, Version: 6}
+	if ok, err := c.Set(ctx, older); err != nil || ok {
+		t.Fatalf("Set(older) = %v, %v; want rejected", ok, err)
+	}
+
+	// Mesma sequência, versão maior: bloqueio mudou o disponível sem lançamento
+	held := balances.Balance{AccountID: id, Balance: 5000, HeldAmount: 2000, AvailableBalance: 3000, LastSequenceNumber: 42, Version: 8}
+	if ok, err := c.Set(ctx, held); err != nil || !ok {
+		t.Fatalf("Set(held) = %v, %v", ok, err)
+	}
+
+	// Refresh atrasado que leu antes do bloqueio: mesma sequência, versão anterior
+	if ok, err := c.Set(ctx, newer); err != nil || ok {
+		t.Fatalf("Set(stale, same sequence) = %v, %v; want rejected", ok, err)
+	}
+
+	// Mesma versão é o mesmo saldo e não regrava
+	if ok, err := c.Set(ctx, held); err != nil || ok {
+		t.Fatalf("Set(same version) = %v, %v; want rejected", ok, err)
+	}
+
+	got, err := c.Get(ctx, id)
+	if err != nil || got == nil || got.AvailableBalance != 3000 {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if ttl := srv.TTL(key(id)); ttl != time.Minute {
+		t.Errorf("ttl = %v", ttl)
+	}
+}
@@ -0,0 +1,110 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Reconstrução de agregados por replay e snapshots
+// ============================================================================
+
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Aggregate é um estado reconstruído aplicando seus eventos em ordem.
+// O estado precisa ser serializável em JSON para ser guardado em snapshot.
+type Aggregate interface {
+	Apply(e Event) error
+}
+
+// LoadAggregate parte do snapshot mais recente (se houver), aplica os eventos
+// posteriores e devolve a versão resultante (0 = agregado inexistente).
+func (s *Store) LoadAggregate(ctx context.Context, q queryer, stream Stream, agg Aggregate) (int64, error) {
+	version, err := s.loadSnapshot(ctx, q, stream, agg)
+	if err != nil {
+		return 0, err
+	}
+
+	events, err := s.Load(ctx, q, stream, version)
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range events {
+		if err := agg.Apply(e); err != nil {
+			return 0, fmt.Errorf("replay %s v%d: %w", stream, e.Version, err)
+		}
+		version = e.Version
+	}
+	return version, nil
+}
+
+// Save grava os eventos com concorrência otimista, aplica-os ao agregado e
+// grava um snapshot sempre que a versão cruza um múltiplo de snapshotEvery.
+func (s *Store) Save(ctx context.Context, tx *sql.Tx, stream Stream, agg Aggregate, expectedVersion int64, data ...EventData) (int64, error) {
+	events, err := s.Append(ctx, tx, stream, expectedVersion, data...)
+	if err != nil {
+		return 0, err
+	}
+
+	version := expectedVersion
+	for _, e := range events {
+		if err := agg.Apply(e); err != nil {
+			return 0, fmt.Errorf("apply %s v%d: %w", stream, e.Version, err)
+		}
+		version = e.Version
+	}
+
+	if crossesSnapshot(expectedVersion, version, s.snapshotEvery) {
+		if err := s.saveSnapshot(ctx, tx, stream, version, agg); err != nil {
+			return 0, err
+		}
+	}
+	return version, nil
+}
+
+func crossesSnapshot(from, to, every int64) bool {
+	return every > 0 && to/every > from/every
+}
+
+func (s *Store) loadSnapshot(ctx context.Context, q queryer, stream Stream, agg Aggregate) (int64, error) {
+	var version int64
+	var state []byte
+	err := q.QueryRowContext(ctx, `
+		SELECT aggregate_version, state
+		FROM event_sourcing.aggregate_snapshots
+		WHERE aggregate_type = $1 AND aggregate_id = $2
+	`, stream.Type, stream.ID).Scan(&version, &state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load %s snapshot: %w", stream, err)
+	}
+	if err := json.Unmarshal(state, agg); err != nil {
+		return 0, fmt.Errorf("decode %s snapshot: %w", stream, err)
+	}
+	return version, nil
+}
+
+// saveSnapshot mantém apenas o snapshot mais recente de cada agregado
+func (s *Store) saveSnapshot(ctx context.Context, tx *sql.Tx, stream Stream, version int64, agg Aggregate) error {
+	state, err := json.Marshal(agg)
+	if err != nil {
+		return fmt.Errorf("encode %s snapshot: %w", stream, err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO event_sourcing.aggregate_snapshots (aggregate_type, aggregate_id, aggregate_version, state)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (aggregate_type, aggregate_id) DO UPDATE
+		SET aggregate_version = EXCLUDED.aggregate_version,
+		    state = EXCLUDED.state,
+		    created_at = NOW()
+		WHERE event_sourcing.aggregate_snapshots.aggregate_version < EXCLUDED.aggregate_version
+	`, stream.Type, stream.ID, version, state)
+	if err != nil {
+		return fmt.Errorf("save %s snapshot: %w", stream, err)
+	}
+	return nil
+}
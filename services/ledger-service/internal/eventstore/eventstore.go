@@ -0,0 +1,211 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Event store (event_sourcing.event_store) com concorrência otimista
+// ============================================================================
+
+package eventstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Erros do event store
+var (
+	ErrConcurrencyConflict = errors.New("aggregate was modified concurrently")
+	ErrInvalidEvent        = errors.New("invalid event")
+)
+
+// DefaultSnapshotEvery é o intervalo padrão (em versões) entre snapshots de um agregado
+const DefaultSnapshotEvery = 50
+
+// Stream identifica o fluxo de eventos de um agregado
+type Stream struct {
+	Type string
+	ID   string
+}
+
+func (s Stream) String() string {
+	return s.Type + "/" + s.ID
+}
+
+// EventData é um evento ainda não gravado
+type EventData struct {
+	Type     string
+	Version  int // versão do formato do payload
+	Payload  interface{}
+	Metadata map[string]interface{}
+}
+
+// Event é um evento gravado; Version é a versão do agregado após aplicá-lo
+type Event struct {
+	ID            string          `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Type          string          `json:"event_type"`
+	EventVersion  int             `json:"event_version"`
+	Version       int64           `json:"aggregate_version"`
+	Payload       json.RawMessage `json:"payload"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Decode desserializa o payload do evento
+func (e Event) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return fmt.Errorf("decode %s v%d: %w", e.Type, e.EventVersion, err)
+	}
+	return nil
+}
+
+// Store acessa event_sourcing.event_store e event_sourcing.aggregate_snapshots
+type Store struct {
+	db            *sql.DB
+	snapshotEvery int64
+}
+
+// NewStore cria um Store; snapshotEvery <= 0 desativa snapshots
+func NewStore(db *sql.DB, snapshotEvery int) *Store {
+	return &Store{db: db, snapshotEvery: int64(snapshotEvery)}
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Version devolve a versão atual do agregado (0 se não houver eventos)
+func (s *Store) Version(ctx context.Context, q queryer, stream Stream) (int64, error) {
+	var version int64
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(aggregate_version), 0)
+		FROM event_sourcing.event_store
+		WHERE aggregate_type = $1 AND aggregate_id = $2
+	`, stream.Type, stream.ID).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("load %s version: %w", stream, err)
+	}
+	return version, nil
+}
+
+// Append grava os eventos se a versão atual do agregado for expectedVersion.
+// Uma corrida entre duas transações é resolvida pela constraint
+// event_store_version_unique: a segunda recebe ErrConcurrencyConflict.
+func (s *Store) Append(ctx context.Context, tx *sql.Tx, stream Stream, expectedVersion int64, data ...EventData) ([]Event, error) {
+	current, err := s.Version(ctx, tx, stream)
+	if err != nil {
+		return nil, err
+	}
+	if current != expectedVersion {
+		return nil, fmt.Errorf("%w: %s expected version %d, current %d",
+			ErrConcurrencyConflict, stream, expectedVersion, current)
+	}
+
+	events := make([]Event, 0, len(data))
+	for i, d := range data {
+		if d.Type == "" {
+			return nil, fmt.Errorf("%w: event type is required", ErrInvalidEvent)
+		}
+		payload, err := json.Marshal(d.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("%w: encode %s payload: %v", ErrInvalidEvent, d.Type, err)
+		}
+		metadata := d.Metadata
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("%w: encode %s metadata: %v", ErrInvalidEvent, d.Type, err)
+		}
+		eventVersion := d.Version
+		if eventVersion == 0 {
+			eventVersion = 1
+		}
+
+		e := Event{
+			AggregateType: stream.Type,
+			AggregateID:   stream.ID,
+			Type:          d.Type,
+			EventVersion:  eventVersion,
+			Version:       expectedVersion + int64(i) + 1,
+			Payload:       payload,
+			Metadata:      metadataJSON,
+		}
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO event_sourcing.event_store (
+				aggregate_type, aggregate_id, event_type, event_version,
+				aggregate_version, payload, metadata
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at
+		`,
+			e.AggregateType, e.AggregateID, e.Type, e.EventVersion,
+			e.Version, payload, metadataJSON,
+		).Scan(&e.ID, &e.CreatedAt)
+		if err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				return nil, fmt.Errorf("%w: %s version %d already exists", ErrConcurrencyConflict, stream, e.Version)
+			}
+			return nil, fmt.Errorf("append %s to %s: %w", d.Type, stream, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// Load devolve os eventos do agregado com versão maior que afterVersion, em ordem
+func (s *Store) Load(ctx context.Context, q queryer, stream Stream, afterVersion int64) ([]Event, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, aggregate_type, aggregate_id, event_type, event_version,
+		       aggregate_version, payload, COALESCE(metadata, '{}'), created_at
+		FROM event_sourcing.event_store
+		WHERE aggregate_type = $1 AND aggregate_id = $2 AND aggregate_version > $3
+		ORDER BY aggregate_version
+	`, stream.Type, stream.ID, afterVersion)
+	if err != nil {
+		return nil, fmt.Errorf("load %s events: %w", stream, err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load %s events: %w", stream, err)
+	}
+	return events, nil
+}
+
+// History devolve todos os eventos do agregado, do primeiro ao último
+func (s *Store) History(ctx context.Context, stream Stream) ([]Event, error) {
+	return s.Load(ctx, s.db, stream, 0)
+}
+
+func scanEvent(row scanner) (*Event, error) {
+	var e Event
+	var payload, metadata []byte
+	err := row.Scan(&e.ID, &e.AggregateType, &e.AggregateID, &e.Type, &e.EventVersion,
+		&e.Version, &payload, &metadata, &e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("scan event: %w", err)
+	}
+	e.Payload = payload
+	e.Metadata = metadata
+	return &e, nil
+}
@@ -0,0 +1,41 @@
+package eventstore
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCrossesSnapshot(t *testing.T) {
+	cases := []struct {
+		from, to, every int64
+		want            bool
+	}{
+		{0, 1, 50, false},
+		{48, 49, 50, false},
+		{49, 50, 50, true},
+		{49, 51, 50, true},
+		{50, 51, 50, false},
+		{98, 102, 50, true},
+		{10, 20, 0, false},
+	}
+	for _, tc := range cases {
+		if got := crossesSnapshot(tc.from, tc.to, tc.every); got != tc.want {
+			t.Errorf("crossesSnapshot(%d, %d, %d) = %v, want %v", tc.from, tc.to, tc.every, got, tc.want)
+		}
+	}
+}
+
+func TestEventDecode(t *testing.T) {
+	e := Event{Type: "AccountOpened", EventVersion: 1, Payload: json.RawMessage(`{"name":"Conta"}`)}
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := e.Decode(&p); err != nil || p.Name != "Conta" {
+		t.Fatalf("Decode = %+v, %v", p, err)
+	}
+
+	e.Payload = json.RawMessage(`{`)
+	if err := e.Decode(&p); err == nil {
+		t.Fatal("expected error for malformed payload")
+	}
+}
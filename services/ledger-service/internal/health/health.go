@@ -0,0 +1,174 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Verificação de dependências para o readiness probe
+// ============================================================================
+
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
+)
+
+var dependencyUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "ledger_dependency_up",
+	Help: "Whether the last readiness probe of the dependency succeeded (1) or failed (0)",
+}, []string{"dependency"})
+
+// Estados do relatório
+const (
+	StatusReady    = "ready"
+	StatusDegraded = "degraded"  // dependência não crítica fora do ar
+	StatusNotReady = "not_ready" // dependência crítica fora do ar
+)
+
+// DefaultTimeout é o tempo máximo de cada probe
+const DefaultTimeout = 2 * time.Second
+
+// Probe testa uma dependência
+type Probe func(ctx context.Context) error
+
+// Check é uma dependência verificada pelo readiness probe.
+// Critical = a instância não deve receber tráfego sem ela.
+type Check struct {
+	Name     string
+	Critical bool
+	Timeout  time.Duration
+	Probe    Probe
+}
+
+// Result é o resultado de um Check
+type Result struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report é o resultado de todos os checks
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready informa se nenhuma dependência crítica falhou
+func (r Report) Ready() bool {
+	return r.Status != StatusNotReady
+}
+
+// Checker executa os checks em paralelo
+type Checker struct {
+	checks []Check
+}
+
+// NewChecker cria um Checker com as dependências informadas
+func NewChecker(checks ...Check) *Checker {
+	return &Checker{checks: checks}
+}
+
+// Run executa todos os probes, cada um com seu timeout
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+		}(check)
+	}
+	wg.Wait()
+
+	for _, check := range c.checks {
+		if report.Checks[check.Name].Status == "ok" {
+			continue
+		}
+		if check.Critical {
+			report.Status = StatusNotReady
+		} else if report.Status == StatusReady {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func run(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Probe(ctx)
+	result := Result{
+		Status:    "ok",
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "error"
+		result.Error = err.Error()
+		dependencyUp.WithLabelValues(check.Name).Set(0)
+	} else {
+		dependencyUp.WithLabelValues(check.Name).Set(1)
+	}
+	return result
+}
+
+// PostgresProbe executa uma consulta trivial (Ping sozinho pode reutilizar uma conexão ociosa)
+func PostgresProbe(db *sql.DB) Probe {
+	return func(ctx context.Context) error {
+		var one int
+		return db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	}
+}
+
+// RedisProbe envia PING
+func RedisProbe(client *redis.Client) Probe {
+	return func(ctx context.Context) error {
+		return client.Ping(ctx).Err()
+	}
+}
+
+// KafkaProbe conecta a um dos brokers e lê os metadados do cluster
+func KafkaProbe(brokers []string) Probe {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, broker := range brokers {
+			conn, err := (&kafka.Dialer{}).DialContext(ctx, "tcp", broker)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if deadline, ok := ctx.Deadline(); ok {
+				conn.SetDeadline(deadline)
+			}
+			_, err = conn.Brokers()
+			conn.Close()
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		if len(errs) == 0 {
+			return errors.New("no brokers configured")
+		}
+		return errors.Join(errs...)
+	}
+}
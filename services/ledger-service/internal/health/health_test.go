@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerRun(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hang := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	cases := []struct {
+		name   string
+		checks []Check
+		want   string
+	}{
+		{"all up", []Check{{Name: "database", Critical: true, Probe: ok}, {Name: "redis", Probe: ok}}, StatusReady},
+		{"optional down", []Check{{Name: "database", Critical: true, Probe: ok}, {Name: "redis", Probe: down}}, StatusDegraded},
+		{"critical down", []Check{{Name: "database", Critical: true, Probe: down}, {Name: "redis", Probe: ok}}, StatusNotReady},
+		{"critical timeout", []Check{{Name: "database", Critical: true, Timeout: 10 * time.Millisecond, Probe: hang}}, StatusNotReady},
+	}
+	for _, tc := range cases {
+		report := NewChecker(tc.checks...).Run(context.Background())
+		if report.Status != tc.want {
+			t.Errorf("%s: status = %s, want %s", tc.name, report.Status, tc.want)
+		}
+		if len(report.Checks) != len(tc.checks) {
+			t.Errorf("%s: %d results for %d checks", tc.name, len(report.Checks), len(tc.checks))
+		}
+		if report.Ready() != (tc.want != StatusNotReady) {
+			t.Errorf("%s: Ready() = %v", tc.name, report.Ready())
+		}
+	}
+}
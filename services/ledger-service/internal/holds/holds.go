@@ -0,0 +1,155 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Bloqueios de saldo (core.balance_holds): autorização, captura e liberação
+// ============================================================================
+
+package holds
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// Status espelha o CHECK de core.balance_holds.status
+type Status string
+
+const (
+	StatusActive   Status = "ACTIVE"
+	StatusReleased Status = "RELEASED"
+	StatusCaptured Status = "CAPTURED"
+	StatusExpired  Status = "EXPIRED"
+)
+
+const (
+	// DefaultTTL é a validade de um bloqueio quando expires_at não é informado
+	DefaultTTL = 7 * 24 * time.Hour
+	// MaxTTL limita por quanto tempo um saldo pode ficar reservado
+	MaxTTL = 30 * 24 * time.Hour
+)
+
+// Erros de bloqueios
+var (
+	ErrNotFound           = errors.New("hold not found")
+	ErrInvalidRequest     = errors.New("invalid hold request")
+	ErrDuplicateReference = errors.New("hold reference already used for this account")
+	ErrNotActive          = errors.New("hold is not active")
+	ErrExpired            = errors.New("hold has expired")
+	ErrCaptureExceeded    = errors.New("capture amount exceeds the held amount")
+)
+
+// Hold representa uma linha de core.balance_holds
+type Hold struct {
+	ID                    string       `json:"id"`
+	AccountID             string       `json:"account_id"`
+	Amount                ledger.Money `json:"amount"`
+	Reason                string       `json:"reason"`
+	ReferenceID           string       `json:"reference_id"`
+	Status                Status       `json:"status"`
+	ExpiresAt             time.Time    `json:"expires_at"`
+	ReleasedAt            *time.Time   `json:"released_at,omitempty"`
+	ReleasedReason        string       `json:"released_reason,omitempty"`
+	CapturedTransactionID *string      `json:"captured_transaction_id,omitempty"`
+	CreatedAt             time.Time    `json:"created_at"`
+}
+
+// PlaceRequest reserva parte do saldo disponível de uma conta
+type PlaceRequest struct {
+	AccountID   string       `json:"-"`
+	Amount      ledger.Money `json:"amount"`
+	Reason      string       `json:"reason"`
+	ReferenceID string       `json:"reference_id"`
+	ExpiresAt   *time.Time   `json:"expires_at,omitempty"`
+}
+
+// Validate normaliza e valida a requisição de bloqueio
+func (r *PlaceRequest) Validate(now time.Time) error {
+	r.AccountID = strings.ToLower(strings.TrimSpace(r.AccountID))
+	r.Reason = strings.TrimSpace(r.Reason)
+	r.ReferenceID = strings.TrimSpace(r.ReferenceID)
+	if r.ExpiresAt == nil {
+		expiresAt := now.Add(DefaultTTL)
+		r.ExpiresAt = &expiresAt
+	}
+
+	switch {
+	case !ledger.IsUUID(r.AccountID):
+		return fmt.Errorf("%w: account_id must be a UUID", ErrInvalidRequest)
+	case r.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+	case r.Reason == "" || len(r.Reason) > 255:
+		return fmt.Errorf("%w: reason must have 1-255 characters", ErrInvalidRequest)
+	case r.ReferenceID == "" || len(r.ReferenceID) > 100:
+		return fmt.Errorf("%w: reference_id must have 1-100 characters", ErrInvalidRequest)
+	case !r.ExpiresAt.After(now):
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidRequest)
+	case r.ExpiresAt.Sub(now) > MaxTTL:
+		return fmt.Errorf("%w: expires_at must be within %s", ErrInvalidRequest, MaxTTL)
+	}
+	return nil
+}
+
+// CaptureRequest converte o bloqueio em uma transação do ledger.
+// O valor reservado é debitado da conta do bloqueio e creditado em DestinationAccountID;
+// Amount nil captura o valor total, e qualquer sobra é devolvida ao saldo disponível.
+type CaptureRequest struct {
+	ReferenceID          string                 `json:"-"`
+	DestinationAccountID string                 `json:"destination_account_id"`
+	Amount               *ledger.Money          `json:"amount,omitempty"`
+	TransactionType      string                 `json:"transaction_type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Metadata             map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// posting monta o lançamento de captura sobre o bloqueio
+func (r CaptureRequest) posting(h *Hold, currency string) (ledger.PostingRequest, error) {
+	destination := strings.ToLower(strings.TrimSpace(r.DestinationAccountID))
+	if !ledger.IsUUID(destination) {
+		return ledger.PostingRequest{}, fmt.Errorf("%w: destination_account_id must be a UUID", ErrInvalidRequest)
+	}
+	amount := h.Amount
+	if r.Amount != nil {
+		amount = *r.Amount
+	}
+	if amount <= 0 {
+		return ledger.PostingRequest{}, fmt.Errorf("%w: amount must be positive", ErrInvalidRequest)
+	}
+	if amount > h.Amount {
+		return ledger.PostingRequest{}, fmt.Errorf("%w: requested %s, held %s", ErrCaptureExceeded, amount, h.Amount)
+	}
+
+	txType := r.TransactionType
+	if txType == "" {
+		txType = "HOLD_CAPTURE"
+	}
+	description := r.Description
+	if strings.TrimSpace(description) == "" {
+		description = "Captura do bloqueio " + h.ReferenceID + ": " + h.Reason
+	}
+	metadata := map[string]interface{}{}
+	for k, v := range r.Metadata {
+		metadata[k] = v
+	}
+	metadata["hold_id"] = h.ID
+
+	return ledger.PostingRequest{
+		ReferenceID:     r.ReferenceID,
+		TransactionType: txType,
+		Description:     description,
+		Currency:        currency,
+		Metadata:        metadata,
+		Legs: []ledger.Leg{
+			{AccountID: h.AccountID, EntryType: ledger.EntryDebit, Amount: amount},
+			{AccountID: destination, EntryType: ledger.EntryCredit, Amount: amount},
+		},
+	}, nil
+}
+
+// CaptureResult devolve o bloqueio capturado e a transação gerada
+type CaptureResult struct {
+	Hold        *Hold               `json:"hold"`
+	Transaction *ledger.Transaction `json:"transaction"`
+}
@@ -0,0 +1,62 @@
+package holds
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+func TestPlaceRequestValidate(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	req := PlaceRequest{
+		AccountID:   "7D1C1B8E-3F4A-4C55-9B1E-2F7C3A9D0E11",
+		Amount:      5000,
+		Reason:      "card authorization",
+		ReferenceID: "auth-123",
+	}
+	if err := req.Validate(now); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	if !req.ExpiresAt.Equal(now.Add(DefaultTTL)) {
+		t.Fatalf("expires_at default = %v", req.ExpiresAt)
+	}
+
+	past := now.Add(-time.Minute)
+	req.ExpiresAt = &past
+	if err := req.Validate(now); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest for past expiry, got %v", err)
+	}
+}
+
+func TestCapturePosting(t *testing.T) {
+	hold := &Hold{
+		ID:          "hold-1",
+		AccountID:   "7d1c1b8e-3f4a-4c55-9b1e-2f7c3a9d0e11",
+		Amount:      5000,
+		Reason:      "card authorization",
+		ReferenceID: "auth-123",
+	}
+	req := CaptureRequest{ReferenceID: "cap-1", DestinationAccountID: "0a6f5e2d-1c3b-4a99-8d7e-6f5a4b3c2d1e"}
+
+	posting, err := req.posting(hold, "BRL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if posting.Amount() != 5000 || posting.Metadata["hold_id"] != "hold-1" {
+		t.Fatalf("unexpected posting: %+v", posting)
+	}
+
+	partial := ledger.Money(1200)
+	req.Amount = &partial
+	if posting, _ = req.posting(hold, "BRL"); posting.Amount() != 1200 {
+		t.Fatalf("partial capture amount = %d", posting.Amount())
+	}
+
+	over := ledger.Money(5001)
+	req.Amount = &over
+	if _, err := req.posting(hold, "BRL"); !errors.Is(err, ErrCaptureExceeded) {
+		t.Fatalf("expected ErrCaptureExceeded, got %v", err)
+	}
+}
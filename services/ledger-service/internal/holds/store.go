@@ -0,0 +1,379 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Persistência de bloqueios de saldo em PostgreSQL
+// ============================================================================
+
+package holds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// Store mantém core.balance_holds e os saldos reservados em core.accounts.
+//
+// Um bloqueio ACTIVE move o valor de available_balance para blocked_balance;
+// qualquer resolução (RELEASED, CAPTURED, EXPIRED) devolve o valor antes de
+// aplicar o efeito final. A ordem de bloqueio é sempre hold -> contas.
+type Store struct {
+	db     *sql.DB
+	ledger *ledger.Store
+}
+
+// NewStore cria um Store; capturas são lançadas através do ledger informado
+func NewStore(db *sql.DB, ledgerStore *ledger.Store) *Store {
+	return &Store{db: db, ledger: ledgerStore}
+}
+
+const selectHold = `
+	SELECT id, account_id, amount, reason, reference_id, status, expires_at,
+	       released_at, COALESCE(released_reason, ''), captured_transaction_id, created_at
+	FROM core.balance_holds
+`
+
+// Place reserva o valor no saldo disponível da conta
+func (s *Store) Place(ctx context.Context, req PlaceRequest) (*Hold, error) {
+	if err := req.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	var available ledger.Money
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, available_balance FROM core.accounts WHERE id = $1 FOR UPDATE
+	`, req.AccountID).Scan(&status, &available)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ledger.ErrAccountNotFound, req.AccountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock account: %w", err)
+	}
+	if status != "ACTIVE" {
+		return nil, fmt.Errorf("%w: account %s is %s", ledger.ErrAccountNotActive, req.AccountID, status)
+	}
+	if available < req.Amount {
+		return nil, fmt.Errorf("%w: available %s, requested %s", ledger.ErrInsufficientFunds, available, req.Amount)
+	}
+
+	var id string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO core.balance_holds (account_id, amount, reason, reference_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, req.AccountID, req.Amount, req.Reason, req.ReferenceID, *req.ExpiresAt).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateReference, req.ReferenceID)
+		}
+		return nil, fmt.Errorf("insert hold: %w", err)
+	}
+
+	if err := adjustReservation(ctx, tx, req.AccountID, req.Amount); err != nil {
+		return nil, err
+	}
+
+	hold, err := getHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return hold, nil
+}
+
+// Get carrega um bloqueio pelo ID
+func (s *Store) Get(ctx context.Context, id string) (*Hold, error) {
+	return getHold(ctx, s.db, id)
+}
+
+// ListByAccount lista os bloqueios de uma conta, opcionalmente filtrados por status
+func (s *Store) ListByAccount(ctx context.Context, accountID string, status Status) ([]Hold, error) {
+	if !ledger.IsUUID(accountID) {
+		return nil, fmt.Errorf("%w: %s", ledger.ErrAccountNotFound, accountID)
+	}
+
+	rows, err := s.db.QueryContext(ctx, selectHold+`
+		WHERE account_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT 500
+	`, accountID, string(status))
+	if err != nil {
+		return nil, fmt.Errorf("list holds: %w", err)
+	}
+	defer rows.Close()
+
+	holds := []Hold{}
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, *hold)
+	}
+	return holds, rows.Err()
+}
+
+// Release cancela o bloqueio e devolve o valor ao saldo disponível
+func (s *Store) Release(ctx context.Context, id, reason string) (*Hold, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" || len(reason) > 255 {
+		return nil, fmt.Errorf("%w: reason must have 1-255 characters", ErrInvalidRequest)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hold, err := lockHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != StatusActive {
+		return nil, fmt.Errorf("%w: status is %s", ErrNotActive, hold.Status)
+	}
+
+	if err := adjustReservation(ctx, tx, hold.AccountID, -hold.Amount); err != nil {
+		return nil, err
+	}
+	if err := resolveHold(ctx, tx, []string{hold.ID}, StatusReleased, reason, ""); err != nil {
+		return nil, err
+	}
+
+	hold, err = getHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return hold, nil
+}
+
+// CaptureTx libera a reserva e lança a transação correspondente na mesma transação SQL
+func (s *Store) CaptureTx(ctx context.Context, tx *sql.Tx, id string, req CaptureRequest) (*CaptureResult, error) {
+	hold, err := lockHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status != StatusActive {
+		return nil, fmt.Errorf("%w: status is %s", ErrNotActive, hold.Status)
+	}
+	if !hold.ExpiresAt.After(time.Now()) {
+		return nil, ErrExpired
+	}
+
+	// Bloquear as duas contas na mesma ordem usada pelo ledger
+	var currency string
+	err = tx.QueryRowContext(ctx, `
+		SELECT currency FROM core.accounts WHERE id = $1
+	`, hold.AccountID).Scan(&currency)
+	if err != nil {
+		return nil, fmt.Errorf("load account: %w", err)
+	}
+	posting, err := req.posting(hold, currency)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockAccountIDs(ctx, tx, hold.AccountID, posting.Legs[1].AccountID); err != nil {
+		return nil, err
+	}
+
+	// Devolver a reserva integral; a captura debita apenas o valor capturado
+	if err := adjustReservation(ctx, tx, hold.AccountID, -hold.Amount); err != nil {
+		return nil, err
+	}
+	txn, err := s.ledger.PostTx(ctx, tx, posting)
+	if err != nil {
+		return nil, err
+	}
+
+	releasedReason := "captured"
+	if captured := posting.Amount(); captured < hold.Amount {
+		releasedReason = fmt.Sprintf("captured %s of %s", captured, hold.Amount)
+	}
+	if err := resolveHold(ctx, tx, []string{hold.ID}, StatusCaptured, releasedReason, txn.ID); err != nil {
+		return nil, err
+	}
+
+	hold, err = getHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &CaptureResult{Hold: hold, Transaction: txn}, nil
+}
+
+// ExpireDue expira até limit bloqueios vencidos e devolve quantos foram
+// expirados e as contas cujo disponível foi liberado.
+// SKIP LOCKED permite várias réplicas executando o sweeper ao mesmo tempo.
+func (s *Store) ExpireDue(ctx context.Context, limit int) (int, []string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, account_id, amount
+		FROM core.balance_holds
+		WHERE status = 'ACTIVE' AND expires_at <= NOW()
+		ORDER BY expires_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, nil, fmt.Errorf("select expired holds: %w", err)
+	}
+
+	var ids []string
+	released := make(map[string]ledger.Money)
+	for rows.Next() {
+		var id, accountID string
+		var amount ledger.Money
+		if err := rows.Scan(&id, &accountID, &amount); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("scan expired hold: %w", err)
+		}
+		ids = append(ids, id)
+		released[accountID] += amount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("select expired holds: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil, nil
+	}
+
+	accountIDs := make([]string, 0, len(released))
+	for accountID := range released {
+		accountIDs = append(accountIDs, accountID)
+	}
+	sort.Strings(accountIDs)
+	for _, accountID := range accountIDs {
+		if err := adjustReservation(ctx, tx, accountID, -released[accountID]); err != nil {
+			return 0, nil, err
+		}
+	}
+	if err := resolveHold(ctx, tx, ids, StatusExpired, "expired", ""); err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return len(ids), accountIDs, nil
+}
+
+// adjustReservation move delta de available_balance para blocked_balance (delta negativo devolve)
+func adjustReservation(ctx context.Context, tx *sql.Tx, accountID string, delta ledger.Money) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE core.accounts
+		SET available_balance = available_balance - $2,
+		    blocked_balance = blocked_balance + $2
+		WHERE id = $1
+	`, accountID, delta)
+	if err != nil {
+		return fmt.Errorf("update reserved balance: %w", err)
+	}
+	return nil
+}
+
+// resolveHold grava o estado terminal; released_at é preenchido em toda resolução
+func resolveHold(ctx context.Context, tx *sql.Tx, ids []string, status Status, reason, transactionID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE core.balance_holds
+		SET status = $2,
+		    released_at = NOW(),
+		    released_reason = $3,
+		    captured_transaction_id = NULLIF($4, '')::uuid
+		WHERE id = ANY($1)
+	`, pq.Array(ids), string(status), reason, transactionID)
+	if err != nil {
+		return fmt.Errorf("update hold status: %w", err)
+	}
+	return nil
+}
+
+func lockAccountIDs(ctx context.Context, tx *sql.Tx, ids ...string) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id FROM core.accounts WHERE id = ANY($1) ORDER BY id FOR UPDATE
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("lock accounts: %w", err)
+	}
+	return rows.Close()
+}
+
+func lockHold(ctx context.Context, tx *sql.Tx, id string) (*Hold, error) {
+	if !ledger.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+	hold, err := scanHold(tx.QueryRowContext(ctx, selectHold+" WHERE id = $1 FOR UPDATE", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return hold, err
+}
+
+// queryer abstrai *sql.DB e *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func getHold(ctx context.Context, q queryer, id string) (*Hold, error) {
+	if !ledger.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+	hold, err := scanHold(q.QueryRowContext(ctx, selectHold+" WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return hold, err
+}
+
+func scanHold(row scanner) (*Hold, error) {
+	var h Hold
+	var releasedAt sql.NullTime
+	var capturedTransactionID sql.NullString
+
+	err := row.Scan(
+		&h.ID, &h.AccountID, &h.Amount, &h.Reason, &h.ReferenceID, &h.Status, &h.ExpiresAt,
+		&releasedAt, &h.ReleasedReason, &capturedTransactionID, &h.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan hold: %w", err)
+	}
+	if releasedAt.Valid {
+		h.ReleasedAt = &releasedAt.Time
+	}
+	if capturedTransactionID.Valid {
+		h.CapturedTransactionID = &capturedTransactionID.String
+	}
+	return &h, nil
+}
@@ -0,0 +1,76 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Expiração periódica de bloqueios vencidos
+// ============================================================================
+
+package holds
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const sweepBatchSize = 500
+
+// Sweeper expira bloqueios ACTIVE cujo expires_at já passou
+type Sweeper struct {
+	store    *Store
+	interval time.Duration
+	logger   *zap.SugaredLogger
+	onExpire []func(ctx context.Context, accountIDs ...string)
+}
+
+// NewSweeper cria um sweeper que roda a cada interval
+func NewSweeper(store *Store, interval time.Duration, logger *zap.SugaredLogger) *Sweeper {
+	return &Sweeper{store: store, interval: interval, logger: logger}
+}
+
+// OnExpire registra um observador chamado após o commit de cada lote, com as
+// contas cujo disponível mudou (ex.: refresh do cache de saldos). Deve ser
+// chamado antes de Run.
+func (s *Sweeper) OnExpire(fn func(ctx context.Context, accountIDs ...string)) {
+	s.onExpire = append(s.onExpire, fn)
+}
+
+// Run executa até o contexto ser cancelado
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+// sweep processa lotes até não restarem bloqueios vencidos
+func (s *Sweeper) sweep(ctx context.Context) {
+	total := 0
+	for {
+		n, accountIDs, err := s.store.ExpireDue(ctx, sweepBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.logger.Errorw("Failed to expire balance holds", "error", err)
+			}
+			break
+		}
+		if len(accountIDs) > 0 {
+			for _, fn := range s.onExpire {
+				fn(ctx, accountIDs...)
+			}
+		}
+		total += n
+		if n < sweepBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		s.logger.Infow("Expired balance holds", "count", total)
+	}
+}
@@ -0,0 +1,121 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Armazenamento durável de chaves de idempotência (X-Idempotency-Key)
+// ============================================================================
+
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrFingerprintMismatch indica reuso da chave com um payload diferente
+var ErrFingerprintMismatch = errors.New("idempotency key already used with a different request")
+
+// Response é a resposta gravada junto com a chave e devolvida em replays
+type Response struct {
+	StatusCode    int
+	Body          []byte
+	TransactionID string
+}
+
+// Store persiste as chaves em core.idempotency_keys.
+// A chave é a mesma gravada em core.transactions.reference_id.
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre o pool de conexões informado
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Fingerprint calcula o SHA-256 de método, rota e corpo JSON canonicalizado.
+// A canonicalização ignora espaços e ordem de campos, preservando os números literais.
+func Fingerprint(method, route string, body []byte) (string, error) {
+	canonical := []byte{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+
+		var payload interface{}
+		if err := decoder.Decode(&payload); err != nil {
+			return "", fmt.Errorf("invalid JSON body: %w", err)
+		}
+		var err error
+		if canonical, err = json.Marshal(payload); err != nil {
+			return "", err
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(route))
+	h.Write([]byte{0})
+	h.Write(canonical)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Execute executa fn no máximo uma vez por chave.
+//
+// Requisições concorrentes com a mesma chave são serializadas por um advisory
+// lock transacional. Se a chave já foi concluída, a resposta original é devolvida
+// (replayed = true) sem executar fn; se o fingerprint diverge, retorna
+// ErrFingerprintMismatch. Erros de fn não são gravados, permitindo nova tentativa.
+func (s *Store) Execute(ctx context.Context, key, fingerprint string, fn func(tx *sql.Tx) (*Response, error)) (resp *Response, replayed bool, err error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, key); err != nil {
+		return nil, false, fmt.Errorf("acquire idempotency lock: %w", err)
+	}
+
+	var stored Response
+	var storedFingerprint string
+	var transactionID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT request_fingerprint, response_status, response_body, transaction_id
+		FROM core.idempotency_keys
+		WHERE idempotency_key = $1
+	`, key).Scan(&storedFingerprint, &stored.StatusCode, &stored.Body, &transactionID)
+	switch {
+	case err == nil:
+		if storedFingerprint != fingerprint {
+			return nil, false, ErrFingerprintMismatch
+		}
+		stored.TransactionID = transactionID.String
+		return &stored, true, nil
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, false, fmt.Errorf("load idempotency key: %w", err)
+	}
+
+	resp, err = fn(tx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO core.idempotency_keys (
+			idempotency_key, request_fingerprint, transaction_id, response_status, response_body
+		) VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5)
+	`, key, fingerprint, resp.TransactionID, resp.StatusCode, resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("store idempotency key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("commit transaction: %w", err)
+	}
+	return resp, false, nil
+}
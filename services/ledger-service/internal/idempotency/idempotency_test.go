@@ -0,0 +1,31 @@
+package idempotency
+
+import "testing"
+
+func TestFingerprintCanonicalizesBody(t *testing.T) {
+	a, err := Fingerprint("POST", "/v1/transactions", []byte(`{"description":"x","legs":[{"amount":10.50}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Fingerprint("POST", "/v1/transactions", []byte("{\n  \"legs\": [{\"amount\": 10.50}],\n  \"description\": \"x\"\n}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Fatal("fingerprint should ignore whitespace and field order")
+	}
+
+	c, _ := Fingerprint("POST", "/v1/transactions", []byte(`{"description":"x","legs":[{"amount":10.51}]}`))
+	if a == c {
+		t.Fatal("fingerprint should change when the amount changes")
+	}
+
+	d, _ := Fingerprint("POST", "/v1/transactions/:id/reverse", []byte(`{"description":"x","legs":[{"amount":10.50}]}`))
+	if a == d {
+		t.Fatal("fingerprint should be bound to the route")
+	}
+
+	if _, err := Fingerprint("POST", "/v1/transactions", []byte(`{broken`)); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
@@ -0,0 +1,266 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Verificação de integridade do ledger (débito = crédito, saldo = lançamentos)
+// ============================================================================
+
+package integrity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// ErrInvalidScope indica um intervalo de datas inválido
+var ErrInvalidScope = errors.New("invalid balance check scope")
+
+// Métricas expostas em /metrics para o Alertmanager
+var (
+	unbalancedTransactions = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ledger_unbalanced_transactions",
+		Help: "Transactions whose debits differ from credits (or without entries) in the last full check",
+	})
+	accountBalanceDrift = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ledger_account_balance_drift",
+		Help: "Accounts whose stored balance differs from the sum of their entries in the last full check",
+	})
+	lastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "ledger_balance_check_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful full ledger balance check",
+	})
+)
+
+// Scope restringe a verificação a um intervalo de partition_date [From, To).
+// Zero value verifica o ledger inteiro.
+type Scope struct {
+	From time.Time
+	To   time.Time
+}
+
+// IsFull informa se a verificação cobre todas as partições
+func (s Scope) IsFull() bool {
+	return s.From.IsZero() && s.To.IsZero()
+}
+
+// ParseScope interpreta os parâmetros from/to (YYYY-MM-DD, to inclusivo)
+// ou partition (YYYY-MM, um mês inteiro).
+func ParseScope(from, to, partition string) (Scope, error) {
+	var scope Scope
+	if partition != "" {
+		if from != "" || to != "" {
+			return scope, fmt.Errorf("%w: use either partition or from/to", ErrInvalidScope)
+		}
+		month, err := time.Parse("2006-01", partition)
+		if err != nil {
+			return scope, fmt.Errorf("%w: partition must be YYYY-MM", ErrInvalidScope)
+		}
+		return Scope{From: month, To: month.AddDate(0, 1, 0)}, nil
+	}
+
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return scope, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidScope)
+		}
+		scope.From = t
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return scope, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidScope)
+		}
+		scope.To = t.AddDate(0, 0, 1)
+	}
+	if !scope.From.IsZero() && !scope.To.IsZero() && !scope.From.Before(scope.To) {
+		return scope, fmt.Errorf("%w: from must not be after to", ErrInvalidScope)
+	}
+	return scope, nil
+}
+
+// args devolve os limites como parâmetros SQL (NULL = aberto)
+func (s Scope) args() (interface{}, interface{}) {
+	var from, to interface{}
+	if !s.From.IsZero() {
+		from = s.From.Format("2006-01-02")
+	}
+	if !s.To.IsZero() {
+		to = s.To.Format("2006-01-02")
+	}
+	return from, to
+}
+
+// UnbalancedTransaction é uma transação cujos lançamentos não fecham
+type UnbalancedTransaction struct {
+	TransactionID string       `json:"transaction_id"`
+	ReferenceID   string       `json:"reference_id"`
+	PartitionDate string       `json:"partition_date"`
+	TotalDebits   ledger.Money `json:"total_debits"`
+	TotalCredits  ledger.Money `json:"total_credits"`
+	Difference    ledger.Money `json:"difference"`
+	MissingLegs   bool         `json:"missing_entries,omitempty"`
+}
+
+// AccountDrift é uma conta cujo saldo gravado difere da soma dos lançamentos
+type AccountDrift struct {
+	AccountID       string       `json:"account_id"`
+	AccountNumber   string       `json:"account_number"`
+	StoredBalance   ledger.Money `json:"stored_balance"`
+	ComputedBalance ledger.Money `json:"computed_balance"`
+	Difference      ledger.Money `json:"difference"`
+}
+
+// Report é o resultado de uma verificação
+type Report struct {
+	Balanced               bool                    `json:"balanced"`
+	From                   string                  `json:"from,omitempty"`
+	To                     string                  `json:"to,omitempty"`
+	TotalDebits            ledger.Money            `json:"total_debits"`
+	TotalCredits           ledger.Money            `json:"total_credits"`
+	UnbalancedTransactions []UnbalancedTransaction `json:"unbalanced_transactions"`
+	AccountDrifts          []AccountDrift          `json:"account_drifts"`
+	CheckedAt              time.Time               `json:"checked_at"`
+	Duration               string                  `json:"duration"`
+}
+
+// Checker executa as verificações contra o PostgreSQL
+type Checker struct {
+	db *sql.DB
+}
+
+// NewChecker cria um Checker sobre o pool de conexões informado
+func NewChecker(db *sql.DB) *Checker {
+	return &Checker{db: db}
+}
+
+// Check verifica o escopo informado. Verificações completas atualizam as métricas.
+//
+// Saldos são cumulativos, então o recálculo por conta sempre usa todos os
+// lançamentos; o escopo apenas limita quais contas são verificadas às que
+// tiveram movimento no período.
+func (c *Checker) Check(ctx context.Context, scope Scope) (*Report, error) {
+	start := time.Now()
+
+	// Leitura consistente entre as consultas
+	tx, err := c.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	report := &Report{
+		UnbalancedTransactions: []UnbalancedTransaction{},
+		AccountDrifts:          []AccountDrift{},
+		CheckedAt:              start.UTC(),
+	}
+	if !scope.From.IsZero() {
+		report.From = scope.From.Format("2006-01-02")
+	}
+	if !scope.To.IsZero() {
+		report.To = scope.To.AddDate(0, 0, -1).Format("2006-01-02")
+	}
+	from, to := scope.args()
+
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount) FILTER (WHERE entry_type = 'DEBIT'), 0),
+		       COALESCE(SUM(amount) FILTER (WHERE entry_type = 'CREDIT'), 0)
+		FROM core.ledger_entries
+		WHERE ($1::date IS NULL OR partition_date >= $1::date)
+		  AND ($2::date IS NULL OR partition_date < $2::date)
+	`, from, to).Scan(&report.TotalDebits, &report.TotalCredits)
+	if err != nil {
+		return nil, fmt.Errorf("sum entries: %w", err)
+	}
+
+	if err := unbalanced(ctx, tx, from, to, report); err != nil {
+		return nil, err
+	}
+	if err := drifts(ctx, tx, scope, from, to, report); err != nil {
+		return nil, err
+	}
+
+	report.Balanced = report.TotalDebits == report.TotalCredits &&
+		len(report.UnbalancedTransactions) == 0 && len(report.AccountDrifts) == 0
+	report.Duration = time.Since(start).String()
+
+	if scope.IsFull() {
+		unbalancedTransactions.Set(float64(len(report.UnbalancedTransactions)))
+		accountBalanceDrift.Set(float64(len(report.AccountDrifts)))
+		lastSuccess.Set(float64(time.Now().Unix()))
+	}
+	return report, nil
+}
+
+// unbalanced lê core.transaction_balance_check e transações sem nenhum lançamento
+func unbalanced(ctx context.Context, tx *sql.Tx, from, to interface{}, report *Report) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT transaction_id, reference_id, partition_date::text,
+		       total_debits, total_credits, difference, FALSE
+		FROM core.transaction_balance_check
+		WHERE ($1::date IS NULL OR partition_date >= $1::date)
+		  AND ($2::date IS NULL OR partition_date < $2::date)
+		UNION ALL
+		SELECT t.id, t.reference_id, t.partition_date::text, 0, 0, 0, TRUE
+		FROM core.transactions t
+		WHERE ($1::date IS NULL OR t.partition_date >= $1::date)
+		  AND ($2::date IS NULL OR t.partition_date < $2::date)
+		  AND NOT EXISTS (SELECT 1 FROM core.ledger_entries le WHERE le.transaction_id = t.id)
+		ORDER BY 3, 1
+	`, from, to)
+	if err != nil {
+		return fmt.Errorf("check transactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u UnbalancedTransaction
+		if err := rows.Scan(&u.TransactionID, &u.ReferenceID, &u.PartitionDate,
+			&u.TotalDebits, &u.TotalCredits, &u.Difference, &u.MissingLegs); err != nil {
+			return fmt.Errorf("scan unbalanced transaction: %w", err)
+		}
+		report.UnbalancedTransactions = append(report.UnbalancedTransactions, u)
+	}
+	return rows.Err()
+}
+
+// drifts recalcula o saldo de cada conta (CREDIT soma, DEBIT subtrai) e compara com core.accounts
+func drifts(ctx context.Context, tx *sql.Tx, scope Scope, from, to interface{}, report *Report) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.id, a.account_number, a.balance, COALESCE(e.computed, 0)
+		FROM core.accounts a
+		LEFT JOIN (
+			SELECT account_id,
+			       SUM(CASE WHEN entry_type = 'CREDIT' THEN amount ELSE -amount END) AS computed
+			FROM core.ledger_entries
+			GROUP BY account_id
+		) e ON e.account_id = a.id
+		WHERE a.balance <> COALESCE(e.computed, 0)
+		  AND ($3 OR EXISTS (
+			SELECT 1 FROM core.ledger_entries s
+			WHERE s.account_id = a.id
+			  AND ($1::date IS NULL OR s.partition_date >= $1::date)
+			  AND ($2::date IS NULL OR s.partition_date < $2::date)
+		  ))
+		ORDER BY a.id
+	`, from, to, scope.IsFull())
+	if err != nil {
+		return fmt.Errorf("check account balances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d AccountDrift
+		if err := rows.Scan(&d.AccountID, &d.AccountNumber, &d.StoredBalance, &d.ComputedBalance); err != nil {
+			return fmt.Errorf("scan account drift: %w", err)
+		}
+		d.Difference = d.StoredBalance - d.ComputedBalance
+		report.AccountDrifts = append(report.AccountDrifts, d)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,38 @@
+package integrity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	scope, err := ParseScope("", "", "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.From.Format("2006-01-02") != "2026-02-01" || scope.To.Format("2006-01-02") != "2026-03-01" {
+		t.Fatalf("partition scope = %v..%v", scope.From, scope.To)
+	}
+
+	scope, err = ParseScope("2026-01-10", "2026-01-10", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope.To.Format("2006-01-02") != "2026-01-11" {
+		t.Fatalf("to must be inclusive, got %v", scope.To)
+	}
+
+	if scope, _ := ParseScope("", "", ""); !scope.IsFull() {
+		t.Fatal("empty parameters should check the full ledger")
+	}
+
+	for _, tc := range [][3]string{
+		{"2026-01-10", "2026-01-09", ""},
+		{"10/01/2026", "", ""},
+		{"2026-01-01", "", "2026-01"},
+	} {
+		if _, err := ParseScope(tc[0], tc[1], tc[2]); !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("ParseScope(%q) expected ErrInvalidScope, got %v", tc, err)
+		}
+	}
+}
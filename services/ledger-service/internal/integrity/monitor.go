@@ -0,0 +1,58 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Verificação periódica de integridade para alimentar os alertas
+// ============================================================================
+
+package integrity
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Monitor executa verificações completas em intervalos regulares
+type Monitor struct {
+	checker  *Checker
+	interval time.Duration
+	logger   *zap.SugaredLogger
+}
+
+// NewMonitor cria um monitor que roda a cada interval
+func NewMonitor(checker *Checker, interval time.Duration, logger *zap.SugaredLogger) *Monitor {
+	return &Monitor{checker: checker, interval: interval, logger: logger}
+}
+
+// Run executa até o contexto ser cancelado
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.check(ctx)
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	report, err := m.checker.Check(ctx, Scope{})
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Errorw("Ledger balance check failed", "error", err)
+		}
+		return
+	}
+	if !report.Balanced {
+		m.logger.Errorw("Ledger is out of balance",
+			"unbalanced_transactions", len(report.UnbalancedTransactions),
+			"account_drifts", len(report.AccountDrifts),
+			"total_debits", report.TotalDebits,
+			"total_credits", report.TotalCredits,
+		)
+	}
+}
@@ -0,0 +1,83 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Eventos de domínio gravados na outbox junto com cada lançamento
+// ============================================================================
+
+package ledger
+
+import (
+	"github.com/kaminoclone/ledger-service/internal/outbox"
+)
+
+// TransactionPosted é publicado em transactions.created
+type TransactionPosted struct {
+	TransactionID   string                 `json:"transaction_id"`
+	ReferenceID     string                 `json:"reference_id"`
+	TransactionType string                 `json:"transaction_type"`
+	Description     string                 `json:"description"`
+	Amount          Money                  `json:"amount"`
+	Currency        string                 `json:"currency"`
+	Status          TransactionStatus      `json:"status"`
+	ReversalOf      *string                `json:"reversal_of,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	Entries         []Entry                `json:"entries"`
+}
+
+// BalanceUpdated é publicado em accounts.balance-updates, um por lançamento
+type BalanceUpdated struct {
+	AccountID      string    `json:"account_id"`
+	TransactionID  string    `json:"transaction_id"`
+	EntryID        string    `json:"entry_id"`
+	EntryType      EntryType `json:"entry_type"`
+	Amount         Money     `json:"amount"`
+	BalanceBefore  Money     `json:"balance_before"`
+	BalanceAfter   Money     `json:"balance_after"`
+	Currency       string    `json:"currency"`
+	SequenceNumber int64     `json:"sequence_number"`
+}
+
+// postingEvents monta os eventos de uma transação gravada.
+// Atualizações de saldo usam a conta como chave para manter a ordem por conta.
+func postingEvents(txn *Transaction) []outbox.Event {
+	events := []outbox.Event{{
+		AggregateType: "Transaction",
+		AggregateID:   txn.ID,
+		EventType:     "TransactionPosted",
+		Topic:         outbox.TopicTransactionsCreated,
+		PartitionKey:  txn.ID,
+		Payload: TransactionPosted{
+			TransactionID:   txn.ID,
+			ReferenceID:     txn.ReferenceID,
+			TransactionType: txn.TransactionType,
+			Description:     txn.Description,
+			Amount:          txn.Amount,
+			Currency:        txn.Currency,
+			Status:          txn.Status,
+			ReversalOf:      txn.ReversalOf,
+			Metadata:        txn.Metadata,
+			Entries:         txn.Entries,
+		},
+	}}
+
+	for _, e := range txn.Entries {
+		events = append(events, outbox.Event{
+			AggregateType: "Account",
+			AggregateID:   e.AccountID,
+			EventType:     "BalanceUpdated",
+			Topic:         outbox.TopicAccountBalanceUpdates,
+			PartitionKey:  e.AccountID,
+			Payload: BalanceUpdated{
+				AccountID:      e.AccountID,
+				TransactionID:  txn.ID,
+				EntryID:        e.ID,
+				EntryType:      e.EntryType,
+				Amount:         e.Amount,
+				BalanceBefore:  e.BalanceBefore,
+				BalanceAfter:   e.BalanceAfter,
+				Currency:       txn.Currency,
+				SequenceNumber: e.SequenceNumber,
+			},
+		})
+	}
+	return events
+}
@@ -0,0 +1,197 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Modelo de lançamentos contábeis (Double-Entry Bookkeeping)
+// ============================================================================
+
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EntryType espelha core.entry_type
+type EntryType string
+
+const (
+	EntryDebit  EntryType = "DEBIT"
+	EntryCredit EntryType = "CREDIT"
+)
+
+// TransactionStatus espelha core.transaction_status
+type TransactionStatus string
+
+const (
+	StatusPending    TransactionStatus = "PENDING"
+	StatusProcessing TransactionStatus = "PROCESSING"
+	StatusCompleted  TransactionStatus = "COMPLETED"
+	StatusFailed     TransactionStatus = "FAILED"
+	StatusReversed   TransactionStatus = "REVERSED"
+	StatusCancelled  TransactionStatus = "CANCELLED"
+)
+
+// Erros de negócio do ledger
+var (
+	ErrInvalidPosting    = errors.New("invalid posting")
+	ErrUnbalanced        = errors.New("posting is unbalanced")
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrAccountNotActive  = errors.New("account is not active")
+	ErrCurrencyMismatch  = errors.New("currency mismatch")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrDuplicateRef      = errors.New("reference already used")
+	ErrNotFound          = errors.New("transaction not found")
+)
+
+// Leg representa uma perna (débito ou crédito) de um lançamento
+type Leg struct {
+	AccountID string    `json:"account_id"`
+	EntryType EntryType `json:"entry_type"`
+	Amount    Money     `json:"amount"`
+}
+
+// PostingRequest descreve uma transação a ser lançada no ledger
+type PostingRequest struct {
+	ReferenceID       string                 `json:"-"`
+	ExternalReference string                 `json:"external_reference,omitempty"`
+	TransactionType   string                 `json:"transaction_type"`
+	Description       string                 `json:"description"`
+	Currency          string                 `json:"currency"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+	Tags              []string               `json:"tags,omitempty"`
+	Legs              []Leg                  `json:"legs"`
+
+	// ReversalOf liga um estorno à transação original
+	ReversalOf string `json:"-"`
+}
+
+// Transaction representa uma linha de core.transactions com seus lançamentos
+type Transaction struct {
+	ID                string                 `json:"id"`
+	ReferenceID       string                 `json:"reference_id"`
+	ExternalReference string                 `json:"external_reference,omitempty"`
+	TransactionType   string                 `json:"transaction_type"`
+	Description       string                 `json:"description"`
+	Amount            Money                  `json:"amount"`
+	Currency          string                 `json:"currency"`
+	Status            TransactionStatus      `json:"status"`
+	Metadata          map[string]interface{} `json:"metadata,omitempty"`
+	Tags              []string               `json:"tags,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+	ProcessedAt       *time.Time             `json:"processed_at,omitempty"`
+	ReversedBy        *string                `json:"reversed_by,omitempty"`
+	ReversalOf        *string                `json:"reversal_of,omitempty"`
+	PartitionDate     time.Time              `json:"-"`
+	Entries           []Entry                `json:"entries"`
+}
+
+// Entry representa uma linha de core.ledger_entries
+type Entry struct {
+	ID             string    `json:"id"`
+	TransactionID  string    `json:"transaction_id"`
+	AccountID      string    `json:"account_id"`
+	EntryType      EntryType `json:"entry_type"`
+	Amount         Money     `json:"amount"`
+	BalanceBefore  Money     `json:"balance_before"`
+	BalanceAfter   Money     `json:"balance_after"`
+	SequenceNumber int64     `json:"sequence_number"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+var (
+	uuidPattern     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// IsUUID valida o formato textual de um UUID
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// Normalize aplica valores padrão à requisição
+func (r *PostingRequest) Normalize() {
+	r.Currency = strings.ToUpper(strings.TrimSpace(r.Currency))
+	if r.Currency == "" {
+		r.Currency = "BRL"
+	}
+	r.TransactionType = strings.ToUpper(strings.TrimSpace(r.TransactionType))
+	for i := range r.Legs {
+		r.Legs[i].EntryType = EntryType(strings.ToUpper(string(r.Legs[i].EntryType)))
+		r.Legs[i].AccountID = strings.ToLower(strings.TrimSpace(r.Legs[i].AccountID))
+	}
+}
+
+// Validate verifica a estrutura do lançamento e se débitos = créditos
+func (r *PostingRequest) Validate() error {
+	if r.ReferenceID == "" || len(r.ReferenceID) > 100 {
+		return fmt.Errorf("%w: reference_id must have 1-100 characters", ErrInvalidPosting)
+	}
+	if r.TransactionType == "" || len(r.TransactionType) > 50 {
+		return fmt.Errorf("%w: transaction_type must have 1-50 characters", ErrInvalidPosting)
+	}
+	if strings.TrimSpace(r.Description) == "" {
+		return fmt.Errorf("%w: description is required", ErrInvalidPosting)
+	}
+	if !currencyPattern.MatchString(r.Currency) {
+		return fmt.Errorf("%w: currency must be an ISO 4217 code", ErrInvalidPosting)
+	}
+	if len(r.Legs) < 2 {
+		return fmt.Errorf("%w: at least one debit and one credit leg are required", ErrInvalidPosting)
+	}
+
+	var debits, credits Money
+	for i, leg := range r.Legs {
+		if !IsUUID(leg.AccountID) {
+			return fmt.Errorf("%w: legs[%d].account_id is not a valid UUID", ErrInvalidPosting, i)
+		}
+		if leg.Amount <= 0 {
+			return fmt.Errorf("%w: legs[%d].amount must be positive", ErrInvalidPosting, i)
+		}
+		// Somas que estouram int64 dariam a volta e poderiam se igualar
+		switch leg.EntryType {
+		case EntryDebit:
+			if debits > math.MaxInt64-leg.Amount {
+				return fmt.Errorf("%w: total of debit legs is too large", ErrInvalidPosting)
+			}
+			debits += leg.Amount
+		case EntryCredit:
+			if credits > math.MaxInt64-leg.Amount {
+				return fmt.Errorf("%w: total of credit legs is too large", ErrInvalidPosting)
+			}
+			credits += leg.Amount
+		default:
+			return fmt.Errorf("%w: legs[%d].entry_type must be DEBIT or CREDIT", ErrInvalidPosting, i)
+		}
+	}
+
+	if debits == 0 || credits == 0 {
+		return fmt.Errorf("%w: at least one debit and one credit leg are required", ErrInvalidPosting)
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: debits=%s credits=%s", ErrUnbalanced, debits, credits)
+	}
+	return nil
+}
+
+// Amount retorna o valor total da transação (soma dos débitos)
+func (r *PostingRequest) Amount() Money {
+	var total Money
+	for _, leg := range r.Legs {
+		if leg.EntryType == EntryDebit {
+			total += leg.Amount
+		}
+	}
+	return total
+}
+
+// signedAmount retorna o efeito do lançamento no saldo da conta.
+// Segue a convenção do trigger update_account_balance: CREDIT soma, DEBIT subtrai.
+func signedAmount(entryType EntryType, amount Money) Money {
+	if entryType == EntryCredit {
+		return amount
+	}
+	return -amount
+}
@@ -0,0 +1,196 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Money
+		ok   bool
+	}{
+		{"150", 15000, true},
+		{"150.5", 15050, true},
+		{"150.25", 15025, true},
+		{"0.01", 1, true},
+		{".99", 99, true},
+		{"-3.20", -320, true},
+		{"10.500", 1050, true},
+		{"10.505", 0, false},
+		{"", 0, false},
+		{"abc", 0, false},
+		{"1.2.3", 0, false},
+	}
+
+	for _, tc := range cases {
+		got, err := ParseMoney(tc.in)
+		if tc.ok && err != nil {
+			t.Errorf("ParseMoney(%q) unexpected error: %v", tc.in, err)
+			continue
+		}
+		if !tc.ok {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) expected error, got %d", tc.in, got)
+			}
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var leg Leg
+	if err := json.Unmarshal([]byte(`{"amount": 1234.56}`), &leg); err != nil {
+		t.Fatalf("unmarshal number: %v", err)
+	}
+	if leg.Amount != 123456 {
+		t.Fatalf("amount = %d, want 123456", leg.Amount)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": "0.10"}`), &leg); err != nil {
+		t.Fatalf("unmarshal string: %v", err)
+	}
+	if leg.Amount != 10 {
+		t.Fatalf("amount = %d, want 10", leg.Amount)
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 1e3}`), &leg); err == nil {
+		t.Fatal("expected error for exponent notation")
+	}
+
+	out, _ := json.Marshal(Money(-5))
+	if string(out) != "-0.05" {
+		t.Fatalf("marshal = %s, want -0.05", out)
+	}
+}
+
+func TestPostingRequestValidate(t *testing.T) {
+	const (
+		accA = "7d1c1b8e-3f4a-4c55-9b1e-2f7c3a9d0e11"
+		accB = "0a6f5e2d-1c3b-4a99-8d7e-6f5a4b3c2d1e"
+	)
+
+	base := func() PostingRequest {
+		return PostingRequest{
+			ReferenceID:     "idem-1",
+			TransactionType: "transfer",
+			Description:     "Transferência interna",
+			Legs: []Leg{
+				{AccountID: accA, EntryType: "debit", Amount: 1000},
+				{AccountID: accB, EntryType: "credit", Amount: 1000},
+			},
+		}
+	}
+
+	req := base()
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		t.Fatalf("valid request rejected: %v", err)
+	}
+	if req.Currency != "BRL" || req.TransactionType != "TRANSFER" {
+		t.Fatalf("normalize defaults not applied: %+v", req)
+	}
+	if req.Amount() != 1000 {
+		t.Fatalf("amount = %d, want 1000", req.Amount())
+	}
+
+	req = base()
+	req.Legs[1].Amount = 999
+	req.Normalize()
+	if err := req.Validate(); !errors.Is(err, ErrUnbalanced) {
+		t.Fatalf("expected ErrUnbalanced, got %v", err)
+	}
+
+	req = base()
+	req.Legs[1].EntryType = EntryDebit
+	req.Normalize()
+	if err := req.Validate(); !errors.Is(err, ErrInvalidPosting) {
+		t.Fatalf("expected ErrInvalidPosting for debit-only posting, got %v", err)
+	}
+
+	req = base()
+	req.Legs[0].AccountID = "not-a-uuid"
+	req.Normalize()
+	if err := req.Validate(); !errors.Is(err, ErrInvalidPosting) {
+		t.Fatalf("expected ErrInvalidPosting for bad account id, got %v", err)
+	}
+
+	// Débitos e créditos que estouram int64 dão a volta no mesmo valor
+	req = base()
+	req.Legs = []Leg{
+		{AccountID: accA, EntryType: EntryDebit, Amount: math.MaxInt64},
+		{AccountID: accA, EntryType: EntryDebit, Amount: 2},
+		{AccountID: accB, EntryType: EntryCredit, Amount: math.MaxInt64},
+		{AccountID: accB, EntryType: EntryCredit, Amount: 2},
+	}
+	req.Normalize()
+	if err := req.Validate(); !errors.Is(err, ErrInvalidPosting) {
+		t.Fatalf("expected ErrInvalidPosting for overflowing legs, got %v", err)
+	}
+}
+
+func TestInvertLegs(t *testing.T) {
+	entries := []Entry{
+		{AccountID: "payer", EntryType: EntryDebit, Amount: 1000},
+		{AccountID: "merchant", EntryType: EntryCredit, Amount: 970},
+		{AccountID: "fees", EntryType: EntryCredit, Amount: 30},
+	}
+
+	full := InvertLegs(entries, 1000, 1000)
+	if len(full) != 3 || full[0].EntryType != EntryCredit || full[1].EntryType != EntryDebit {
+		t.Fatalf("full reversal should mirror every leg: %+v", full)
+	}
+
+	partial := InvertLegs(entries, 333, 1000)
+	var debits, credits Money
+	byAccount := map[string]Money{}
+	for _, leg := range partial {
+		byAccount[leg.AccountID] = leg.Amount
+		if leg.EntryType == EntryDebit {
+			debits += leg.Amount
+		} else {
+			credits += leg.Amount
+		}
+	}
+	if debits != 333 || credits != 333 {
+		t.Fatalf("partial reversal unbalanced: debits=%d credits=%d", debits, credits)
+	}
+	// 970*333/1000 = 323.01 e 30*333/1000 = 9.99: o centavo restante vai para o maior resto
+	if byAccount["merchant"] != 323 || byAccount["fees"] != 10 || byAccount["payer"] != 333 {
+		t.Fatalf("unexpected allocation: %+v", byAccount)
+	}
+}
+
+func TestCheckTransactionLimit(t *testing.T) {
+	limit := Money(50000)
+	accounts := map[string]*lockedAccount{
+		"payer":    {ID: "payer", TransactionLimit: &limit},
+		"merchant": {ID: "merchant"},
+	}
+	req := PostingRequest{Legs: []Leg{
+		{AccountID: "payer", EntryType: EntryDebit, Amount: 30000},
+		{AccountID: "payer", EntryType: EntryDebit, Amount: 30000},
+		{AccountID: "merchant", EntryType: EntryCredit, Amount: 60000},
+	}}
+
+	// As pernas de débito da mesma conta somam contra o limite
+	if err := checkLimits(context.Background(), nil, req, accounts); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("err = %v, want ErrLimitExceeded", err)
+	}
+
+	req.Legs[1].Amount, req.Legs[2].Amount = 20000, 50000
+	if err := checkLimits(context.Background(), nil, req, accounts); err != nil {
+		t.Fatalf("within limit: %v", err)
+	}
+
+	req.Legs[1].Amount, req.Legs[2].Amount = 90000, 120000
+	req.ReversalOf = "original"
+	if err := checkLimits(context.Background(), nil, req, accounts); err != nil {
+		t.Fatalf("reversal should bypass limits: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Limites por transação e diário (core.accounts.transaction_limit/daily_limit)
+// ============================================================================
+
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrLimitExceeded indica saída acima do limite da conta
+var ErrLimitExceeded = errors.New("account limit exceeded")
+
+// DailyWindow é a janela móvel do limite diário
+const DailyWindow = 24 * time.Hour
+
+// effectiveLimitColumns devolve os limites da conta elevados pelos aumentos
+// aprovados e vigentes. Limite NULL continua sem limite.
+const effectiveLimitColumns = `
+	CASE WHEN a.daily_limit IS NULL THEN NULL ELSE GREATEST(a.daily_limit, (
+		SELECT MAX(r.requested_limit) FROM core.account_limit_requests r
+		WHERE r.account_id = a.id AND r.limit_type = 'DAILY' AND r.status = 'APPROVED'
+		  AND NOW() >= r.valid_from AND NOW() < r.valid_until
+	)) END,
+	CASE WHEN a.transaction_limit IS NULL THEN NULL ELSE GREATEST(a.transaction_limit, (
+		SELECT MAX(r.requested_limit) FROM core.account_limit_requests r
+		WHERE r.account_id = a.id AND r.limit_type = 'TRANSACTION' AND r.status = 'APPROVED'
+		  AND NOW() >= r.valid_from AND NOW() < r.valid_until
+	)) END`
+
+// outboundAmounts soma os débitos de cada conta no lançamento
+func outboundAmounts(legs []Leg) map[string]Money {
+	out := make(map[string]Money)
+	for _, leg := range legs {
+		if leg.EntryType == EntryDebit {
+			out[leg.AccountID] += leg.Amount
+		}
+	}
+	return out
+}
+
+// checkLimits valida as saídas contra os limites das contas já bloqueadas
+// (FOR UPDATE). Como todo lançamento que debita a conta disputa o mesmo lock,
+// soma e verificação são atômicas. Estornos não consomem nem respeitam limite.
+func checkLimits(ctx context.Context, q queryer, req PostingRequest, accounts map[string]*lockedAccount) error {
+	if req.ReversalOf != "" {
+		return nil
+	}
+
+	outbound := outboundAmounts(req.Legs)
+	ids := make([]string, 0, len(outbound))
+	for id := range outbound {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		acc, amount := accounts[id], outbound[id]
+		if acc.TransactionLimit != nil && amount > *acc.TransactionLimit {
+			return fmt.Errorf("%w: account %s transaction limit is %s, requested %s",
+				ErrLimitExceeded, id, *acc.TransactionLimit, amount)
+		}
+		if acc.DailyLimit == nil {
+			continue
+		}
+		used, err := DailyUsage(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		if used+amount > *acc.DailyLimit {
+			return fmt.Errorf("%w: account %s daily limit is %s, used %s, requested %s",
+				ErrLimitExceeded, id, *acc.DailyLimit, used, amount)
+		}
+	}
+	return nil
+}
+
+// DailyUsage soma as saídas da conta nas últimas 24 horas, sem contar estornos
+func DailyUsage(ctx context.Context, q queryer, accountID string) (Money, error) {
+	var used Money
+	err := q.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(e.amount), 0)
+		FROM core.ledger_entries e
+		JOIN core.transactions t ON t.id = e.transaction_id AND t.partition_date = e.partition_date
+		WHERE e.account_id = $1
+		  AND e.entry_type = 'DEBIT'
+		  AND e.created_at > NOW() - $2::interval
+		  AND e.partition_date >= (NOW() - $2::interval)::date
+		  AND t.reversal_of IS NULL
+	`, accountID, fmt.Sprintf("%d seconds", int64(DailyWindow.Seconds()))).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("load daily usage: %w", err)
+	}
+	return used, nil
+}
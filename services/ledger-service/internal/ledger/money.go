@@ -0,0 +1,152 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Representação monetária em centavos (sem ponto flutuante)
+// ============================================================================
+
+package ledger
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money representa um valor monetário em centavos.
+// Os valores são trafegados como DECIMAL(18,2) no banco e como número
+// com duas casas decimais no JSON, sem passar por float64.
+type Money int64
+
+// ErrInvalidAmount indica um valor monetário malformado
+var ErrInvalidAmount = errors.New("invalid monetary amount")
+
+// ParseMoney converte um valor decimal ("150", "150.5", "-3.25") em centavos
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := false
+	switch s[0] {
+	case '-':
+		negative = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+	if intPart == "" && (!hasFrac || fracPart == "") {
+		return 0, ErrInvalidAmount
+	}
+	if len(fracPart) > 2 {
+		// Aceitar zeros à direita ("10.500"), rejeitar sub-centavos
+		if strings.TrimRight(fracPart[2:], "0") != "" {
+			return 0, fmt.Errorf("%w: more than 2 decimal places", ErrInvalidAmount)
+		}
+		fracPart = fracPart[:2]
+	}
+	for len(fracPart) < 2 {
+		fracPart += "0"
+	}
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	units, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || units < 0 {
+		return 0, ErrInvalidAmount
+	}
+	cents, err := strconv.ParseInt(fracPart, 10, 64)
+	if err != nil || cents < 0 {
+		return 0, ErrInvalidAmount
+	}
+	// DECIMAL(18,2) comporta até 16 dígitos inteiros
+	if units > 9999999999999999 {
+		return 0, fmt.Errorf("%w: out of range", ErrInvalidAmount)
+	}
+
+	value := Money(units*100 + cents)
+	if negative {
+		value = -value
+	}
+	return value, nil
+}
+
+// String formata o valor com duas casas decimais ("150.25")
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// MarshalJSON serializa como número JSON com duas casas decimais
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON aceita número JSON ou string decimal
+func (m *Money) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		raw = s
+	}
+	// Notação científica não é aceita para valores monetários
+	if strings.ContainsAny(raw, "eE") {
+		return ErrInvalidAmount
+	}
+	v, err := ParseMoney(raw)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Scan implementa sql.Scanner para colunas DECIMAL
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		return m.scanString(string(v))
+	case string:
+		return m.scanString(v)
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		return m.scanString(strconv.FormatFloat(v, 'f', 2, 64))
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) scanString(s string) error {
+	v, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// Value implementa driver.Valuer
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,191 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Estorno de transações com lançamentos espelhados
+// ============================================================================
+
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// Erros de estorno
+var (
+	ErrAlreadyReversed  = errors.New("transaction already reversed")
+	ErrNotReversible    = errors.New("transaction cannot be reversed")
+	ErrReversalExceeded = errors.New("reversal amount exceeds the remaining amount")
+)
+
+// ReversalRequest descreve um estorno total (Amount nil) ou parcial
+type ReversalRequest struct {
+	ReferenceID string `json:"-"`
+	Amount      *Money `json:"amount,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// ReverseTx lança uma nova transação com as pernas invertidas da original.
+//
+// Estornos parciais são ligados à original por reversal_of; quando a soma dos
+// estornos atinge o valor total, a original passa a REVERSED e reversed_by
+// aponta para o estorno que a completou.
+func (s *Store) ReverseTx(ctx context.Context, tx *sql.Tx, originalID string, req ReversalRequest) (*Transaction, error) {
+	if !IsUUID(originalID) {
+		return nil, ErrNotFound
+	}
+
+	// Bloquear a original para serializar estornos concorrentes
+	var status TransactionStatus
+	var reversedBy, reversalOf sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT status, reversed_by, reversal_of
+		FROM core.transactions
+		WHERE id = $1
+		FOR UPDATE
+	`, originalID).Scan(&status, &reversedBy, &reversalOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock transaction: %w", err)
+	}
+
+	switch {
+	case reversedBy.Valid || status == StatusReversed:
+		return nil, ErrAlreadyReversed
+	case status != StatusCompleted:
+		return nil, fmt.Errorf("%w: status is %s", ErrNotReversible, status)
+	case reversalOf.Valid:
+		return nil, fmt.Errorf("%w: transaction is itself a reversal", ErrNotReversible)
+	}
+
+	original, err := getTransaction(ctx, tx, originalID)
+	if err != nil {
+		return nil, err
+	}
+
+	var alreadyReversed Money
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(amount), 0)
+		FROM core.transactions
+		WHERE reversal_of = $1 AND status = 'COMPLETED'
+	`, originalID).Scan(&alreadyReversed)
+	if err != nil {
+		return nil, fmt.Errorf("sum previous reversals: %w", err)
+	}
+
+	remaining := original.Amount - alreadyReversed
+	amount := remaining
+	if req.Amount != nil {
+		amount = *req.Amount
+	}
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidPosting)
+	}
+	if amount > remaining {
+		return nil, fmt.Errorf("%w: requested=%s remaining=%s", ErrReversalExceeded, amount, remaining)
+	}
+
+	description := "Estorno de " + original.ReferenceID
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		description += ": " + reason
+	}
+
+	reversal, err := s.PostTx(ctx, tx, PostingRequest{
+		ReferenceID:       req.ReferenceID,
+		ExternalReference: original.ExternalReference,
+		TransactionType:   "REVERSAL",
+		Description:       description,
+		Currency:          original.Currency,
+		Metadata: map[string]interface{}{
+			"original_transaction_id": original.ID,
+			"original_type":           original.TransactionType,
+			"reason":                  req.Reason,
+			"partial":                 amount != original.Amount,
+		},
+		Legs:       InvertLegs(original.Entries, amount, original.Amount),
+		ReversalOf: original.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if alreadyReversed+amount == original.Amount {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE core.transactions
+			SET status = 'REVERSED', reversed_by = $2
+			WHERE id = $1
+		`, original.ID, reversal.ID)
+		if err != nil {
+			return nil, fmt.Errorf("mark transaction reversed: %w", err)
+		}
+	}
+
+	return reversal, nil
+}
+
+// InvertLegs gera as pernas espelhadas dos lançamentos originais.
+// Para estornos parciais o valor é rateado proporcionalmente em cada lado
+// (débitos e créditos) pelo método do maior resto, mantendo o balanceamento.
+func InvertLegs(entries []Entry, amount, total Money) []Leg {
+	if amount == total {
+		legs := make([]Leg, 0, len(entries))
+		for _, e := range entries {
+			legs = append(legs, Leg{AccountID: e.AccountID, EntryType: invert(e.EntryType), Amount: e.Amount})
+		}
+		return legs
+	}
+
+	var legs []Leg
+	for _, side := range []EntryType{EntryDebit, EntryCredit} {
+		type share struct {
+			index     int
+			amount    Money
+			remainder int64
+		}
+		var shares []share
+		var allocated Money
+		for i, e := range entries {
+			if e.EntryType != side {
+				continue
+			}
+			// e.Amount * amount / total em precisão arbitrária
+			num := new(big.Int).Mul(big.NewInt(int64(e.Amount)), big.NewInt(int64(amount)))
+			quo, rem := new(big.Int).QuoRem(num, big.NewInt(int64(total)), new(big.Int))
+			shares = append(shares, share{index: i, amount: Money(quo.Int64()), remainder: rem.Int64()})
+			allocated += Money(quo.Int64())
+		}
+
+		order := make([]int, len(shares))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return shares[order[a]].remainder > shares[order[b]].remainder
+		})
+		for i := 0; allocated < amount && len(order) > 0; i = (i + 1) % len(order) {
+			shares[order[i]].amount++
+			allocated++
+		}
+
+		for _, sh := range shares {
+			if sh.amount > 0 {
+				e := entries[sh.index]
+				legs = append(legs, Leg{AccountID: e.AccountID, EntryType: invert(side), Amount: sh.amount})
+			}
+		}
+	}
+	return legs
+}
+
+func invert(t EntryType) EntryType {
+	if t == EntryDebit {
+		return EntryCredit
+	}
+	return EntryDebit
+}
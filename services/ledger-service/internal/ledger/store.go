@@ -0,0 +1,334 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Persistência do ledger em PostgreSQL
+// ============================================================================
+
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/kaminoclone/ledger-service/internal/outbox"
+)
+
+// Store executa lançamentos no ledger de forma atômica
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre o pool de conexões informado
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// lockedAccount é o estado de uma conta bloqueada (FOR UPDATE) durante o lançamento
+type lockedAccount struct {
+	ID               string
+	Balance          Money
+	AvailableBalance Money
+	Currency         string
+	Status           string
+	AccountType      string
+	// Limites efetivos (com aumentos aprovados); nil = sem limite
+	DailyLimit       *Money
+	TransactionLimit *Money
+}
+
+// allowsNegative segue a constraint accounts_balance_positive do schema
+func (a *lockedAccount) allowsNegative() bool {
+	return a.AccountType == "LIABILITY" || a.AccountType == "EXPENSE"
+}
+
+// Post valida e grava a transação e seus lançamentos em uma única transação SQL
+func (s *Store) Post(ctx context.Context, req PostingRequest) (*Transaction, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	txn, err := s.PostTx(ctx, tx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return txn, nil
+}
+
+// PostTx grava o lançamento dentro de uma transação SQL já aberta.
+// As contas envolvidas são bloqueadas em ordem determinística para evitar deadlocks.
+func (s *Store) PostTx(ctx context.Context, tx *sql.Tx, req PostingRequest) (*Transaction, error) {
+	req.Normalize()
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	accounts, err := lockAccounts(ctx, tx, req.Legs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calcular saldos resultantes antes de gravar qualquer linha
+	balances := make(map[string]Money, len(accounts))
+	available := make(map[string]Money, len(accounts))
+	for id, acc := range accounts {
+		if acc.Currency != req.Currency {
+			return nil, fmt.Errorf("%w: account %s is %s, transaction is %s",
+				ErrCurrencyMismatch, id, acc.Currency, req.Currency)
+		}
+		balances[id] = acc.Balance
+		available[id] = acc.AvailableBalance
+	}
+	for _, leg := range req.Legs {
+		available[leg.AccountID] += signedAmount(leg.EntryType, leg.Amount)
+	}
+	for id, acc := range accounts {
+		if available[id] < 0 && !acc.allowsNegative() {
+			return nil, fmt.Errorf("%w: account %s", ErrInsufficientFunds, id)
+		}
+	}
+	if err := checkLimits(ctx, tx, req, accounts); err != nil {
+		return nil, err
+	}
+
+	metadata, err := json.Marshal(nonNilMap(req.Metadata))
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidPosting, err)
+	}
+	tags := req.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	txn := &Transaction{
+		ReferenceID:       req.ReferenceID,
+		ExternalReference: req.ExternalReference,
+		TransactionType:   req.TransactionType,
+		Description:       req.Description,
+		Amount:            req.Amount(),
+		Currency:          req.Currency,
+		Status:            StatusCompleted,
+		Metadata:          req.Metadata,
+		Tags:              req.Tags,
+	}
+
+	var processedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO core.transactions (
+			reference_id, external_reference, transaction_type, description,
+			amount, currency, status, metadata, tags, processed_at, reversal_of
+		) VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6, $7, $8, $9, NOW(), NULLIF($10, '')::uuid)
+		RETURNING id, created_at, processed_at, partition_date
+	`,
+		txn.ReferenceID, txn.ExternalReference, txn.TransactionType, txn.Description,
+		txn.Amount, txn.Currency, txn.Status, metadata, pq.Array(tags), req.ReversalOf,
+	).Scan(&txn.ID, &txn.CreatedAt, &processedAt, &txn.PartitionDate)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateRef, txn.ReferenceID)
+		}
+		return nil, fmt.Errorf("insert transaction: %w", err)
+	}
+	txn.ProcessedAt = &processedAt
+	if req.ReversalOf != "" {
+		txn.ReversalOf = &req.ReversalOf
+	}
+
+	// O trigger trigger_ledger_balance aplica o efeito de cada linha em core.accounts;
+	// balance_before/balance_after são calculados aqui sobre as linhas já bloqueadas.
+	for _, leg := range req.Legs {
+		entry := Entry{
+			TransactionID: txn.ID,
+			AccountID:     leg.AccountID,
+			EntryType:     leg.EntryType,
+			Amount:        leg.Amount,
+			BalanceBefore: balances[leg.AccountID],
+		}
+		entry.BalanceAfter = entry.BalanceBefore + signedAmount(leg.EntryType, leg.Amount)
+		balances[leg.AccountID] = entry.BalanceAfter
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO core.ledger_entries (
+				transaction_id, account_id, entry_type, amount,
+				balance_before, balance_after, partition_date
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, sequence_number, created_at
+		`,
+			entry.TransactionID, entry.AccountID, entry.EntryType, entry.Amount,
+			entry.BalanceBefore, entry.BalanceAfter, txn.PartitionDate,
+		).Scan(&entry.ID, &entry.SequenceNumber, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("insert ledger entry: %w", err)
+		}
+		txn.Entries = append(txn.Entries, entry)
+	}
+
+	// Eventos publicados pelo relay somente se esta transação for confirmada
+	if err := outbox.Insert(ctx, tx, postingEvents(txn)...); err != nil {
+		return nil, err
+	}
+
+	return txn, nil
+}
+
+// lockAccounts bloqueia as contas das pernas do lançamento (ordenadas por ID)
+func lockAccounts(ctx context.Context, tx *sql.Tx, legs []Leg) (map[string]*lockedAccount, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, leg := range legs {
+		if !seen[leg.AccountID] {
+			seen[leg.AccountID] = true
+			ids = append(ids, leg.AccountID)
+		}
+	}
+	sort.Strings(ids)
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.id, a.balance, a.available_balance, a.currency, a.status, c.account_type,
+		       `+effectiveLimitColumns+`
+		FROM core.accounts a
+		JOIN core.chart_of_accounts c ON c.id = a.chart_account_id
+		WHERE a.id = ANY($1)
+		ORDER BY a.id
+		FOR UPDATE OF a
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("lock accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := make(map[string]*lockedAccount, len(ids))
+	for rows.Next() {
+		var acc lockedAccount
+		if err := rows.Scan(&acc.ID, &acc.Balance, &acc.AvailableBalance,
+			&acc.Currency, &acc.Status, &acc.AccountType,
+			&acc.DailyLimit, &acc.TransactionLimit); err != nil {
+			return nil, fmt.Errorf("scan account: %w", err)
+		}
+		accounts[acc.ID] = &acc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lock accounts: %w", err)
+	}
+
+	for _, id := range ids {
+		acc, ok := accounts[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, id)
+		}
+		// Contas BLOCKED, FROZEN, CLOSED ou pendentes não recebem lançamentos
+		if acc.Status != "ACTIVE" {
+			return nil, fmt.Errorf("%w: account %s is %s", ErrAccountNotActive, id, acc.Status)
+		}
+	}
+	return accounts, nil
+}
+
+// GetTransaction carrega uma transação e seus lançamentos
+func (s *Store) GetTransaction(ctx context.Context, id string) (*Transaction, error) {
+	return getTransaction(ctx, s.db, id)
+}
+
+// queryer abstrai *sql.DB e *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func getTransaction(ctx context.Context, q queryer, id string) (*Transaction, error) {
+	if !IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	var txn Transaction
+	var metadata []byte
+	var tags pq.StringArray
+	var processedAt sql.NullTime
+	var reversedBy, reversalOf sql.NullString
+	err := q.QueryRowContext(ctx, `
+		SELECT id, reference_id, COALESCE(external_reference, ''), transaction_type,
+		       description, amount, currency, status, COALESCE(metadata, '{}'),
+		       COALESCE(tags, '{}'), created_at, processed_at, reversed_by, reversal_of,
+		       partition_date
+		FROM core.transactions
+		WHERE id = $1
+	`, id).Scan(
+		&txn.ID, &txn.ReferenceID, &txn.ExternalReference, &txn.TransactionType,
+		&txn.Description, &txn.Amount, &txn.Currency, &txn.Status, &metadata,
+		&tags, &txn.CreatedAt, &processedAt, &reversedBy, &reversalOf,
+		&txn.PartitionDate,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load transaction: %w", err)
+	}
+
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &txn.Metadata); err != nil {
+			return nil, fmt.Errorf("decode metadata: %w", err)
+		}
+	}
+	if len(txn.Metadata) == 0 {
+		txn.Metadata = nil
+	}
+	if len(tags) > 0 {
+		txn.Tags = tags
+	}
+	if processedAt.Valid {
+		txn.ProcessedAt = &processedAt.Time
+	}
+	if reversedBy.Valid {
+		txn.ReversedBy = &reversedBy.String
+	}
+	if reversalOf.Valid {
+		txn.ReversalOf = &reversalOf.String
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, transaction_id, account_id, entry_type, amount,
+		       balance_before, balance_after, sequence_number, created_at
+		FROM core.ledger_entries
+		WHERE transaction_id = $1 AND partition_date = $2
+		ORDER BY sequence_number
+	`, txn.ID, txn.PartitionDate)
+	if err != nil {
+		return nil, fmt.Errorf("load entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.TransactionID, &e.AccountID, &e.EntryType, &e.Amount,
+			&e.BalanceBefore, &e.BalanceAfter, &e.SequenceNumber, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan entry: %w", err)
+		}
+		txn.Entries = append(txn.Entries, e)
+	}
+	return &txn, rows.Err()
+}
+
+func nonNilMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+// isUniqueViolation identifica erros de chave duplicada do PostgreSQL
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
@@ -0,0 +1,361 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Consumo de limites e pedidos de aumento temporário com aprovação
+// ============================================================================
+
+package limits
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+var (
+	ErrNotFound        = errors.New("limit request not found")
+	ErrAccountNotFound = errors.New("account not found")
+	ErrInvalidRequest  = errors.New("invalid limit request")
+	ErrNotPending      = errors.New("limit request is not pending")
+	ErrSelfApproval    = errors.New("limit request cannot be decided by its requester")
+)
+
+// Type espelha o CHECK de core.account_limit_requests.limit_type
+type Type string
+
+const (
+	TypeDaily       Type = "DAILY"
+	TypeTransaction Type = "TRANSACTION"
+)
+
+// Status espelha o CHECK de core.account_limit_requests.status
+type Status string
+
+const (
+	StatusPending  Status = "PENDING"
+	StatusApproved Status = "APPROVED"
+	StatusRejected Status = "REJECTED"
+)
+
+const (
+	defaultDurationHours = 24
+	maxDurationHours     = 720
+)
+
+// Request é um pedido de aumento temporário de limite
+type Request struct {
+	ID             string       `json:"id"`
+	AccountID      string       `json:"account_id"`
+	LimitType      Type         `json:"limit_type"`
+	RequestedLimit ledger.Money `json:"requested_limit"`
+	DurationHours  int          `json:"duration_hours"`
+	Reason         string       `json:"reason"`
+	Status         Status       `json:"status"`
+	RequestedBy    *string      `json:"requested_by,omitempty"`
+	DecidedBy      *string      `json:"decided_by,omitempty"`
+	DecisionReason *string      `json:"decision_reason,omitempty"`
+	DecidedAt      *time.Time   `json:"decided_at,omitempty"`
+	ValidFrom      *time.Time   `json:"valid_from,omitempty"`
+	ValidUntil     *time.Time   `json:"valid_until,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+// Active informa se o aumento aprovado está vigente em now
+func (r *Request) Active(now time.Time) bool {
+	return r.Status == StatusApproved && r.ValidFrom != nil && r.ValidUntil != nil &&
+		!now.Before(*r.ValidFrom) && now.Before(*r.ValidUntil)
+}
+
+// IncreaseRequest é o corpo de POST /accounts/:id/limits/requests
+type IncreaseRequest struct {
+	LimitType      Type         `json:"limit_type"`
+	RequestedLimit ledger.Money `json:"requested_limit"`
+	DurationHours  int          `json:"duration_hours,omitempty"`
+	Reason         string       `json:"reason"`
+}
+
+// Validate normaliza e valida o pedido
+func (r *IncreaseRequest) Validate() error {
+	r.LimitType = Type(strings.ToUpper(strings.TrimSpace(string(r.LimitType))))
+	r.Reason = strings.TrimSpace(r.Reason)
+	if r.DurationHours == 0 {
+		r.DurationHours = defaultDurationHours
+	}
+
+	switch {
+	case r.LimitType != TypeDaily && r.LimitType != TypeTransaction:
+		return fmt.Errorf("%w: limit_type must be DAILY or TRANSACTION", ErrInvalidRequest)
+	case r.RequestedLimit <= 0:
+		return fmt.Errorf("%w: requested_limit must be positive", ErrInvalidRequest)
+	case r.DurationHours < 1 || r.DurationHours > maxDurationHours:
+		return fmt.Errorf("%w: duration_hours must be between 1 and %d", ErrInvalidRequest, maxDurationHours)
+	case r.Reason == "":
+		return fmt.Errorf("%w: reason is required", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// Usage é o consumo dos limites da conta na janela móvel de 24 horas
+type Usage struct {
+	AccountID                 string        `json:"account_id"`
+	Currency                  string        `json:"currency"`
+	DailyLimit                *ledger.Money `json:"daily_limit"`
+	EffectiveDailyLimit       *ledger.Money `json:"effective_daily_limit"`
+	DailyUsed                 ledger.Money  `json:"daily_used"`
+	DailyRemaining            *ledger.Money `json:"daily_remaining"`
+	TransactionLimit          *ledger.Money `json:"transaction_limit"`
+	EffectiveTransactionLimit *ledger.Money `json:"effective_transaction_limit"`
+	WindowStart               time.Time     `json:"window_start"`
+	WindowEnd                 time.Time     `json:"window_end"`
+	ActiveIncreases           []Request     `json:"active_increases"`
+}
+
+// applyIncreases calcula limites efetivos e saldo restante; limite nil continua sem limite
+func (u *Usage) applyIncreases(now time.Time, requests []Request) {
+	u.ActiveIncreases = []Request{}
+	u.EffectiveDailyLimit = u.DailyLimit
+	u.EffectiveTransactionLimit = u.TransactionLimit
+	for _, r := range requests {
+		if !r.Active(now) {
+			continue
+		}
+		u.ActiveIncreases = append(u.ActiveIncreases, r)
+		switch r.LimitType {
+		case TypeDaily:
+			u.EffectiveDailyLimit = raise(u.EffectiveDailyLimit, r.RequestedLimit)
+		case TypeTransaction:
+			u.EffectiveTransactionLimit = raise(u.EffectiveTransactionLimit, r.RequestedLimit)
+		}
+	}
+	if u.EffectiveDailyLimit != nil {
+		remaining := *u.EffectiveDailyLimit - u.DailyUsed
+		if remaining < 0 {
+			remaining = 0
+		}
+		u.DailyRemaining = &remaining
+	}
+}
+
+func raise(limit *ledger.Money, to ledger.Money) *ledger.Money {
+	if limit == nil || *limit >= to {
+		return limit
+	}
+	return &to
+}
+
+// Store consulta consumo e gerencia pedidos de aumento
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre o pool de conexões informado
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Usage devolve limites, consumo e aumentos vigentes da conta
+func (s *Store) Usage(ctx context.Context, accountID string) (*Usage, error) {
+	if !ledger.IsUUID(accountID) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+
+	u := Usage{AccountID: accountID}
+	var now time.Time
+	err := s.db.QueryRowContext(ctx, `
+		SELECT currency, daily_limit, transaction_limit, NOW()
+		FROM core.accounts
+		WHERE id = $1
+	`, accountID).Scan(&u.Currency, &u.DailyLimit, &u.TransactionLimit, &now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load account limits: %w", err)
+	}
+
+	if u.DailyUsed, err = ledger.DailyUsage(ctx, s.db, accountID); err != nil {
+		return nil, err
+	}
+	requests, err := s.listRequests(ctx, `WHERE account_id = $1 AND status = 'APPROVED' AND valid_until > $2`, accountID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	u.WindowEnd = now
+	u.WindowStart = now.Add(-ledger.DailyWindow)
+	u.applyIncreases(now, requests)
+	return &u, nil
+}
+
+// RequestIncrease registra um pedido PENDING; só faz sentido para limites configurados.
+// requestedBy é o identificador do principal (uid ou subject), gravado como veio.
+func (s *Store) RequestIncrease(ctx context.Context, accountID string, req IncreaseRequest, requestedBy string) (*Request, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	requestedBy = strings.TrimSpace(requestedBy)
+	if requestedBy == "" {
+		return nil, fmt.Errorf("%w: requester identity is required", ErrInvalidRequest)
+	}
+	if !ledger.IsUUID(accountID) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+
+	column := "daily_limit"
+	if req.LimitType == TypeTransaction {
+		column = "transaction_limit"
+	}
+	var current *ledger.Money
+	err := s.db.QueryRowContext(ctx, `SELECT `+column+` FROM core.accounts WHERE id = $1`, accountID).Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load account limits: %w", err)
+	}
+	switch {
+	case current == nil:
+		return nil, fmt.Errorf("%w: account has no %s limit", ErrInvalidRequest, strings.ToLower(string(req.LimitType)))
+	case req.RequestedLimit <= *current:
+		return nil, fmt.Errorf("%w: requested_limit must exceed the current limit %s", ErrInvalidRequest, *current)
+	}
+
+	var id string
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO core.account_limit_requests (
+			account_id, limit_type, requested_limit, duration_hours, reason, requested_by
+		) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, accountID, req.LimitType, req.RequestedLimit, req.DurationHours, req.Reason, requestedBy).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("insert limit request: %w", err)
+	}
+	return s.Get(ctx, id)
+}
+
+// Decide aprova ou rejeita um pedido PENDING. A vigência começa na aprovação.
+// O solicitante não pode decidir o próprio pedido.
+func (s *Store) Decide(ctx context.Context, id string, approve bool, decidedBy, reason string) (*Request, error) {
+	reason, decidedBy = strings.TrimSpace(reason), strings.TrimSpace(decidedBy)
+	if decidedBy == "" {
+		return nil, fmt.Errorf("%w: approver identity is required", ErrInvalidRequest)
+	}
+	if !approve && reason == "" {
+		return nil, fmt.Errorf("%w: reason is required to reject", ErrInvalidRequest)
+	}
+	if !ledger.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status Status
+	var requestedBy sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT status, requested_by FROM core.account_limit_requests WHERE id = $1 FOR UPDATE
+	`, id).Scan(&status, &requestedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock limit request: %w", err)
+	}
+	if status != StatusPending {
+		return nil, fmt.Errorf("%w: %s", ErrNotPending, status)
+	}
+	if err := checkDecider(requestedBy, decidedBy); err != nil {
+		return nil, err
+	}
+
+	if approve {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE core.account_limit_requests
+			SET status = 'APPROVED', decided_by = $2, decision_reason = NULLIF($3, ''),
+			    decided_at = NOW(), valid_from = NOW(), valid_until = NOW() + duration_hours * INTERVAL '1 hour'
+			WHERE id = $1
+		`, id, decidedBy, reason)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE core.account_limit_requests
+			SET status = 'REJECTED', decided_by = $2, decision_reason = $3, decided_at = NOW()
+			WHERE id = $1
+		`, id, decidedBy, reason)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decide limit request: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return s.Get(ctx, id)
+}
+
+// Get carrega um pedido pelo ID
+func (s *Store) Get(ctx context.Context, id string) (*Request, error) {
+	if !ledger.IsUUID(id) {
+		return nil, ErrNotFound
+	}
+	list, err := s.listRequests(ctx, `WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	return &list[0], nil
+}
+
+// ListByAccount lista os pedidos da conta, do mais recente para o mais antigo
+func (s *Store) ListByAccount(ctx context.Context, accountID string) ([]Request, error) {
+	if !ledger.IsUUID(accountID) {
+		return nil, fmt.Errorf("%w: %s", ErrAccountNotFound, accountID)
+	}
+	return s.listRequests(ctx, `WHERE account_id = $1`, accountID)
+}
+
+func (s *Store) listRequests(ctx context.Context, where string, args ...interface{}) ([]Request, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, account_id, limit_type, requested_limit, duration_hours, reason, status,
+		       requested_by, decided_by, decision_reason, decided_at, valid_from, valid_until, created_at
+		FROM core.account_limit_requests
+		`+where+`
+		ORDER BY created_at DESC, id DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list limit requests: %w", err)
+	}
+	defer rows.Close()
+
+	list := []Request{}
+	for rows.Next() {
+		var r Request
+		if err := rows.Scan(&r.ID, &r.AccountID, &r.LimitType, &r.RequestedLimit, &r.DurationHours,
+			&r.Reason, &r.Status, &r.RequestedBy, &r.DecidedBy, &r.DecisionReason,
+			&r.DecidedAt, &r.ValidFrom, &r.ValidUntil, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan limit request: %w", err)
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// checkDecider aplica os quatro olhos: quem decide não pode ser quem pediu.
+// Pedido sem solicitante registrado não tem como ser conferido e é recusado.
+func checkDecider(requestedBy sql.NullString, decidedBy string) error {
+	if !requestedBy.Valid || strings.TrimSpace(requestedBy.String) == "" {
+		return fmt.Errorf("%w: request has no recorded requester", ErrSelfApproval)
+	}
+	if strings.EqualFold(strings.TrimSpace(requestedBy.String), decidedBy) {
+		return ErrSelfApproval
+	}
+	return nil
+}
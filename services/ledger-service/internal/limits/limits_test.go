@@ -0,0 +1,94 @@
+package limits
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+func TestIncreaseRequestValidate(t *testing.T) {
+	req := IncreaseRequest{LimitType: " daily ", RequestedLimit: 500000, Reason: "viagem"}
+	if err := req.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if req.LimitType != TypeDaily || req.DurationHours != defaultDurationHours {
+		t.Errorf("normalized = %+v", req)
+	}
+
+	invalid := []IncreaseRequest{
+		{LimitType: "MONTHLY", RequestedLimit: 1, Reason: "x"},
+		{LimitType: TypeDaily, RequestedLimit: 0, Reason: "x"},
+		{LimitType: TypeDaily, RequestedLimit: 1, Reason: "x", DurationHours: maxDurationHours + 1},
+		{LimitType: TypeTransaction, RequestedLimit: 1, Reason: "  "},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%+v: err = %v", r, err)
+		}
+	}
+}
+
+func TestUsageApplyIncreases(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	from, until := now.Add(-time.Hour), now.Add(time.Hour)
+	expired := now.Add(-time.Minute)
+	money := func(v ledger.Money) *ledger.Money { return &v }
+
+	u := Usage{DailyLimit: money(100000), TransactionLimit: money(50000), DailyUsed: 130000}
+	u.applyIncreases(now, []Request{
+		{LimitType: TypeDaily, RequestedLimit: 200000, Status: StatusApproved, ValidFrom: &from, ValidUntil: &until},
+		{LimitType: TypeDaily, RequestedLimit: 900000, Status: StatusApproved, ValidFrom: &from, ValidUntil: &expired},
+		{LimitType: TypeTransaction, RequestedLimit: 40000, Status: StatusApproved, ValidFrom: &from, ValidUntil: &until},
+	})
+
+	if *u.EffectiveDailyLimit != 200000 {
+		t.Errorf("effective daily = %s, want 2000.00", *u.EffectiveDailyLimit)
+	}
+	if *u.EffectiveTransactionLimit != 50000 {
+		t.Errorf("effective transaction = %s, a smaller increase must not lower the limit", *u.EffectiveTransactionLimit)
+	}
+	if *u.DailyRemaining != 70000 {
+		t.Errorf("remaining = %s, want 700.00", *u.DailyRemaining)
+	}
+	if len(u.ActiveIncreases) != 2 {
+		t.Errorf("active increases = %d, want 2", len(u.ActiveIncreases))
+	}
+
+	unlimited := Usage{DailyUsed: 130000}
+	unlimited.applyIncreases(now, []Request{
+		{LimitType: TypeDaily, RequestedLimit: 200000, Status: StatusApproved, ValidFrom: &from, ValidUntil: &until},
+	})
+	if unlimited.EffectiveDailyLimit != nil || unlimited.DailyRemaining != nil {
+		t.Error("account without daily limit gained one")
+	}
+}
+
+func TestCheckDecider(t *testing.T) {
+	requester := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	cases := []struct {
+		name        string
+		requestedBy sql.NullString
+		decidedBy   string
+		ok          bool
+	}{
+		{"other uuid", requester("0b0e9a52-54c6-4c58-9a7e-0d9c1a6c2f10"), "5f4c8d0e-7a1b-4f3e-9d2c-1b6a8e4f0c21", true},
+		{"same uuid", requester("0b0e9a52-54c6-4c58-9a7e-0d9c1a6c2f10"), "0B0E9A52-54C6-4C58-9A7E-0D9C1A6C2F10", false},
+		// Subjects que não são UUID também contam (ex.: auth0|123, e-mail)
+		{"same non-uuid subject", requester("auth0|123"), "auth0|123", false},
+		{"other non-uuid subject", requester("auth0|123"), "auth0|456", true},
+		{"no recorded requester", sql.NullString{}, "auth0|456", false},
+		{"blank requester", requester(" "), "auth0|456", false},
+	}
+	for _, tc := range cases {
+		err := checkDecider(tc.requestedBy, tc.decidedBy)
+		if tc.ok && err != nil {
+			t.Errorf("%s: err = %v", tc.name, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrSelfApproval) {
+			t.Errorf("%s: err = %v; want ErrSelfApproval", tc.name, err)
+		}
+	}
+}
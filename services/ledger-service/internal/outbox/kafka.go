@@ -0,0 +1,81 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Publicação de eventos no Kafka
+// ============================================================================
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Message é uma mensagem a ser publicada
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers map[string]string
+}
+
+// Publisher publica mensagens; o erro de índice i corresponde a msgs[i]
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) []error
+	Close() error
+}
+
+// KafkaPublisher publica com acks de todas as réplicas e particionamento por chave
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher cria um publisher para os brokers informados
+func NewKafkaPublisher(brokers []string) *KafkaPublisher {
+	return &KafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			MaxAttempts:  1, // retries ficam a cargo do relay, com backoff persistido
+			BatchTimeout: 10 * time.Millisecond,
+			WriteTimeout: 10 * time.Second,
+			Compression:  kafka.Lz4,
+		},
+	}
+}
+
+// Publish envia o lote e devolve o resultado de cada mensagem
+func (p *KafkaPublisher) Publish(ctx context.Context, msgs []Message) []error {
+	kmsgs := make([]kafka.Message, len(msgs))
+	for i, m := range msgs {
+		headers := make([]kafka.Header, 0, len(m.Headers))
+		for k, v := range m.Headers {
+			headers = append(headers, kafka.Header{Key: k, Value: []byte(v)})
+		}
+		kmsgs[i] = kafka.Message{Topic: m.Topic, Key: m.Key, Value: m.Value, Headers: headers}
+	}
+
+	errs := make([]error, len(msgs))
+	err := p.writer.WriteMessages(ctx, kmsgs...)
+	if err == nil {
+		return errs
+	}
+
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) && len(writeErrs) == len(msgs) {
+		copy(errs, writeErrs)
+		return errs
+	}
+	for i := range errs {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Close descarrega mensagens pendentes e fecha as conexões
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
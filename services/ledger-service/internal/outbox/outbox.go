@@ -0,0 +1,143 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Transactional outbox (event_sourcing.outbox_events)
+// ============================================================================
+
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Tópicos Kafka criados pelo kafka-init do docker-compose
+const (
+	TopicTransactionsCreated   = "transactions.created"
+	TopicAccountBalanceUpdates = "accounts.balance-updates"
+	TopicAccountEvents         = "accounts.events"
+	TopicPaymentsProcessed     = "payments.processed"
+	TopicDLQTransactions       = "dlq.transactions"
+)
+
+// Status espelha o CHECK de event_sourcing.outbox_events.status
+type Status string
+
+const (
+	StatusPending    Status = "PENDING"
+	StatusPublishing Status = "PUBLISHING"
+	StatusPublished  Status = "PUBLISHED"
+	StatusFailed     Status = "FAILED"
+)
+
+// Event é um evento a ser gravado na outbox junto com a mudança que o originou
+type Event struct {
+	AggregateType string
+	AggregateID   string
+	EventType     string
+	EventVersion  int
+	Payload       interface{}
+	Metadata      map[string]interface{}
+	Topic         string
+	PartitionKey  string
+}
+
+// Insert grava os eventos na transação SQL informada.
+// O evento só existe se a transação de negócio for confirmada.
+func Insert(ctx context.Context, tx *sql.Tx, events ...Event) error {
+	for _, e := range events {
+		payload, err := json.Marshal(e.Payload)
+		if err != nil {
+			return fmt.Errorf("encode %s payload: %w", e.EventType, err)
+		}
+		metadata := e.Metadata
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("encode %s metadata: %w", e.EventType, err)
+		}
+		version := e.EventVersion
+		if version == 0 {
+			version = 1
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO event_sourcing.outbox_events (
+				aggregate_type, aggregate_id, event_type, event_version,
+				payload, metadata, topic, partition_key
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		`,
+			e.AggregateType, e.AggregateID, e.EventType, version,
+			payload, metadataJSON, e.Topic, e.PartitionKey,
+		)
+		if err != nil {
+			return fmt.Errorf("insert outbox event %s: %w", e.EventType, err)
+		}
+	}
+	return nil
+}
+
+// record é uma linha reivindicada pelo relay
+type record struct {
+	ID            string
+	AggregateType string
+	AggregateID   string
+	EventType     string
+	EventVersion  int
+	Payload       json.RawMessage
+	Metadata      json.RawMessage
+	Topic         string
+	PartitionKey  string
+	RetryCount    int
+	MaxRetries    int
+	CreatedAt     time.Time
+}
+
+// envelope é o corpo publicado no Kafka
+type envelope struct {
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	EventVersion  int             `json:"event_version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Payload       json.RawMessage `json:"payload"`
+	Metadata      json.RawMessage `json:"metadata,omitempty"`
+}
+
+// message monta a mensagem Kafka; sem partition_key, o agregado define a partição
+func (r record) message() (Message, error) {
+	value, err := json.Marshal(envelope{
+		EventID:       r.ID,
+		EventType:     r.EventType,
+		EventVersion:  r.EventVersion,
+		AggregateType: r.AggregateType,
+		AggregateID:   r.AggregateID,
+		OccurredAt:    r.CreatedAt.UTC(),
+		Payload:       r.Payload,
+		Metadata:      r.Metadata,
+	})
+	if err != nil {
+		return Message{}, fmt.Errorf("encode envelope: %w", err)
+	}
+
+	key := r.PartitionKey
+	if key == "" {
+		key = r.AggregateID
+	}
+	return Message{
+		Topic: r.Topic,
+		Key:   []byte(key),
+		Value: value,
+		Headers: map[string]string{
+			"event_id":       r.ID,
+			"event_type":     r.EventType,
+			"aggregate_type": r.AggregateType,
+			"aggregate_id":   r.AggregateID,
+		},
+	}, nil
+}
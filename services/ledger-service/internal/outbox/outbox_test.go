@@ -0,0 +1,81 @@
+package outbox
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	base, max := time.Second, time.Minute
+
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{6, 32 * time.Second},
+		{7, time.Minute},
+		{20, time.Minute},
+	}
+	for _, tc := range cases {
+		for i := 0; i < 50; i++ {
+			got := Backoff(tc.attempt, base, max)
+			low := time.Duration(float64(tc.want) * 0.8)
+			high := time.Duration(float64(tc.want) * 1.2)
+			if got < low || got > high {
+				t.Fatalf("Backoff(%d) = %v, want within [%v, %v]", tc.attempt, got, low, high)
+			}
+		}
+	}
+}
+
+func TestRecordMessage(t *testing.T) {
+	rec := record{
+		ID:            "11111111-1111-1111-1111-111111111111",
+		AggregateType: "Account",
+		AggregateID:   "22222222-2222-2222-2222-222222222222",
+		EventType:     "BalanceUpdated",
+		EventVersion:  1,
+		Payload:       json.RawMessage(`{"balance_after":100}`),
+		Metadata:      json.RawMessage(`{}`),
+		Topic:         TopicAccountBalanceUpdates,
+		CreatedAt:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+
+	msg, err := rec.message()
+	if err != nil {
+		t.Fatalf("message() error = %v", err)
+	}
+	if string(msg.Key) != rec.AggregateID {
+		t.Errorf("key = %q, want aggregate id", msg.Key)
+	}
+	if msg.Topic != TopicAccountBalanceUpdates {
+		t.Errorf("topic = %q", msg.Topic)
+	}
+	if msg.Headers["event_id"] != rec.ID || msg.Headers["event_type"] != "BalanceUpdated" {
+		t.Errorf("headers = %v", msg.Headers)
+	}
+
+	var env envelope
+	if err := json.Unmarshal(msg.Value, &env); err != nil {
+		t.Fatalf("unmarshal envelope: %v", err)
+	}
+	if env.EventID != rec.ID || string(env.Payload) != `{"balance_after":100}` {
+		t.Errorf("envelope = %+v", env)
+	}
+
+	rec.PartitionKey = "custom-key"
+	msg, _ = rec.message()
+	if string(msg.Key) != "custom-key" {
+		t.Errorf("key = %q, want partition key", msg.Key)
+	}
+
+	// Payload inválido não vira mensagem (o relay não a publica)
+	rec.Payload = json.RawMessage(`{"balance_after":`)
+	if msg, err := rec.message(); err == nil || msg.Value != nil {
+		t.Errorf("message() with invalid payload = %+v, %v; want error", msg, err)
+	}
+}
@@ -0,0 +1,303 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Relay da outbox para o Kafka com backoff e dead-letter
+// ============================================================================
+
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_events_published_total",
+		Help: "Outbox events published to Kafka",
+	}, []string{"topic"})
+	eventsFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_events_failed_total",
+		Help: "Outbox publish attempts that failed and were scheduled for retry",
+	}, []string{"topic"})
+	eventsDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "outbox_events_dead_lettered_total",
+		Help: "Outbox events moved to the dead-letter topic after exhausting retries",
+	}, []string{"topic"})
+)
+
+// RelayConfig controla o relay
+type RelayConfig struct {
+	BatchSize    int
+	PollInterval time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+	// Lease é por quanto tempo uma linha PUBLISHING fica reservada para esta instância;
+	// após expirar, outra réplica pode reivindicá-la (ex.: processo morto no meio do envio).
+	Lease    time.Duration
+	DLQTopic string
+}
+
+// DefaultRelayConfig devolve valores adequados para o ambiente local
+func DefaultRelayConfig() RelayConfig {
+	return RelayConfig{
+		BatchSize:    100,
+		PollInterval: 500 * time.Millisecond,
+		BaseBackoff:  time.Second,
+		MaxBackoff:   5 * time.Minute,
+		Lease:        time.Minute,
+		DLQTopic:     TopicDLQTransactions,
+	}
+}
+
+// Relay publica eventos pendentes da outbox
+type Relay struct {
+	db        *sql.DB
+	publisher Publisher
+	config    RelayConfig
+	logger    *zap.SugaredLogger
+}
+
+// NewRelay cria um relay sobre o pool de conexões e o publisher informados
+func NewRelay(db *sql.DB, publisher Publisher, config RelayConfig, logger *zap.SugaredLogger) *Relay {
+	return &Relay{db: db, publisher: publisher, config: config, logger: logger}
+}
+
+// Run publica lotes até o contexto ser cancelado; lotes cheios são seguidos imediatamente
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Errorw("Outbox relay failed", "error", err)
+		}
+
+		wait := r.config.PollInterval
+		if err == nil && n == r.config.BatchSize {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RelayOnce reivindica um lote, publica e grava o resultado de cada evento
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	records, err := r.claim(ctx)
+	if err != nil || len(records) == 0 {
+		return 0, err
+	}
+
+	// Eventos que não serializam não entram no lote: uma mensagem vazia seria
+	// publicada como sucesso. Eles seguem direto para markFailed.
+	msgs := make([]Message, len(records))
+	results := make([]error, len(records))
+	batch := make([]Message, 0, len(records))
+	indexes := make([]int, 0, len(records))
+	for i, rec := range records {
+		msgs[i], results[i] = rec.message()
+		if results[i] == nil {
+			batch = append(batch, msgs[i])
+			indexes = append(indexes, i)
+		}
+	}
+	if len(batch) > 0 {
+		for j, publishErr := range r.publisher.Publish(ctx, batch) {
+			results[indexes[j]] = publishErr
+		}
+	}
+
+	for i, rec := range records {
+		if results[i] == nil {
+			err = r.markPublished(ctx, rec)
+		} else {
+			err = r.markFailed(ctx, rec, msgs[i], results[i])
+		}
+		if err != nil {
+			return len(records), err
+		}
+	}
+	return len(records), nil
+}
+
+// claim marca como PUBLISHING os eventos prontos para envio.
+// SKIP LOCKED permite várias réplicas do relay sem publicar o mesmo evento duas vezes.
+// Um evento só sai depois dos anteriores da mesma chave de partição (a chave
+// da mensagem): enquanto um deles estiver pendente, em envio ou aguardando
+// retentativa, os seguintes esperam. Eventos no dead-letter não bloqueiam.
+func (r *Relay) claim(ctx context.Context) ([]record, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE event_sourcing.outbox_events o
+		SET status = 'PUBLISHING',
+		    next_retry_at = NOW() + $2::interval
+		FROM (
+			SELECT e.id
+			FROM event_sourcing.outbox_events e
+			WHERE (e.status = 'PENDING'
+			       OR (e.status = 'FAILED' AND e.retry_count < e.max_retries AND e.next_retry_at <= NOW())
+			       OR (e.status = 'PUBLISHING' AND e.next_retry_at <= NOW()))
+			  AND NOT EXISTS (
+			      SELECT 1
+			      FROM event_sourcing.outbox_events prev
+			      WHERE COALESCE(NULLIF(prev.partition_key, ''), prev.aggregate_id::text)
+			            = COALESCE(NULLIF(e.partition_key, ''), e.aggregate_id::text)
+			        AND prev.created_at < e.created_at
+			        AND prev.status <> 'PUBLISHED'
+			        AND NOT (prev.status = 'FAILED' AND prev.retry_count >= prev.max_retries)
+			  )
+			ORDER BY e.created_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		) claimed
+		WHERE o.id = claimed.id
+		RETURNING o.id, o.aggregate_type, o.aggregate_id, o.event_type, COALESCE(o.event_version, 1),
+		          o.payload, COALESCE(o.metadata, '{}'), o.topic, COALESCE(o.partition_key, ''),
+		          COALESCE(o.retry_count, 0), COALESCE(o.max_retries, 5), o.created_at
+	`, r.config.BatchSize, fmt.Sprintf("%d milliseconds", r.config.Lease.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var records []record
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.ID, &rec.AggregateType, &rec.AggregateID, &rec.EventType,
+			&rec.EventVersion, &rec.Payload, &rec.Metadata, &rec.Topic, &rec.PartitionKey,
+			&rec.RetryCount, &rec.MaxRetries, &rec.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", err)
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", err)
+	}
+
+	// UPDATE ... RETURNING não garante ordem
+	sortByCreatedAt(records)
+	return records, nil
+}
+
+func (r *Relay) markPublished(ctx context.Context, rec record) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE event_sourcing.outbox_events
+		SET status = 'PUBLISHED', published_at = NOW(), next_retry_at = NULL, last_error = NULL
+		WHERE id = $1
+	`, rec.ID)
+	if err != nil {
+		return fmt.Errorf("mark event %s published: %w", rec.ID, err)
+	}
+	eventsPublished.WithLabelValues(rec.Topic).Inc()
+	return nil
+}
+
+// markFailed agenda um novo envio com backoff exponencial ou, esgotadas as
+// tentativas, publica no tópico de dead-letter.
+func (r *Relay) markFailed(ctx context.Context, rec record, msg Message, cause error) error {
+	attempts := rec.RetryCount + 1
+	if attempts >= rec.MaxRetries {
+		return r.deadLetter(ctx, rec, msg, cause)
+	}
+
+	delay := Backoff(attempts, r.config.BaseBackoff, r.config.MaxBackoff)
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE event_sourcing.outbox_events
+		SET status = 'FAILED',
+		    retry_count = $2,
+		    next_retry_at = NOW() + $3::interval,
+		    last_error = $4
+		WHERE id = $1
+	`, rec.ID, attempts, fmt.Sprintf("%d milliseconds", delay.Milliseconds()), cause.Error())
+	if err != nil {
+		return fmt.Errorf("mark event %s failed: %w", rec.ID, err)
+	}
+	eventsFailed.WithLabelValues(rec.Topic).Inc()
+	r.logger.Warnw("Outbox event publish failed, retry scheduled",
+		"event_id", rec.ID,
+		"topic", rec.Topic,
+		"attempt", attempts,
+		"retry_in", delay.String(),
+		"error", cause,
+	)
+	return nil
+}
+
+// deadLetter publica o evento no DLQ com a causa e o tópico original nos headers.
+// Se o próprio DLQ falhar, o evento volta a ser tentado no próximo ciclo.
+func (r *Relay) deadLetter(ctx context.Context, rec record, msg Message, cause error) error {
+	dlq := Message{
+		Topic:   r.config.DLQTopic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: map[string]string{},
+	}
+	for k, v := range msg.Headers {
+		dlq.Headers[k] = v
+	}
+	dlq.Headers["original_topic"] = rec.Topic
+	dlq.Headers["error"] = cause.Error()
+	dlq.Headers["attempts"] = fmt.Sprint(rec.RetryCount + 1)
+	if dlq.Value == nil {
+		// Falha de serialização: publicar o payload bruto
+		dlq.Value = rec.Payload
+	}
+
+	if errs := r.publisher.Publish(ctx, []Message{dlq}); errs[0] != nil {
+		_, err := r.db.ExecContext(ctx, `
+			UPDATE event_sourcing.outbox_events
+			SET status = 'FAILED', next_retry_at = NOW() + $2::interval, last_error = $3
+			WHERE id = $1
+		`, rec.ID, fmt.Sprintf("%d milliseconds", r.config.MaxBackoff.Milliseconds()),
+			"dead-letter publish failed: "+errs[0].Error())
+		if err != nil {
+			return fmt.Errorf("mark event %s failed: %w", rec.ID, err)
+		}
+		r.logger.Errorw("Outbox dead-letter publish failed", "event_id", rec.ID, "error", errs[0])
+		return nil
+	}
+
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE event_sourcing.outbox_events
+		SET status = 'FAILED', retry_count = max_retries, next_retry_at = NULL, last_error = $2
+		WHERE id = $1
+	`, rec.ID, "dead-lettered to "+r.config.DLQTopic+": "+cause.Error())
+	if err != nil {
+		return fmt.Errorf("mark event %s dead-lettered: %w", rec.ID, err)
+	}
+	eventsDeadLettered.WithLabelValues(rec.Topic).Inc()
+	r.logger.Errorw("Outbox event dead-lettered",
+		"event_id", rec.ID,
+		"topic", rec.Topic,
+		"dlq", r.config.DLQTopic,
+		"error", cause,
+	)
+	return nil
+}
+
+// Backoff devolve o atraso da tentativa n (1, 2, 4, 8... x base, limitado a max) com jitter de ±20%
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	jitter := time.Duration(float64(delay) * 0.2 * (2*rand.Float64() - 1))
+	return delay + jitter
+}
+
+func sortByCreatedAt(records []record) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+}
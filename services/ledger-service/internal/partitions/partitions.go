@@ -0,0 +1,273 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Gestão de partições mensais (criação antecipada e retenção)
+// ============================================================================
+
+package partitions
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidPolicy indica uma configuração de retenção inválida
+var ErrInvalidPolicy = errors.New("invalid partition policy")
+
+// Table descreve uma tabela particionada por RANGE (partition_date) com partições mensais
+type Table struct {
+	Schema string
+	Name   string
+	// RetentionMonths é quantos meses completos manter anexados; 0 = manter sempre
+	RetentionMonths int
+}
+
+// QualifiedName devolve schema.tabela com identificadores escapados
+func (t Table) QualifiedName() string {
+	return pq.QuoteIdentifier(t.Schema) + "." + pq.QuoteIdentifier(t.Name)
+}
+
+func (t Table) String() string {
+	return t.Schema + "." + t.Name
+}
+
+// partitionName segue o padrão do schema.sql: <tabela>_YYYY_MM
+func (t Table) partitionName(month time.Time) string {
+	return fmt.Sprintf("%s_%04d_%02d", t.Name, month.Year(), int(month.Month()))
+}
+
+// DefaultTables são as tabelas particionadas do schema.
+// Ledger e transações são imutáveis e não expiram; logs de integração expiram primeiro.
+func DefaultTables() []Table {
+	return []Table{
+		{Schema: "core", Name: "transactions"},
+		{Schema: "core", Name: "ledger_entries"},
+		{Schema: "payments", Name: "card_transactions"},
+		{Schema: "audit", Name: "audit_log", RetentionMonths: 60},
+		{Schema: "integrations", Name: "api_logs", RetentionMonths: 6},
+	}
+}
+
+// ApplyRetention sobrescreve a retenção a partir de "schema.tabela=meses,..."
+func ApplyRetention(tables []Table, spec string) ([]Table, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return tables, nil
+	}
+
+	out := append([]Table(nil), tables...)
+	for _, item := range strings.Split(spec, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q must be schema.table=months", ErrInvalidPolicy, item)
+		}
+		months, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || months < 0 {
+			return nil, fmt.Errorf("%w: %q retention must be a non-negative number of months", ErrInvalidPolicy, item)
+		}
+
+		found := false
+		for i := range out {
+			if out[i].String() == strings.TrimSpace(name) {
+				out[i].RetentionMonths = months
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s is not a managed table", ErrInvalidPolicy, name)
+		}
+	}
+	return out, nil
+}
+
+// Config controla o Manager
+type Config struct {
+	Tables        []Table
+	MonthsAhead   int
+	ArchiveSchema string // partições expiradas são desanexadas e movidas para este schema
+	Interval      time.Duration
+}
+
+// Manager cria partições futuras e aplica a política de retenção
+type Manager struct {
+	db     *sql.DB
+	config Config
+	logger *zap.SugaredLogger
+}
+
+// NewManager cria um Manager sobre o pool de conexões informado
+func NewManager(db *sql.DB, config Config, logger *zap.SugaredLogger) *Manager {
+	return &Manager{db: db, config: config, logger: logger}
+}
+
+// maintenanceLockKey serializa a manutenção entre réplicas do serviço
+const maintenanceLockKey = "ledger-service:partition-maintenance"
+
+// Maintain garante as partições do mês corrente até MonthsAhead e desanexa as expiradas
+func (m *Manager) Maintain(ctx context.Context, now time.Time) error {
+	current := monthStart(now)
+	for _, table := range m.config.Tables {
+		if err := m.maintainTable(ctx, table, current); err != nil {
+			return fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) maintainTable(ctx context.Context, table Table, current time.Time) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	err = tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtextextended($1, 0))`,
+		maintenanceLockKey+":"+table.String()).Scan(&locked)
+	if err != nil {
+		return fmt.Errorf("acquire lock: %w", err)
+	}
+	if !locked {
+		// Outra réplica está cuidando desta tabela
+		return nil
+	}
+
+	existing, err := attachedPartitions(ctx, tx, table)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i <= m.config.MonthsAhead; i++ {
+		month := current.AddDate(0, i, 0)
+		name := table.partitionName(month)
+		if existing[name] {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(
+			`CREATE TABLE IF NOT EXISTS %s.%s PARTITION OF %s FOR VALUES FROM ('%s') TO ('%s')`,
+			pq.QuoteIdentifier(table.Schema), pq.QuoteIdentifier(name), table.QualifiedName(),
+			month.Format("2006-01-02"), month.AddDate(0, 1, 0).Format("2006-01-02"),
+		))
+		if err != nil {
+			return fmt.Errorf("create partition %s: %w", name, err)
+		}
+		m.logger.Infow("Partition created", "table", table.String(), "partition", name)
+	}
+
+	if table.RetentionMonths > 0 {
+		cutoff := current.AddDate(0, -table.RetentionMonths, 0)
+		for name := range existing {
+			month, ok := partitionMonth(table, name)
+			if !ok || !month.Before(cutoff) {
+				continue
+			}
+			if err := m.archive(ctx, tx, table, name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// archive desanexa a partição e a move para o schema de arquivo
+func (m *Manager) archive(ctx context.Context, tx *sql.Tx, table Table, name string) error {
+	partition := pq.QuoteIdentifier(table.Schema) + "." + pq.QuoteIdentifier(name)
+	archived := table.Schema + "_" + name
+
+	statements := []string{
+		fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, table.QualifiedName(), partition),
+		fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS %s`, pq.QuoteIdentifier(m.config.ArchiveSchema)),
+		// Prefixar com o schema de origem evita colisão de nomes no arquivo
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, partition, pq.QuoteIdentifier(archived)),
+		fmt.Sprintf(`ALTER TABLE %s.%s SET SCHEMA %s`, pq.QuoteIdentifier(table.Schema),
+			pq.QuoteIdentifier(archived), pq.QuoteIdentifier(m.config.ArchiveSchema)),
+	}
+	for _, stmt := range statements {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("archive partition %s: %w", name, err)
+		}
+	}
+	m.logger.Infow("Partition detached and archived",
+		"table", table.String(),
+		"partition", name,
+		"archive", m.config.ArchiveSchema+"."+archived,
+	)
+	return nil
+}
+
+// Run executa a manutenção a cada Interval até o contexto ser cancelado
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := m.Maintain(ctx, now); err != nil && ctx.Err() == nil {
+				m.logger.Errorw("Partition maintenance failed", "error", err)
+			}
+		}
+	}
+}
+
+// attachedPartitions lista as partições anexadas à tabela
+func attachedPartitions(ctx context.Context, tx *sql.Tx, table Table) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT child.relname
+		FROM pg_inherits i
+		JOIN pg_class parent ON parent.oid = i.inhparent
+		JOIN pg_namespace ns ON ns.oid = parent.relnamespace
+		JOIN pg_class child ON child.oid = i.inhrelid
+		WHERE ns.nspname = $1 AND parent.relname = $2
+	`, table.Schema, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan partition: %w", err)
+		}
+		existing[name] = true
+	}
+	return existing, rows.Err()
+}
+
+var partitionSuffix = regexp.MustCompile(`_(\d{4})_(\d{2})$`)
+
+// partitionMonth extrai o mês de uma partição <tabela>_YYYY_MM
+func partitionMonth(table Table, name string) (time.Time, bool) {
+	if !strings.HasPrefix(name, table.Name+"_") {
+		return time.Time{}, false
+	}
+	m := partitionSuffix.FindStringSubmatch(name)
+	if m == nil || len(name) != len(table.Name)+len(m[0]) {
+		return time.Time{}, false
+	}
+	month, err := time.Parse("2006-01", m[1]+"-"+m[2])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,45 @@
+package partitions
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPartitionMonth(t *testing.T) {
+	entries := Table{Schema: "core", Name: "ledger_entries"}
+
+	month, ok := partitionMonth(entries, "ledger_entries_2024_01")
+	if !ok || !month.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("partitionMonth = %v, %v", month, ok)
+	}
+	if got := entries.partitionName(month.AddDate(0, 11, 0)); got != "ledger_entries_2024_12" {
+		t.Fatalf("partitionName = %s", got)
+	}
+
+	for _, name := range []string{"ledger_entries_default", "ledger_entries_old_2024_01", "transactions_2024_01", "ledger_entries_2024_13"} {
+		if _, ok := partitionMonth(entries, name); ok {
+			t.Errorf("partitionMonth(%q) should not match", name)
+		}
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	tables, err := ApplyRetention(DefaultTables(), "integrations.api_logs=3, core.transactions=120")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, table := range tables {
+		got[table.String()] = table.RetentionMonths
+	}
+	if got["integrations.api_logs"] != 3 || got["core.transactions"] != 120 || got["audit.audit_log"] != 60 {
+		t.Fatalf("unexpected retention: %v", got)
+	}
+
+	for _, spec := range []string{"core.unknown=1", "core.transactions", "core.transactions=-1"} {
+		if _, err := ApplyRetention(DefaultTables(), spec); !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("ApplyRetention(%q) expected ErrInvalidPolicy, got %v", spec, err)
+		}
+	}
+}
@@ -0,0 +1,651 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Execução de pagamentos agendados e recorrentes (payments.payment_intents)
+// ============================================================================
+
+package payments
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+	"github.com/kaminoclone/ledger-service/internal/outbox"
+)
+
+// ErrNotExecutable indica um intent que o executor não sabe lançar
+var ErrNotExecutable = errors.New("payment intent cannot be executed")
+
+// Status espelha o enum payments.payment_status
+type Status string
+
+const (
+	StatusApproved   Status = "APPROVED"
+	StatusProcessing Status = "PROCESSING"
+	StatusCompleted  Status = "COMPLETED"
+	StatusFailed     Status = "FAILED"
+)
+
+// TransactionType é o tipo das transações do ledger geradas pelo executor
+const TransactionType = "SCHEDULED_PAYMENT"
+
+var executionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ledger_scheduled_payments_total",
+	Help: "Scheduled payment executions by outcome",
+}, []string{"outcome"})
+
+// Config controla lote, periodicidade e retentativas do executor
+type Config struct {
+	Interval   time.Duration
+	BatchSize  int
+	MaxRetries int
+	RetryDelay time.Duration
+
+	// Erros de infraestrutura desfazem a execução; o intent é adiado com
+	// backoff (Interval a RetryDelay) e vira FAILED após MaxAttempts
+	MaxAttempts int
+
+	// Conta de receita das tarifas (fee_amount) e conta transitória que
+	// recebe o valor de destinos externos (BANK_ACCOUNT, PIX_KEY) até a
+	// liquidação pela integração
+	FeeAccountID        string
+	SettlementAccountID string
+}
+
+// Intent é a parte de payments.payment_intents usada na execução
+type Intent struct {
+	ID                   string
+	UserID               string
+	SourceAccountID      string
+	DestinationType      string
+	DestinationAccountID string
+	Amount               ledger.Money
+	FeeAmount            ledger.Money
+	Currency             string
+	PaymentMethod        string
+	Direction            string
+	Description          string
+	ScheduledFor         time.Time
+	IsRecurring          bool
+	RecurringConfig      []byte
+	Metadata             map[string]interface{}
+}
+
+// retryCount lê o contador de retentativas gravado no metadata
+func (i *Intent) retryCount() int {
+	n, _ := i.Metadata["retry_count"].(float64)
+	return int(n)
+}
+
+// recurrence lê a regra de um intent recorrente (nil nos avulsos)
+func (i *Intent) recurrence() (*Recurrence, error) {
+	if !i.IsRecurring {
+		return nil, nil
+	}
+	r, err := ParseRecurrence(i.RecurringConfig)
+	if err != nil {
+		return nil, err
+	}
+	if r.NominalAt == nil {
+		// Retentativas e adiamentos mudam scheduled_for; a série segue a data original
+		nominal := i.ScheduledFor
+		r.NominalAt = &nominal
+	}
+	return &r, nil
+}
+
+// seriesID identifica a série recorrente: o primeiro intent dela
+func (i *Intent) seriesID() string {
+	if id, ok := i.Metadata["recurrence_series_id"].(string); ok && id != "" {
+		return id
+	}
+	return i.ID
+}
+
+// PaymentExecuted é publicado em payments.processed após o lançamento
+type PaymentExecuted struct {
+	PaymentIntentID     string       `json:"payment_intent_id"`
+	Status              Status       `json:"status"`
+	LedgerTransactionID string       `json:"ledger_transaction_id"`
+	SourceAccountID     string       `json:"source_account_id"`
+	DestinationType     string       `json:"destination_type"`
+	Amount              ledger.Money `json:"amount"`
+	FeeAmount           ledger.Money `json:"fee_amount"`
+	Currency            string       `json:"currency"`
+	PaymentMethod       string       `json:"payment_method"`
+	ScheduledFor        time.Time    `json:"scheduled_for"`
+	ExecutedAt          time.Time    `json:"executed_at"`
+}
+
+// Executor lança no ledger os intents APPROVED com scheduled_for vencido
+type Executor struct {
+	db       *sql.DB
+	ledger   *ledger.Store
+	calendar *Calendar
+	config   Config
+	logger   *zap.SugaredLogger
+	onPosted []func(ctx context.Context, accountIDs ...string)
+}
+
+// NewExecutor cria o executor; lançamentos passam pelo ledger informado
+func NewExecutor(db *sql.DB, ledgerStore *ledger.Store, calendar *Calendar, config Config, logger *zap.SugaredLogger) *Executor {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 100
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 5
+	}
+	return &Executor{db: db, ledger: ledgerStore, calendar: calendar, config: config, logger: logger}
+}
+
+// OnPosted registra um observador chamado após o commit de cada lançamento,
+// com as contas movimentadas (ex.: refresh do cache de saldos). Deve ser
+// chamado antes de Run.
+func (e *Executor) OnPosted(fn func(ctx context.Context, accountIDs ...string)) {
+	e.onPosted = append(e.onPosted, fn)
+}
+
+// Run executa até o contexto ser cancelado
+func (e *Executor) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.executeDue(ctx)
+		}
+	}
+}
+
+// executeDue processa até BatchSize intents vencidos, um por transação SQL
+func (e *Executor) executeDue(ctx context.Context) {
+	for i := 0; i < e.config.BatchSize; i++ {
+		found, err := e.ExecuteNext(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				e.logger.Errorw("Failed to execute scheduled payment", "error", err)
+			}
+			return
+		}
+		if !found {
+			return
+		}
+	}
+}
+
+// ExecuteNext reivindica o próximo intent vencido e o executa. SKIP LOCKED
+// permite várias réplicas; o lock do intent vale até o commit, então cada
+// ocorrência é lançada uma única vez. Devolve false quando não há intents.
+// Um erro na execução do intent é registrado nele (recordFailure) e não
+// interrompe o lote; erros devolvidos impedem reivindicar o próximo.
+func (e *Executor) ExecuteNext(ctx context.Context) (bool, error) {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	intent, err := claimDue(ctx, tx)
+	if err != nil || intent == nil {
+		return false, err
+	}
+
+	outcome, txn, err := e.execute(ctx, tx, intent, time.Now())
+	if err == nil {
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commit transaction: %w", err)
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return true, e.recordFailure(ctx, intent, err)
+	}
+	if txn != nil {
+		accountIDs := make([]string, 0, len(txn.Entries))
+		for _, entry := range txn.Entries {
+			accountIDs = append(accountIDs, entry.AccountID)
+		}
+		for _, fn := range e.onPosted {
+			fn(ctx, accountIDs...)
+		}
+	}
+
+	executionsTotal.WithLabelValues(outcome).Inc()
+	e.logger.Infow("Scheduled payment processed",
+		"payment_intent_id", intent.ID,
+		"outcome", outcome,
+		"scheduled_for", intent.ScheduledFor,
+	)
+	return true, nil
+}
+
+// recordFailure registra, em transação própria, uma execução desfeita por
+// erro de infraestrutura: incrementa attempts e adia o intent para que os
+// seguintes da fila sigam. Atingido MaxAttempts, a ocorrência vira FAILED.
+func (e *Executor) recordFailure(ctx context.Context, intent *Intent, cause error) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var attempts int
+	err = tx.QueryRowContext(ctx, `
+		UPDATE payments.payment_intents
+		SET attempts = attempts + 1, updated_at = NOW()
+		WHERE id = $1 AND status = 'APPROVED'
+		RETURNING attempts
+	`, intent.ID).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Outra réplica já resolveu o intent
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("record payment intent %s failure: %w", intent.ID, err)
+	}
+
+	// Regra ilegível não chega aqui: execute já encerra o intent
+	recurrence, _ := intent.recurrence()
+	outcome := "error"
+	if attempts >= e.config.MaxAttempts {
+		outcome = "failed"
+		err = e.fail(ctx, tx, intent, recurrence, fmt.Errorf("%d attempts failed: %w", attempts, cause))
+	} else {
+		next := time.Now().Add(outbox.Backoff(attempts, e.config.Interval, e.config.RetryDelay))
+		err = reschedule(ctx, tx, intent, recurrence, next, map[string]interface{}{"last_error": cause.Error()})
+	}
+	if err != nil {
+		return fmt.Errorf("record payment intent %s failure: %w", intent.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	executionsTotal.WithLabelValues(outcome).Inc()
+	e.logger.Errorw("Scheduled payment execution failed",
+		"payment_intent_id", intent.ID,
+		"attempt", attempts,
+		"max_attempts", e.config.MaxAttempts,
+		"outcome", outcome,
+		"error", cause,
+	)
+	return nil
+}
+
+func claimDue(ctx context.Context, tx *sql.Tx) (*Intent, error) {
+	var intent Intent
+	var destination sql.NullString
+	var description sql.NullString
+	var recurring sql.NullBool
+	var metadata []byte
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, user_id, source_account_id, destination_type, destination_account_id,
+		       amount, COALESCE(fee_amount, 0), currency, payment_method, direction,
+		       description, scheduled_for, is_recurring, recurring_config, COALESCE(metadata, '{}')
+		FROM payments.payment_intents
+		WHERE status = 'APPROVED' AND scheduled_for IS NOT NULL AND scheduled_for <= NOW()
+		ORDER BY scheduled_for
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`).Scan(
+		&intent.ID, &intent.UserID, &intent.SourceAccountID, &intent.DestinationType, &destination,
+		&intent.Amount, &intent.FeeAmount, &intent.Currency, &intent.PaymentMethod, &intent.Direction,
+		&description, &intent.ScheduledFor, &recurring, &intent.RecurringConfig, &metadata,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("claim due payment intent: %w", err)
+	}
+	intent.DestinationAccountID = destination.String
+	intent.Description = description.String
+	intent.IsRecurring = recurring.Bool
+	if err := json.Unmarshal(metadata, &intent.Metadata); err != nil || intent.Metadata == nil {
+		intent.Metadata = map[string]interface{}{}
+	}
+	return &intent, nil
+}
+
+// execute decide entre adiar, lançar, retentar ou falhar o intent.
+// Devolve o desfecho para métricas e o lançamento, se houve; erros
+// devolvidos são de infraestrutura e desfazem tudo para nova tentativa
+// no próximo ciclo.
+func (e *Executor) execute(ctx context.Context, tx *sql.Tx, intent *Intent, now time.Time) (string, *ledger.Transaction, error) {
+	recurrence, err := intent.recurrence()
+	if err != nil {
+		return "failed", nil, e.fail(ctx, tx, intent, nil, err)
+	}
+	rule := BusinessDayFollowing
+	if recurrence != nil {
+		rule = recurrence.BusinessDay
+	}
+
+	// Vencimento em dia não útil vai para o próximo dia útil, no mesmo horário
+	if rule == BusinessDayFollowing && !e.calendar.IsBusinessDay(now) {
+		next := e.calendar.Adjust(atClock(now.AddDate(0, 0, 1), intent.ScheduledFor), BusinessDayFollowing)
+		return "deferred", nil, reschedule(ctx, tx, intent, recurrence, next, nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT scheduled_payment`); err != nil {
+		return "", nil, fmt.Errorf("savepoint: %w", err)
+	}
+	txn, err := e.post(ctx, tx, intent)
+	if err != nil {
+		if !isBusinessError(err) {
+			return "", nil, err
+		}
+		if _, rbErr := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT scheduled_payment`); rbErr != nil {
+			return "", nil, fmt.Errorf("rollback to savepoint: %w", rbErr)
+		}
+		if isRetryable(err) && intent.retryCount() < e.config.MaxRetries {
+			return "retry", nil, e.retry(ctx, tx, intent, recurrence, rule, now, err)
+		}
+		return "failed", nil, e.fail(ctx, tx, intent, recurrence, err)
+	}
+
+	return "completed", txn, e.complete(ctx, tx, intent, recurrence, txn, now)
+}
+
+// post lança o débito na origem e os créditos no destino e na conta de tarifas
+func (e *Executor) post(ctx context.Context, tx *sql.Tx, intent *Intent) (*ledger.Transaction, error) {
+	if intent.Direction != "OUTBOUND" {
+		return nil, fmt.Errorf("%w: direction %s", ErrNotExecutable, intent.Direction)
+	}
+
+	var destination string
+	switch intent.DestinationType {
+	case "INTERNAL":
+		destination = intent.DestinationAccountID
+	case "BANK_ACCOUNT", "PIX_KEY":
+		destination = e.config.SettlementAccountID
+	}
+	if destination == "" {
+		return nil, fmt.Errorf("%w: no ledger account for destination %s", ErrNotExecutable, intent.DestinationType)
+	}
+
+	legs := []ledger.Leg{
+		{AccountID: intent.SourceAccountID, EntryType: ledger.EntryDebit, Amount: intent.Amount + intent.FeeAmount},
+		{AccountID: destination, EntryType: ledger.EntryCredit, Amount: intent.Amount},
+	}
+	if intent.FeeAmount > 0 {
+		if e.config.FeeAccountID == "" {
+			return nil, fmt.Errorf("%w: fee account is not configured", ErrNotExecutable)
+		}
+		legs = append(legs, ledger.Leg{AccountID: e.config.FeeAccountID, EntryType: ledger.EntryCredit, Amount: intent.FeeAmount})
+	}
+
+	description := intent.Description
+	if description == "" {
+		description = "Pagamento agendado"
+	}
+	return e.ledger.PostTx(ctx, tx, ledger.PostingRequest{
+		ReferenceID:     "payment-intent:" + intent.ID,
+		TransactionType: TransactionType,
+		Description:     description,
+		Currency:        intent.Currency,
+		Tags:            []string{"scheduled", intent.PaymentMethod},
+		Metadata: map[string]interface{}{
+			"payment_intent_id": intent.ID,
+			"scheduled_for":     intent.ScheduledFor,
+			"destination_type":  intent.DestinationType,
+		},
+		Legs: legs,
+	})
+}
+
+// complete registra o lançamento. Destinos internos terminam aqui; externos
+// ficam em PROCESSING até a integração confirmar a liquidação.
+func (e *Executor) complete(ctx context.Context, tx *sql.Tx, intent *Intent, recurrence *Recurrence, txn *ledger.Transaction, now time.Time) error {
+	status := StatusProcessing
+	if intent.DestinationType == "INTERNAL" {
+		status = StatusCompleted
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		UPDATE payments.payment_intents
+		SET status = $2, ledger_transaction_id = $3, processed_at = NOW(),
+		    completed_at = CASE WHEN $2 = 'COMPLETED' THEN NOW() END,
+		    metadata = COALESCE(metadata, '{}') - 'last_error',
+		    updated_at = NOW()
+		WHERE id = $1
+	`, intent.ID, string(status), txn.ID)
+	if err != nil {
+		return fmt.Errorf("update payment intent: %w", err)
+	}
+	err = recordStatus(ctx, tx, intent.ID, StatusApproved, status, "scheduled payment executed", map[string]interface{}{
+		"ledger_transaction_id": txn.ID,
+		"attempt":               intent.retryCount() + 1,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = outbox.Insert(ctx, tx, outbox.Event{
+		AggregateType: "PaymentIntent",
+		AggregateID:   intent.ID,
+		EventType:     "PaymentExecuted",
+		Topic:         outbox.TopicPaymentsProcessed,
+		PartitionKey:  intent.SourceAccountID,
+		Payload: PaymentExecuted{
+			PaymentIntentID:     intent.ID,
+			Status:              status,
+			LedgerTransactionID: txn.ID,
+			SourceAccountID:     intent.SourceAccountID,
+			DestinationType:     intent.DestinationType,
+			Amount:              intent.Amount,
+			FeeAmount:           intent.FeeAmount,
+			Currency:            intent.Currency,
+			PaymentMethod:       intent.PaymentMethod,
+			ScheduledFor:        intent.ScheduledFor,
+			ExecutedAt:          now,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return e.scheduleNext(ctx, tx, intent, recurrence)
+}
+
+// retry reagenda o intent após RetryDelay, respeitando o calendário
+func (e *Executor) retry(ctx context.Context, tx *sql.Tx, intent *Intent, recurrence *Recurrence, rule BusinessDayRule, now time.Time, cause error) error {
+	attempt := intent.retryCount() + 1
+	next := now.Add(e.config.RetryDelay)
+	if rule != BusinessDayNone {
+		next = e.calendar.Adjust(next, BusinessDayFollowing)
+	}
+
+	if err := reschedule(ctx, tx, intent, recurrence, next, map[string]interface{}{
+		"retry_count": attempt,
+		"last_error":  cause.Error(),
+	}); err != nil {
+		return err
+	}
+	reason := fmt.Sprintf("retry %d/%d: %s", attempt, e.config.MaxRetries, cause)
+	return recordStatus(ctx, tx, intent.ID, StatusApproved, StatusApproved, reason, map[string]interface{}{
+		"next_attempt_at": next,
+	})
+}
+
+// fail encerra a ocorrência; a série recorrente continua na próxima data
+func (e *Executor) fail(ctx context.Context, tx *sql.Tx, intent *Intent, recurrence *Recurrence, cause error) error {
+	lastError, _ := json.Marshal(map[string]interface{}{"last_error": cause.Error()})
+	_, err := tx.ExecContext(ctx, `
+		UPDATE payments.payment_intents
+		SET status = 'FAILED', failed_at = NOW(),
+		    metadata = COALESCE(metadata, '{}') || $2::jsonb,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, intent.ID, lastError)
+	if err != nil {
+		return fmt.Errorf("update payment intent: %w", err)
+	}
+	if err := recordStatus(ctx, tx, intent.ID, StatusApproved, StatusFailed, cause.Error(), map[string]interface{}{
+		"attempts": intent.retryCount() + 1,
+	}); err != nil {
+		return err
+	}
+	return e.scheduleNext(ctx, tx, intent, recurrence)
+}
+
+// scheduleNext cria o intent da próxima ocorrência, já APPROVED. A chave de
+// idempotência (série + número da ocorrência) impede ocorrências duplicadas.
+func (e *Executor) scheduleNext(ctx context.Context, tx *sql.Tx, intent *Intent, recurrence *Recurrence) error {
+	if recurrence == nil {
+		return nil
+	}
+	next, at, ok := recurrence.Next(intent.ScheduledFor, e.calendar)
+	if !ok {
+		return nil
+	}
+
+	config, err := json.Marshal(next)
+	if err != nil {
+		return fmt.Errorf("encode recurring config: %w", err)
+	}
+	metadata, err := json.Marshal(map[string]interface{}{
+		"recurrence_series_id": intent.seriesID(),
+		"previous_intent_id":   intent.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("encode metadata: %w", err)
+	}
+
+	var id string
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO payments.payment_intents (
+			idempotency_key, user_id, source_account_id, destination_type, destination_account_id,
+			destination_bank_details, destination_pix_key, amount, currency, fee_amount,
+			payment_method, direction, status, description, customer_reference,
+			scheduled_for, is_recurring, recurring_config, metadata
+		)
+		SELECT $2, user_id, source_account_id, destination_type, destination_account_id,
+		       destination_bank_details, destination_pix_key, amount, currency, fee_amount,
+		       payment_method, direction, 'APPROVED', description, customer_reference,
+		       $3, TRUE, $4, (COALESCE(metadata, '{}') - 'retry_count' - 'last_error') || $5::jsonb
+		FROM payments.payment_intents
+		WHERE id = $1
+		ON CONFLICT (idempotency_key) DO NOTHING
+		RETURNING id
+	`, intent.ID, fmt.Sprintf("recurring:%s:%d", intent.seriesID(), next.Occurrence), at, config, metadata).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("insert next occurrence: %w", err)
+	}
+
+	reason := fmt.Sprintf("recurring occurrence %d scheduled", next.Occurrence)
+	return recordStatus(ctx, tx, id, "", StatusApproved, reason, map[string]interface{}{
+		"previous_intent_id": intent.ID,
+		"scheduled_for":      at,
+	})
+}
+
+// reschedule move scheduled_for mantendo o intent APPROVED. Intents recorrentes
+// gravam a data nominal para a série não derivar.
+func reschedule(ctx context.Context, tx *sql.Tx, intent *Intent, recurrence *Recurrence, at time.Time, metadata map[string]interface{}) error {
+	patch, err := json.Marshal(nonNilMap(metadata))
+	if err != nil {
+		return fmt.Errorf("encode metadata: %w", err)
+	}
+	var config []byte
+	if recurrence != nil {
+		if config, err = json.Marshal(recurrence); err != nil {
+			return fmt.Errorf("encode recurring config: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE payments.payment_intents
+		SET scheduled_for = $2,
+		    recurring_config = COALESCE($3::jsonb, recurring_config),
+		    metadata = COALESCE(metadata, '{}') || $4::jsonb,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, intent.ID, at, nullJSON(config), patch)
+	if err != nil {
+		return fmt.Errorf("reschedule payment intent: %w", err)
+	}
+	return nil
+}
+
+// recordStatus grava uma linha em payments.payment_status_history (ator SYSTEM)
+func recordStatus(ctx context.Context, tx *sql.Tx, intentID string, from, to Status, reason string, metadata map[string]interface{}) error {
+	if len(reason) > 255 {
+		reason = reason[:255]
+	}
+	encoded, err := json.Marshal(nonNilMap(metadata))
+	if err != nil {
+		return fmt.Errorf("encode status metadata: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO payments.payment_status_history (
+			payment_intent_id, from_status, to_status, reason, actor_type, metadata
+		) VALUES ($1, NULLIF($2, '')::payments.payment_status, $3, $4, 'SYSTEM', $5)
+	`, intentID, string(from), string(to), reason, encoded)
+	if err != nil {
+		return fmt.Errorf("insert payment status history: %w", err)
+	}
+	return nil
+}
+
+// isRetryable separa falhas transitórias de saldo/limite das definitivas
+func isRetryable(err error) bool {
+	return errors.Is(err, ledger.ErrInsufficientFunds) || errors.Is(err, ledger.ErrLimitExceeded)
+}
+
+// isBusinessError identifica recusas do ledger que encerram ou adiam o intent;
+// o restante é falha de infraestrutura
+func isBusinessError(err error) bool {
+	for _, target := range []error{
+		ErrNotExecutable,
+		ledger.ErrInvalidPosting,
+		ledger.ErrUnbalanced,
+		ledger.ErrAccountNotFound,
+		ledger.ErrAccountNotActive,
+		ledger.ErrCurrencyMismatch,
+		ledger.ErrInsufficientFunds,
+		ledger.ErrLimitExceeded,
+		ledger.ErrDuplicateRef,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// atClock devolve o dia de day no horário de clock (fuso de Brasília)
+func atClock(day, clock time.Time) time.Time {
+	d, c := day.In(Location), clock.In(Location)
+	return time.Date(d.Year(), d.Month(), d.Day(), c.Hour(), c.Minute(), c.Second(), 0, Location)
+}
+
+func nonNilMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+func nullJSON(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,138 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 0, 0, 0, Location)
+}
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence([]byte(`{"frequency":"mensal","day_of_month":5}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Frequency != FrequencyMonthly || r.BusinessDay != BusinessDayFollowing || r.Occurrence != 1 {
+		t.Errorf("normalized = %+v", r)
+	}
+
+	for _, raw := range []string{
+		``,
+		`{"frequency":"DAILY"}`,
+		`{"frequency":"WEEKLY","business_day":"NEAREST"}`,
+		`{"frequency":"MONTHLY","day_of_month":32}`,
+		`{"frequency":"MONTHLY","end_date":"31/12/2025"}`,
+	} {
+		if _, err := ParseRecurrence([]byte(raw)); !errors.Is(err, ErrInvalidRecurrence) {
+			t.Errorf("%s: err = %v", raw, err)
+		}
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	cal, err := NewCalendar([]string{"2025-01-25", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	holidays := []time.Time{
+		date(2025, time.January, 1),
+		date(2025, time.March, 3),  // Carnaval
+		date(2025, time.March, 4),  // Carnaval
+		date(2025, time.April, 18), // Sexta-feira Santa
+		date(2025, time.June, 19),  // Corpus Christi
+		date(2025, time.November, 20),
+	}
+	for _, d := range holidays {
+		if cal.IsBusinessDay(d) {
+			t.Errorf("%s should not be a business day", d.Format("2006-01-02"))
+		}
+	}
+	if !cal.IsBusinessDay(date(2025, time.March, 5)) || !cal.IsBusinessDay(date(2023, time.November, 20)) {
+		t.Error("regular weekdays must be business days")
+	}
+
+	// Sábado 1/3/2025 -> segunda e terça de Carnaval -> quarta de Cinzas
+	if got := cal.Adjust(date(2025, time.March, 1), BusinessDayFollowing); !got.Equal(date(2025, time.March, 5)) {
+		t.Errorf("following = %s", got)
+	}
+	if got := cal.Adjust(date(2025, time.March, 1), BusinessDayPreceding); !got.Equal(date(2025, time.February, 28)) {
+		t.Errorf("preceding = %s", got)
+	}
+	if got := cal.Adjust(date(2025, time.March, 1), BusinessDayNone); !got.Equal(date(2025, time.March, 1)) {
+		t.Errorf("none = %s", got)
+	}
+
+	if _, err := NewCalendar([]string{"25/12"}); err == nil {
+		t.Error("invalid holiday accepted")
+	}
+}
+
+func TestRecurrenceNextKeepsNominalDate(t *testing.T) {
+	cal, _ := NewCalendar(nil)
+	r, err := ParseRecurrence([]byte(`{"frequency":"MONTHLY","day_of_month":31}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduled := date(2025, time.January, 31)
+	want := []time.Time{
+		date(2025, time.February, 28),
+		date(2025, time.March, 31),
+		date(2025, time.April, 30),
+		date(2025, time.June, 2), // 31/5 sábado
+	}
+	for i, w := range want {
+		var ok bool
+		r, scheduled, ok = r.Next(scheduled, cal)
+		if !ok {
+			t.Fatalf("occurrence %d: series ended", i+2)
+		}
+		if !scheduled.Equal(w) {
+			t.Errorf("occurrence %d = %s, want %s", r.Occurrence, scheduled.Format("2006-01-02"), w.Format("2006-01-02"))
+		}
+	}
+	if r.Occurrence != 5 || r.NominalAt.Day() != 31 {
+		t.Errorf("recurrence = occurrence %d, nominal %s", r.Occurrence, r.NominalAt)
+	}
+}
+
+func TestRecurrenceEnds(t *testing.T) {
+	cal, _ := NewCalendar(nil)
+
+	r, _ := ParseRecurrence([]byte(`{"frequency":"WEEKLY","max_occurrences":2}`))
+	next, _, ok := r.Next(date(2025, time.March, 10), cal)
+	if !ok {
+		t.Fatal("second occurrence expected")
+	}
+	if _, _, ok := next.Next(date(2025, time.March, 17), cal); ok {
+		t.Error("series must stop at max_occurrences")
+	}
+
+	r, _ = ParseRecurrence([]byte(`{"frequency":"QUINZENAL","end_date":"2025-03-24"}`))
+	if _, at, ok := r.Next(date(2025, time.March, 10), cal); !ok || !at.Equal(date(2025, time.March, 24)) {
+		t.Errorf("end_date is inclusive: ok=%v at=%s", ok, at)
+	}
+	if _, _, ok := r.Next(date(2025, time.March, 11), cal); ok {
+		t.Error("series must stop after end_date")
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	funds := fmt.Errorf("%w: account x", ledger.ErrInsufficientFunds)
+	if !isRetryable(funds) || !isBusinessError(funds) {
+		t.Error("insufficient funds must be a retryable business error")
+	}
+	closed := fmt.Errorf("%w: account x is CLOSED", ledger.ErrAccountNotActive)
+	if isRetryable(closed) || !isBusinessError(closed) {
+		t.Error("inactive account must fail the intent")
+	}
+	if isBusinessError(errors.New("connection reset")) {
+		t.Error("infrastructure errors must roll back")
+	}
+}
@@ -0,0 +1,256 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Regras de recorrência e calendário de dias úteis
+// ============================================================================
+
+package payments
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRecurrence indica um recurring_config inválido
+var ErrInvalidRecurrence = errors.New("invalid recurring config")
+
+// Location é o fuso dos vencimentos (Brasília, sem horário de verão desde 2019)
+var Location = time.FixedZone("BRT", -3*60*60)
+
+// Frequency é a periodicidade da recorrência
+type Frequency string
+
+const (
+	FrequencyWeekly    Frequency = "WEEKLY"
+	FrequencyBiweekly  Frequency = "BIWEEKLY"
+	FrequencyMonthly   Frequency = "MONTHLY"
+	FrequencyQuarterly Frequency = "QUARTERLY"
+	FrequencyYearly    Frequency = "YEARLY"
+)
+
+// frequencyAliases aceita os valores da tela de recorrentes do frontend
+var frequencyAliases = map[string]Frequency{
+	"SEMANAL":    FrequencyWeekly,
+	"QUINZENAL":  FrequencyBiweekly,
+	"MENSAL":     FrequencyMonthly,
+	"TRIMESTRAL": FrequencyQuarterly,
+	"ANUAL":      FrequencyYearly,
+}
+
+// BusinessDayRule define o que fazer quando o vencimento cai em dia não útil
+type BusinessDayRule string
+
+const (
+	BusinessDayFollowing BusinessDayRule = "FOLLOWING" // próximo dia útil (padrão)
+	BusinessDayPreceding BusinessDayRule = "PRECEDING" // dia útil anterior
+	BusinessDayNone      BusinessDayRule = "NONE"      // executa na data nominal
+)
+
+// Recurrence é o conteúdo de payment_intents.recurring_config
+type Recurrence struct {
+	Frequency      Frequency       `json:"frequency"`
+	DayOfMonth     int             `json:"day_of_month,omitempty"`
+	BusinessDay    BusinessDayRule `json:"business_day,omitempty"`
+	EndDate        string          `json:"end_date,omitempty"` // YYYY-MM-DD, inclusive
+	MaxOccurrences int             `json:"max_occurrences,omitempty"`
+
+	// Mantidos pelo executor: número desta ocorrência na série e sua data
+	// nominal (antes de ajustes de dia útil e retentativas), base da próxima
+	Occurrence int        `json:"occurrence,omitempty"`
+	NominalAt  *time.Time `json:"nominal_at,omitempty"`
+}
+
+// ParseRecurrence lê e normaliza o recurring_config
+func ParseRecurrence(raw []byte) (Recurrence, error) {
+	var r Recurrence
+	if len(raw) == 0 {
+		return r, fmt.Errorf("%w: empty", ErrInvalidRecurrence)
+	}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return r, fmt.Errorf("%w: %v", ErrInvalidRecurrence, err)
+	}
+
+	f := strings.ToUpper(strings.TrimSpace(string(r.Frequency)))
+	if alias, ok := frequencyAliases[f]; ok {
+		f = string(alias)
+	}
+	r.Frequency = Frequency(f)
+	switch r.Frequency {
+	case FrequencyWeekly, FrequencyBiweekly, FrequencyMonthly, FrequencyQuarterly, FrequencyYearly:
+	default:
+		return r, fmt.Errorf("%w: unknown frequency %q", ErrInvalidRecurrence, f)
+	}
+
+	r.BusinessDay = BusinessDayRule(strings.ToUpper(strings.TrimSpace(string(r.BusinessDay))))
+	switch r.BusinessDay {
+	case "":
+		r.BusinessDay = BusinessDayFollowing
+	case BusinessDayFollowing, BusinessDayPreceding, BusinessDayNone:
+	default:
+		return r, fmt.Errorf("%w: unknown business_day %q", ErrInvalidRecurrence, r.BusinessDay)
+	}
+
+	if r.DayOfMonth < 0 || r.DayOfMonth > 31 {
+		return r, fmt.Errorf("%w: day_of_month must be between 1 and 31", ErrInvalidRecurrence)
+	}
+	if r.EndDate != "" {
+		if _, err := time.ParseInLocation("2006-01-02", r.EndDate, Location); err != nil {
+			return r, fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidRecurrence)
+		}
+	}
+	if r.Occurrence == 0 {
+		r.Occurrence = 1
+	}
+	return r, nil
+}
+
+// nominal devolve a data nominal desta ocorrência; sem nominal_at, a data agendada
+func (r Recurrence) nominal(scheduled time.Time) time.Time {
+	if r.NominalAt != nil {
+		return r.NominalAt.In(Location)
+	}
+	return scheduled.In(Location)
+}
+
+// Next calcula a próxima ocorrência a partir da atual (agendada para scheduled).
+// Devolve a configuração da próxima, sua data já ajustada ao calendário e false
+// quando a série terminou.
+func (r Recurrence) Next(scheduled time.Time, cal *Calendar) (Recurrence, time.Time, bool) {
+	if r.MaxOccurrences > 0 && r.Occurrence >= r.MaxOccurrences {
+		return r, time.Time{}, false
+	}
+
+	current := r.nominal(scheduled)
+	var next time.Time
+	switch r.Frequency {
+	case FrequencyWeekly:
+		next = current.AddDate(0, 0, 7)
+	case FrequencyBiweekly:
+		next = current.AddDate(0, 0, 14)
+	case FrequencyMonthly:
+		next = addMonths(current, 1, r.DayOfMonth)
+	case FrequencyQuarterly:
+		next = addMonths(current, 3, r.DayOfMonth)
+	case FrequencyYearly:
+		next = addMonths(current, 12, r.DayOfMonth)
+	}
+
+	if r.EndDate != "" {
+		end, _ := time.ParseInLocation("2006-01-02", r.EndDate, Location)
+		if next.After(end.AddDate(0, 0, 1).Add(-time.Nanosecond)) {
+			return r, time.Time{}, false
+		}
+	}
+
+	following := r
+	following.Occurrence = r.Occurrence + 1
+	following.NominalAt = &next
+	return following, cal.Adjust(next, r.BusinessDay), true
+}
+
+// addMonths avança n meses mantendo o dia de vencimento (limitado ao fim do mês)
+func addMonths(t time.Time, n, dayOfMonth int) time.Time {
+	if dayOfMonth == 0 {
+		dayOfMonth = t.Day()
+	}
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	if dayOfMonth > last {
+		dayOfMonth = last
+	}
+	return first.AddDate(0, 0, dayOfMonth-1)
+}
+
+// Calendar conhece fins de semana, feriados nacionais e feriados extras
+type Calendar struct {
+	extra map[string]bool
+}
+
+// NewCalendar cria um calendário com feriados extras (YYYY-MM-DD), ex.: municipais
+func NewCalendar(extra []string) (*Calendar, error) {
+	c := &Calendar{extra: make(map[string]bool)}
+	for _, d := range extra {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: must be YYYY-MM-DD", d)
+		}
+		c.extra[d] = true
+	}
+	return c, nil
+}
+
+// IsBusinessDay informa se a data (no fuso de Brasília) é dia útil bancário
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	t = t.In(Location)
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	key := t.Format("2006-01-02")
+	return !c.extra[key] && !nationalHoliday(t)
+}
+
+// Adjust move a data para um dia útil conforme a regra, mantendo o horário
+func (c *Calendar) Adjust(t time.Time, rule BusinessDayRule) time.Time {
+	step := 0
+	switch rule {
+	case BusinessDayFollowing, "":
+		step = 1
+	case BusinessDayPreceding:
+		step = -1
+	default:
+		return t
+	}
+	for !c.IsBusinessDay(t) {
+		t = t.AddDate(0, 0, step)
+	}
+	return t
+}
+
+// nationalHoliday cobre os feriados nacionais fixos e os móveis (Páscoa)
+// que fecham o sistema bancário
+func nationalHoliday(t time.Time) bool {
+	switch t.Format("01-02") {
+	case "01-01", "04-21", "05-01", "09-07", "10-12", "11-02", "11-15", "12-25":
+		return true
+	case "11-20": // Consciência Negra, nacional desde 2024
+		if t.Year() >= 2024 {
+			return true
+		}
+	}
+
+	easter := easterSunday(t.Year())
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch int(day.Sub(easter).Hours() / 24) {
+	case -48, -47: // Carnaval
+		return true
+	case -2: // Sexta-feira Santa
+		return true
+	case 60: // Corpus Christi
+		return true
+	}
+	return false
+}
+
+// easterSunday usa o algoritmo anônimo gregoriano (Meeus/Jones/Butcher)
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,296 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Avaliação de políticas de autorização (OPA/Rego) carregadas do disco
+// ============================================================================
+
+package policy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDenied       = errors.New("denied by authorization policy")
+	ErrInvalidQuery = errors.New("policy query returned an unexpected document")
+)
+
+// DefaultQuery é o pacote avaliado; deve definir allow e, opcionalmente, reasons
+const DefaultQuery = "data.kamino.ledger.authz"
+
+var decisionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ledger_policy_decisions_total",
+	Help: "Authorization policy decisions by result",
+}, []string{"result"})
+
+// Input é o documento input visto pelas políticas
+type Input struct {
+	Route    Route    `json:"route"`
+	User     User     `json:"user"`
+	Resource Resource `json:"resource"`
+	Request  Request  `json:"request"`
+}
+
+// Route identifica a operação; Path é o template da rota (/v1/accounts/:id)
+type Route struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Action string `json:"action"` // read | write
+}
+
+// User é o principal autenticado
+type User struct {
+	ID        string `json:"id"`
+	Subject   string `json:"subject"`
+	CompanyID string `json:"company_id,omitempty"`
+	Role      string `json:"role"`
+}
+
+// Resource descreve o alvo: contas envolvidas, seus donos e o valor em centavos
+type Resource struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id,omitempty"`
+	AccountIDs []string `json:"account_ids"`
+	OwnerIDs   []string `json:"owner_ids"`
+	Amount     int64    `json:"amount"`
+	Currency   string   `json:"currency,omitempty"`
+}
+
+// Request traz dados da requisição HTTP
+type Request struct {
+	ID        string `json:"id"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+// Decision é o resultado de uma avaliação
+type Decision struct {
+	ID       string   `json:"decision_id"`
+	Allow    bool     `json:"allow"`
+	Reasons  []string `json:"reasons,omitempty"`
+	Revision string   `json:"revision"`
+}
+
+// Engine mantém a política compilada e a recarrega quando os arquivos mudam
+type Engine struct {
+	path     string
+	query    string
+	interval time.Duration
+	logger   *zap.SugaredLogger
+
+	mu       sync.RWMutex
+	prepared rego.PreparedEvalQuery
+	revision string
+}
+
+// NewEngine compila o bundle (diretório ou .tar.gz) em path.
+// Uma política inválida na inicialização impede o serviço de subir.
+func NewEngine(ctx context.Context, path, query string, interval time.Duration, logger *zap.SugaredLogger) (*Engine, error) {
+	if query == "" {
+		query = DefaultQuery
+	}
+	e := &Engine{path: path, query: query, interval: interval, logger: logger}
+	if _, err := e.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Revision identifica a versão carregada (hash dos arquivos do bundle)
+func (e *Engine) Revision() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.revision
+}
+
+// Reload recompila se o conteúdo mudou. Com erro de compilação, a versão
+// anterior continua em uso.
+func (e *Engine) Reload(ctx context.Context) (bool, error) {
+	revision, err := bundleRevision(e.path)
+	if err != nil {
+		return false, err
+	}
+	if revision == e.Revision() {
+		return false, nil
+	}
+
+	prepared, err := rego.New(
+		rego.Query(e.query),
+		rego.LoadBundle(e.path),
+	).PrepareForEval(ctx)
+	if err != nil {
+		return false, fmt.Errorf("compile policy bundle %s: %w", e.path, err)
+	}
+
+	e.mu.Lock()
+	e.prepared = prepared
+	e.revision = revision
+	e.mu.Unlock()
+	return true, nil
+}
+
+// Run verifica mudanças no bundle a cada interval até o contexto ser cancelado
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := e.Reload(ctx)
+			switch {
+			case err != nil && ctx.Err() == nil:
+				e.logger.Errorw("Policy reload failed, keeping previous revision",
+					"path", e.path, "revision", e.Revision(), "error", err)
+			case changed:
+				e.logger.Infow("Policy bundle reloaded", "path", e.path, "revision", e.Revision())
+			}
+		}
+	}
+}
+
+// Evaluate avalia a política e registra a decisão no log de auditoria.
+// Erros de avaliação negam o acesso (fail closed).
+func (e *Engine) Evaluate(ctx context.Context, input Input) (Decision, error) {
+	e.mu.RLock()
+	prepared, revision := e.prepared, e.revision
+	e.mu.RUnlock()
+
+	start := time.Now()
+	decision := Decision{ID: newDecisionID(), Revision: revision}
+	rs, err := prepared.Eval(ctx, rego.EvalInput(input))
+	if err == nil {
+		decision.Allow, decision.Reasons, err = parseResult(rs)
+	}
+
+	result := "deny"
+	switch {
+	case err != nil:
+		result = "error"
+		decision.Allow = false
+	case decision.Allow:
+		result = "allow"
+	case len(decision.Reasons) == 0:
+		decision.Reasons = []string{"not permitted by policy"}
+	}
+	decisionsTotal.WithLabelValues(result).Inc()
+
+	e.logger.Infow("Authorization decision",
+		"audit", true,
+		"decision_id", decision.ID,
+		"result", result,
+		"reasons", decision.Reasons,
+		"revision", revision,
+		"request_id", input.Request.ID,
+		"user_id", input.User.ID,
+		"company_id", input.User.CompanyID,
+		"role", input.User.Role,
+		"method", input.Route.Method,
+		"path", input.Route.Path,
+		"resource_type", input.Resource.Type,
+		"resource_id", input.Resource.ID,
+		"account_ids", input.Resource.AccountIDs,
+		"amount", input.Resource.Amount,
+		"ip", input.Request.IP,
+		"latency_ms", time.Since(start).Milliseconds(),
+		"error", err,
+	)
+	if err != nil {
+		return decision, fmt.Errorf("evaluate policy: %w", err)
+	}
+	return decision, nil
+}
+
+// parseResult lê allow (bool) e reasons (conjunto de strings) do pacote
+func parseResult(rs rego.ResultSet) (bool, []string, error) {
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		// Pacote sem regras definidas para este input
+		return false, nil, nil
+	}
+	doc, ok := rs[0].Expressions[0].Value.(map[string]interface{})
+	if !ok {
+		return false, nil, ErrInvalidQuery
+	}
+
+	allow, _ := doc["allow"].(bool)
+	var reasons []string
+	if list, ok := doc["reasons"].([]interface{}); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				reasons = append(reasons, s)
+			}
+		}
+	}
+	sort.Strings(reasons)
+	// Qualquer motivo de negação prevalece sobre allow
+	return allow && len(reasons) == 0, reasons, nil
+}
+
+// bundleExtensions são os arquivos que compõem um bundle
+var bundleExtensions = map[string]bool{
+	".rego": true, ".json": true, ".yaml": true, ".yml": true, ".manifest": true, ".gz": true,
+}
+
+// bundleRevision devolve o hash dos arquivos do bundle em ordem de caminho
+func bundleRevision(path string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && bundleExtensions[filepath.Ext(p)] {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("read policy bundle: %w", err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("read policy bundle: no policy files in %s", path)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return "", fmt.Errorf("read policy bundle: %w", err)
+		}
+		rel, _ := filepath.Rel(path, name)
+		io.WriteString(h, strings.ReplaceAll(rel, `\`, "/")+"\x00")
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", fmt.Errorf("read policy bundle: %w", err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12], nil
+}
+
+func newDecisionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,109 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ownerID = "7c0b1f4e-2a64-4d1a-9a39-0d2f6f3b8e11"
+	otherID = "0e3c1a55-5d0c-4bb6-8a57-6b9d3b0c2f40"
+)
+
+func input(role, action string, owners []string, amount int64) Input {
+	return Input{
+		Route:    Route{Method: "GET", Path: "/v1/accounts/:id", Action: action},
+		User:     User{ID: ownerID, Role: role},
+		Resource: Resource{Type: "account", OwnerIDs: owners, Amount: amount},
+	}
+}
+
+// TestDefaultBundle avalia a política distribuída em policies/
+func TestDefaultBundle(t *testing.T) {
+	ctx := context.Background()
+	engine, err := NewEngine(ctx, filepath.Join("..", "..", "policies"), "", time.Minute, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ledgerRoute := input("USER", "read", nil, 0)
+	ledgerRoute.Resource.Type = "ledger"
+
+	cases := []struct {
+		name  string
+		input Input
+		allow bool
+	}{
+		{"admin reads any account", input("ADMIN", "read", []string{otherID}, 0), true},
+		{"user reads own account", input("USER", "read", []string{ownerID}, 0), true},
+		{"user reads other account", input("USER", "read", []string{otherID}, 0), false},
+		{"viewer reads own account", input("VIEWER", "read", []string{ownerID}, 0), true},
+		{"viewer writes own account", input("VIEWER", "write", []string{ownerID}, 0), false},
+		{"user within limit", input("USER", "write", []string{ownerID}, 1000000), true},
+		{"user above limit", input("USER", "write", []string{ownerID}, 1000001), false},
+		{"admin above limit", input("ADMIN", "write", []string{otherID}, 50000000), true},
+		{"user on ledger routes", ledgerRoute, false},
+	}
+	for _, tc := range cases {
+		d, err := engine.Evaluate(ctx, tc.input)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if d.Allow != tc.allow {
+			t.Errorf("%s: allow = %v, want %v (reasons %v)", tc.name, d.Allow, tc.allow, d.Reasons)
+		}
+		if !d.Allow && len(d.Reasons) == 0 {
+			t.Errorf("%s: denied without reasons", tc.name)
+		}
+	}
+}
+
+func TestReloadKeepsPreviousRevisionOnError(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "authz.rego")
+	write := func(src string) {
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("package kamino.ledger.authz\n\nallow := true\n")
+	engine, err := NewEngine(ctx, dir, "", time.Minute, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := engine.Revision()
+
+	if changed, err := engine.Reload(ctx); err != nil || changed {
+		t.Fatalf("unchanged bundle: changed=%v err=%v", changed, err)
+	}
+
+	write("package kamino.ledger.authz\n\nallow := \n")
+	if _, err := engine.Reload(ctx); err == nil {
+		t.Fatal("invalid policy compiled")
+	}
+	if engine.Revision() != first {
+		t.Error("revision changed after failed reload")
+	}
+	if d, _ := engine.Evaluate(ctx, Input{}); !d.Allow {
+		t.Error("previous policy no longer in effect")
+	}
+
+	write("package kamino.ledger.authz\n\nimport rego.v1\n\nallow := false\n\nreasons contains \"maintenance window\"\n")
+	if changed, err := engine.Reload(ctx); err != nil || !changed {
+		t.Fatalf("reload: changed=%v err=%v", changed, err)
+	}
+	d, err := engine.Evaluate(ctx, Input{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Allow || len(d.Reasons) != 1 || d.Reasons[0] != "maintenance window" {
+		t.Errorf("decision = %+v", d)
+	}
+}
@@ -0,0 +1,414 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Reconstrução de projeções (core.accounts e cache de saldos) a partir
+// de core.ledger_entries, core.balance_holds e event_sourcing.event_store
+// ============================================================================
+
+package projections
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+
+	"github.com/kaminoclone/ledger-service/internal/accounts"
+	"github.com/kaminoclone/ledger-service/internal/balances"
+	"github.com/kaminoclone/ledger-service/internal/eventstore"
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+// ProjectionAccounts é o nome do checkpoint da reconstrução de contas
+const ProjectionAccounts = "accounts"
+
+// DefaultBatchSize é quantas contas são reconstruídas por transação
+const DefaultBatchSize = 500
+
+// BalanceCache recebe o saldo reconstruído de cada conta. Set só grava sobre
+// uma versão menor: um refresh concorrente mais novo não é sobrescrito.
+type BalanceCache interface {
+	Set(ctx context.Context, b balances.Balance) (bool, error)
+}
+
+// Options controla uma execução
+type Options struct {
+	DryRun    bool // apenas reporta diferenças; não grava nada
+	Resume    bool // continua após o último checkpoint em vez de recomeçar
+	BatchSize int
+}
+
+// Diff é um campo da projeção que difere do valor reconstruído
+type Diff struct {
+	AccountID string `json:"account_id"`
+	Field     string `json:"field"`
+	Current   string `json:"current"`
+	Expected  string `json:"expected"`
+}
+
+// Result resume uma execução
+type Result struct {
+	Accounts        int       `json:"accounts"`
+	AccountsChanged int       `json:"accounts_changed"`
+	Diffs           int       `json:"diffs"`
+	CacheUpdated    int       `json:"cache_updated"`
+	ResumedFrom     string    `json:"resumed_from,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	FinishedAt      time.Time `json:"finished_at"`
+}
+
+// Rebuilder reconstrói as projeções de contas
+type Rebuilder struct {
+	db     *sql.DB
+	events *eventstore.Store
+	cache  BalanceCache // opcional
+	logger *zap.SugaredLogger
+}
+
+// NewRebuilder cria um Rebuilder; cache nil pula a reconstrução do cache
+func NewRebuilder(db *sql.DB, events *eventstore.Store, cache BalanceCache, logger *zap.SugaredLogger) *Rebuilder {
+	return &Rebuilder{db: db, events: events, cache: cache, logger: logger}
+}
+
+// state é a projeção de uma conta (atual ou reconstruída)
+type state struct {
+	ID           string
+	Currency     string
+	Balance      ledger.Money
+	Available    ledger.Money
+	Blocked      ledger.Money
+	Status       accounts.Status
+	ClosedAt     *time.Time
+	LastSequence int64
+	Version      int64 // balance_version lido ou, após a gravação, o resultante
+}
+
+// diff compara a projeção atual com a reconstruída
+func diff(current, expected state) []Diff {
+	var diffs []Diff
+	add := func(field, cur, exp string) {
+		if cur != exp {
+			diffs = append(diffs, Diff{AccountID: current.ID, Field: field, Current: cur, Expected: exp})
+		}
+	}
+	add("balance", current.Balance.String(), expected.Balance.String())
+	add("available_balance", current.Available.String(), expected.Available.String())
+	add("blocked_balance", current.Blocked.String(), expected.Blocked.String())
+	add("status", string(current.Status), string(expected.Status))
+	add("closed_at", formatTime(current.ClosedAt), formatTime(expected.ClosedAt))
+	return diffs
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// Rebuild percorre as contas em ordem de id, em lotes. Cada lote roda em uma
+// transação com as contas bloqueadas (mesma ordem do ledger), de modo que
+// lançamentos concorrentes esperam e a projeção gravada é consistente.
+// Após cada lote o checkpoint é gravado; onDiff recebe cada diferença.
+func (r *Rebuilder) Rebuild(ctx context.Context, opts Options, onDiff func(Diff)) (*Result, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	result := &Result{StartedAt: time.Now().UTC()}
+
+	after := ""
+	if opts.Resume {
+		var err error
+		if after, err = r.loadCheckpoint(ctx); err != nil {
+			return nil, err
+		}
+		result.ResumedFrom = after
+	}
+
+	for {
+		last, err := r.rebuildBatch(ctx, opts, after, result, onDiff)
+		if err != nil {
+			return result, err
+		}
+		if last == "" {
+			break
+		}
+		after = last
+		r.logger.Infow("Projection batch rebuilt",
+			"projection", ProjectionAccounts,
+			"last_account_id", last,
+			"accounts", result.Accounts,
+			"changed", result.AccountsChanged,
+			"dry_run", opts.DryRun,
+		)
+	}
+
+	if !opts.DryRun {
+		if err := r.completeCheckpoint(ctx); err != nil {
+			return result, err
+		}
+	}
+	result.FinishedAt = time.Now().UTC()
+	return result, nil
+}
+
+// rebuildBatch processa as contas seguintes a after e devolve o último id ("" = fim)
+func (r *Rebuilder) rebuildBatch(ctx context.Context, opts Options, after string, result *Result, onDiff func(Diff)) (string, error) {
+	txOpts := &sql.TxOptions{}
+	lock := "FOR UPDATE"
+	if opts.DryRun {
+		txOpts = &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+		lock = ""
+	}
+
+	tx, err := r.db.BeginTx(ctx, txOpts)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := loadCurrent(ctx, tx, after, opts.BatchSize, lock)
+	if err != nil || len(current) == 0 {
+		return "", err
+	}
+
+	ids := make([]string, len(current))
+	for i, c := range current {
+		ids[i] = c.ID
+	}
+	expected, err := r.replay(ctx, tx, current, ids)
+	if err != nil {
+		return "", err
+	}
+
+	for i := range current {
+		diffs := diff(current[i], expected[i])
+		result.Accounts++
+		if len(diffs) == 0 {
+			continue
+		}
+		result.AccountsChanged++
+		result.Diffs += len(diffs)
+		if onDiff != nil {
+			for _, d := range diffs {
+				onDiff(d)
+			}
+		}
+		if !opts.DryRun {
+			if err := writeProjection(ctx, tx, &expected[i]); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	last := ids[len(ids)-1]
+	if opts.DryRun {
+		return last, nil
+	}
+
+	if err := saveCheckpoint(ctx, tx, last); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+
+	// O cache é atualizado só depois do commit, com os valores confirmados
+	if r.cache != nil {
+		for _, e := range expected {
+			stored, err := r.cache.Set(ctx, balances.Balance{
+				AccountID:          e.ID,
+				Currency:           e.Currency,
+				AsOf:               time.Now().UTC(),
+				Balance:            e.Balance,
+				HeldAmount:         e.Blocked,
+				AvailableBalance:   e.Available,
+				LastSequenceNumber: e.LastSequence,
+				Version:            e.Version,
+				Source:             balances.SourceCurrent,
+			})
+			if err != nil {
+				return "", fmt.Errorf("update balance cache for %s: %w", e.ID, err)
+			}
+			if stored {
+				result.CacheUpdated++
+			}
+		}
+	}
+	return last, nil
+}
+
+func loadCurrent(ctx context.Context, tx *sql.Tx, after string, limit int, lock string) ([]state, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, currency, balance, available_balance, blocked_balance, status, closed_at, balance_version
+		FROM core.accounts
+		WHERE ($1 = '' OR id > NULLIF($1, '')::uuid)
+		ORDER BY id
+		LIMIT $2
+	`+lock, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("load accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []state
+	for rows.Next() {
+		var s state
+		var closedAt sql.NullTime
+		if err := rows.Scan(&s.ID, &s.Currency, &s.Balance, &s.Available, &s.Blocked, &s.Status, &closedAt, &s.Version); err != nil {
+			return nil, fmt.Errorf("scan account: %w", err)
+		}
+		if closedAt.Valid {
+			s.ClosedAt = &closedAt.Time
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// replay recalcula cada conta: saldo pela soma dos lançamentos (CREDIT soma,
+// DEBIT subtrai), bloqueado pelos holds ACTIVE e status pelo agregado Account.
+// Contas sem eventos mantêm status e closed_at da projeção.
+func (r *Rebuilder) replay(ctx context.Context, tx *sql.Tx, current []state, ids []string) ([]state, error) {
+	expected := make([]state, len(current))
+	index := make(map[string]int, len(current))
+	for i, c := range current {
+		expected[i] = state{ID: c.ID, Currency: c.Currency, Status: c.Status, ClosedAt: c.ClosedAt, Version: c.Version}
+		index[c.ID] = i
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT account_id,
+		       SUM(CASE WHEN entry_type = 'CREDIT' THEN amount ELSE -amount END),
+		       MAX(sequence_number)
+		FROM core.ledger_entries
+		WHERE account_id = ANY($1::uuid[])
+		GROUP BY account_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("replay ledger entries: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var balance ledger.Money
+		var seq int64
+		if err := rows.Scan(&id, &balance, &seq); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan ledger totals: %w", err)
+		}
+		expected[index[id]].Balance = balance
+		expected[index[id]].LastSequence = seq
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("replay ledger entries: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT account_id, SUM(amount)
+		FROM core.balance_holds
+		WHERE account_id = ANY($1::uuid[]) AND status = 'ACTIVE'
+		GROUP BY account_id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("sum active holds: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var blocked ledger.Money
+		if err := rows.Scan(&id, &blocked); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan active holds: %w", err)
+		}
+		expected[index[id]].Blocked = blocked
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sum active holds: %w", err)
+	}
+
+	for i := range expected {
+		expected[i].Available = expected[i].Balance - expected[i].Blocked
+
+		agg := &accounts.Aggregate{}
+		version, err := r.events.LoadAggregate(ctx, tx, eventstore.Stream{Type: accounts.AggregateType, ID: expected[i].ID}, agg)
+		if err != nil {
+			return nil, err
+		}
+		if version > 0 {
+			expected[i].Status = agg.Status
+			expected[i].ClosedAt = agg.ClosedAt
+		}
+	}
+	return expected, nil
+}
+
+func writeProjection(ctx context.Context, tx *sql.Tx, s *state) error {
+	var closedAt interface{}
+	if s.ClosedAt != nil {
+		closedAt = *s.ClosedAt
+	}
+	err := tx.QueryRowContext(ctx, `
+		UPDATE core.accounts
+		SET balance = $2, available_balance = $3, blocked_balance = $4,
+		    status = $5, closed_at = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING balance_version
+	`, s.ID, s.Balance, s.Available, s.Blocked, string(s.Status), closedAt).Scan(&s.Version)
+	if err != nil {
+		return fmt.Errorf("update account %s: %w", s.ID, err)
+	}
+	return nil
+}
+
+// loadCheckpoint devolve a última conta confirmada de uma execução interrompida
+func (r *Rebuilder) loadCheckpoint(ctx context.Context) (string, error) {
+	var last sql.NullString
+	var completed sql.NullTime
+	err := r.db.QueryRowContext(ctx, `
+		SELECT last_account_id::text, completed_at
+		FROM event_sourcing.projection_checkpoints
+		WHERE projection_name = $1
+	`, ProjectionAccounts).Scan(&last, &completed)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("load checkpoint: %w", err)
+	}
+	if completed.Valid {
+		// A última execução terminou; recomeça do início
+		return "", nil
+	}
+	return last.String, nil
+}
+
+func saveCheckpoint(ctx context.Context, tx *sql.Tx, last string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO event_sourcing.projection_checkpoints (projection_name, last_account_id, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (projection_name) DO UPDATE
+		SET last_account_id = EXCLUDED.last_account_id,
+		    updated_at = NOW(),
+		    completed_at = NULL
+	`, ProjectionAccounts, last)
+	if err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (r *Rebuilder) completeCheckpoint(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE event_sourcing.projection_checkpoints
+		SET completed_at = NOW(), updated_at = NOW()
+		WHERE projection_name = $1
+	`, ProjectionAccounts)
+	if err != nil {
+		return fmt.Errorf("complete checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package projections
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/accounts"
+)
+
+func TestDiff(t *testing.T) {
+	closed := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	current := state{ID: "a", Balance: 10000, Available: 10000, Status: accounts.StatusActive}
+	expected := state{ID: "a", Balance: 12000, Available: 7000, Blocked: 5000, Status: accounts.StatusActive}
+
+	diffs := diff(current, expected)
+	if len(diffs) != 3 {
+		t.Fatalf("expected 3 diffs, got %+v", diffs)
+	}
+	if diffs[0].Field != "balance" || diffs[0].Current != "100.00" || diffs[0].Expected != "120.00" {
+		t.Errorf("unexpected balance diff: %+v", diffs[0])
+	}
+
+	expected = current
+	expected.Status, expected.ClosedAt = accounts.StatusClosed, &closed
+	diffs = diff(current, expected)
+	if len(diffs) != 2 || diffs[1].Field != "closed_at" || diffs[1].Expected != "2026-03-02T15:00:00Z" {
+		t.Errorf("unexpected status diffs: %+v", diffs)
+	}
+
+	if diffs := diff(current, current); len(diffs) != 0 {
+		t.Errorf("expected no diffs, got %+v", diffs)
+	}
+}
@@ -0,0 +1,186 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Exportação de extratos (CSV e OFX)
+// ============================================================================
+
+package statements
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Format é um formato de exportação suportado
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatOFX  Format = "ofx"
+	FormatPDF  Format = "pdf"
+)
+
+// ParseFormat valida o parâmetro format (vazio = JSON)
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCSV, FormatOFX, FormatPDF:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedFormat, s)
+}
+
+// ContentType devolve o MIME type do formato
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatOFX:
+		return "application/x-ofx"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Filename sugere o nome do arquivo exportado
+func (st *Statement) Filename(f Format) string {
+	return fmt.Sprintf("extrato_%s_%s_%s.%s",
+		strings.ReplaceAll(st.AccountNumber, "-", ""),
+		st.From.Format("20060102"), st.To.Format("20060102"), f)
+}
+
+// counterpartNames junta as contrapartidas em um único campo
+func (l Line) counterpartNames() string {
+	names := make([]string, 0, len(l.Counterparts))
+	for _, cp := range l.Counterparts {
+		names = append(names, cp.AccountNumber+" "+cp.Name)
+	}
+	return strings.Join(names, "; ")
+}
+
+// csvText neutraliza fórmulas em campos de texto livre: planilhas executam
+// células iniciadas por =, +, -, @, tab ou CR, então elas ganham um apóstrofo
+// na frente. Valores numéricos gerados pelo serviço não passam por aqui.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WriteCSV escreve o extrato com linhas de saldo inicial e final
+func WriteCSV(w io.Writer, st *Statement) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{
+		{"date", "entry_id", "transaction_id", "reference_id", "transaction_type",
+			"description", "counterpart", "entry_type", "amount", "running_balance"},
+		{st.Period.From, "", "", "", "", "SALDO INICIAL", "", "", "", st.OpeningBalance.String()},
+	}
+	for _, l := range st.Lines {
+		records = append(records, []string{
+			l.PostedAt.UTC().Format(time.RFC3339),
+			l.EntryID, l.TransactionID, csvText(l.ReferenceID), csvText(l.TransactionType),
+			csvText(l.Description), csvText(l.counterpartNames()), string(l.EntryType),
+			l.SignedAmount().String(), l.RunningBalance.String(),
+		})
+	}
+	records = append(records,
+		[]string{st.Period.To, "", "", "", "", "SALDO FINAL", "", "", "", st.ClosingBalance.String()})
+
+	if err := cw.WriteAll(records); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
+
+// ofxBankID identifica a instituição no bloco BANKACCTFROM
+const ofxBankID = "0000"
+
+// WriteOFX escreve o extrato em OFX 1.02 (SGML), formato aceito pelos ERPs nacionais
+func WriteOFX(w io.Writer, st *Statement) error {
+	var b strings.Builder
+	b.WriteString("OFXHEADER:100\r\nDATA:OFXSGML\r\nVERSION:102\r\nSECURITY:NONE\r\n" +
+		"ENCODING:UTF-8\r\nCHARSET:NONE\r\nCOMPRESSION:NONE\r\nOLDFILEUID:NONE\r\nNEWFILEUID:NONE\r\n\r\n")
+
+	tag := func(name, value string) {
+		fmt.Fprintf(&b, "<%s>%s\r\n", name, ofxEscape(value))
+	}
+	open := func(name string) { fmt.Fprintf(&b, "<%s>\r\n", name) }
+	closeTag := func(name string) { fmt.Fprintf(&b, "</%s>\r\n", name) }
+
+	open("OFX")
+	open("SIGNONMSGSRSV1")
+	open("SONRS")
+	open("STATUS")
+	tag("CODE", "0")
+	tag("SEVERITY", "INFO")
+	closeTag("STATUS")
+	tag("DTSERVER", ofxTime(st.GeneratedAt))
+	tag("LANGUAGE", "POR")
+	closeTag("SONRS")
+	closeTag("SIGNONMSGSRSV1")
+
+	open("BANKMSGSRSV1")
+	open("STMTTRNRS")
+	tag("TRNUID", "1")
+	open("STATUS")
+	tag("CODE", "0")
+	tag("SEVERITY", "INFO")
+	closeTag("STATUS")
+	open("STMTRS")
+	tag("CURDEF", st.Currency)
+	open("BANKACCTFROM")
+	tag("BANKID", ofxBankID)
+	tag("ACCTID", st.AccountNumber)
+	tag("ACCTTYPE", "CHECKING")
+	closeTag("BANKACCTFROM")
+
+	open("BANKTRANLIST")
+	tag("DTSTART", ofxTime(st.From))
+	tag("DTEND", ofxTime(st.To.Add(24*time.Hour-time.Second)))
+	for _, l := range st.Lines {
+		trnType := "DEBIT"
+		if l.SignedAmount() > 0 {
+			trnType = "CREDIT"
+		}
+		open("STMTTRN")
+		tag("TRNTYPE", trnType)
+		tag("DTPOSTED", ofxTime(l.PostedAt))
+		tag("TRNAMT", l.SignedAmount().String())
+		tag("FITID", l.EntryID)
+		tag("REFNUM", l.ReferenceID)
+		tag("MEMO", l.Description)
+		closeTag("STMTTRN")
+	}
+	closeTag("BANKTRANLIST")
+
+	open("LEDGERBAL")
+	tag("BALAMT", st.ClosingBalance.String())
+	tag("DTASOF", ofxTime(st.To.Add(24*time.Hour-time.Second)))
+	closeTag("LEDGERBAL")
+	closeTag("STMTRS")
+	closeTag("STMTTRNRS")
+	closeTag("BANKMSGSRSV1")
+	closeTag("OFX")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+// ofxEscape remove quebras de linha e escapa os caracteres reservados do SGML
+func ofxEscape(s string) string {
+	s = strings.NewReplacer("\r", " ", "\n", " ", "&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+	if r := []rune(s); len(r) > 255 {
+		s = string(r[:255])
+	}
+	return s
+}
@@ -0,0 +1,147 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Exportação de extratos em PDF (gerador mínimo, sem dependências externas)
+// ============================================================================
+
+package statements
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Layout A4 em pontos, fonte monoespaçada para alinhar as colunas
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfFontSize     = 8
+	pdfLeading      = 11
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLeading
+)
+
+// WritePDF escreve o extrato como PDF com uma tabela de lançamentos paginada
+func WritePDF(w io.Writer, st *Statement) error {
+	pages := paginate(statementText(st))
+
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catálogo, 2: árvore de páginas, 3: fonte; depois pares página/conteúdo
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for i, lines := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+2*i))
+
+		var content bytes.Buffer
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n",
+			pdfFontSize, pdfLeading, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range lines {
+			fmt.Fprintf(&content, "(%s) '\n", pdfString(line))
+		}
+		footer := fmt.Sprintf("Página %d de %d", i+1, len(pages))
+		fmt.Fprintf(&content, "ET\nBT\n/F1 %d Tf\n%d %d Td\n(%s) Tj\nET\n",
+			pdfFontSize, pdfMargin, pdfMargin/2, pdfString(footer))
+
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// statementText monta as linhas do relatório em colunas de largura fixa
+func statementText(st *Statement) []string {
+	row := func(date, description, counterpart, amount, balance string) string {
+		return fmt.Sprintf("%-10s %-36s %-22s %14s %14s",
+			fit(date, 10), fit(description, 36), fit(counterpart, 22), fit(amount, 14), fit(balance, 14))
+	}
+	rule := strings.Repeat("-", 100)
+
+	lines := []string{
+		"EXTRATO DE CONTA",
+		"",
+		fmt.Sprintf("Conta:   %s - %s", st.AccountNumber, st.AccountName),
+		fmt.Sprintf("Período: %s a %s", st.Period.From, st.Period.To),
+		fmt.Sprintf("Moeda:   %s", st.Currency),
+		fmt.Sprintf("Emitido: %s", st.GeneratedAt.Format("2006-01-02 15:04:05 MST")),
+		"",
+		row("Data", "Descrição", "Contrapartida", "Valor", "Saldo"),
+		rule,
+		row(st.Period.From, "SALDO INICIAL", "", "", st.OpeningBalance.String()),
+	}
+	for _, l := range st.Lines {
+		lines = append(lines, row(l.PostedAt.UTC().Format("2006-01-02"), l.Description,
+			l.counterpartNames(), l.SignedAmount().String(), l.RunningBalance.String()))
+	}
+	lines = append(lines,
+		row(st.Period.To, "SALDO FINAL", "", "", st.ClosingBalance.String()),
+		rule,
+		fmt.Sprintf("Total de créditos: %s", st.TotalCredits),
+		fmt.Sprintf("Total de débitos:  %s", st.TotalDebits),
+		fmt.Sprintf("Lançamentos:       %d", len(st.Lines)),
+	)
+	return lines
+}
+
+func paginate(lines []string) [][]string {
+	perPage := pdfLinesPerPage - 1 // última linha reservada ao rodapé
+	var pages [][]string
+	for len(lines) > perPage {
+		pages = append(pages, lines[:perPage])
+		lines = lines[perPage:]
+	}
+	return append(pages, lines)
+}
+
+// fit corta o texto na largura da coluna
+func fit(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:width-1]) + "~"
+}
+
+// pdfString converte para WinAnsi (Latin-1 cobre o português) e escapa a string literal
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
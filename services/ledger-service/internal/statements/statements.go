@@ -0,0 +1,227 @@
+// ============================================================================
+// KAMINOCLONE - LEDGER SERVICE
+// Extrato de conta com saldo corrente a partir de core.ledger_entries
+// ============================================================================
+
+package statements
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+const (
+	// MaxRange limita o período de um extrato
+	MaxRange = 366 * 24 * time.Hour
+	// MaxLines limita a quantidade de lançamentos em um único extrato
+	MaxLines = 20000
+)
+
+// Erros de extrato
+var (
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrInvalidPeriod     = errors.New("invalid statement period")
+	ErrTooManyLines      = errors.New("statement has too many entries, narrow the period")
+	ErrUnsupportedFormat = errors.New("unsupported statement format")
+)
+
+// Counterpart é a outra ponta de um lançamento
+type Counterpart struct {
+	AccountID     string       `json:"account_id"`
+	AccountNumber string       `json:"account_number"`
+	Name          string       `json:"name"`
+	Amount        ledger.Money `json:"amount"`
+}
+
+// Line é um lançamento do extrato com o saldo após sua aplicação
+type Line struct {
+	EntryID         string           `json:"entry_id"`
+	TransactionID   string           `json:"transaction_id"`
+	ReferenceID     string           `json:"reference_id"`
+	TransactionType string           `json:"transaction_type"`
+	Description     string           `json:"description"`
+	EntryType       ledger.EntryType `json:"entry_type"`
+	Amount          ledger.Money     `json:"amount"`
+	RunningBalance  ledger.Money     `json:"running_balance"`
+	Counterparts    []Counterpart    `json:"counterparts"`
+	PostedAt        time.Time        `json:"posted_at"`
+}
+
+// SignedAmount é o efeito do lançamento no saldo (CREDIT soma, DEBIT subtrai)
+func (l Line) SignedAmount() ledger.Money {
+	if l.EntryType == ledger.EntryCredit {
+		return l.Amount
+	}
+	return -l.Amount
+}
+
+// Statement é o extrato de uma conta em [From, To]
+type Statement struct {
+	AccountID      string       `json:"account_id"`
+	AccountNumber  string       `json:"account_number"`
+	AccountName    string       `json:"account_name"`
+	Currency       string       `json:"currency"`
+	From           time.Time    `json:"-"`
+	To             time.Time    `json:"-"`
+	Period         Period       `json:"period"`
+	OpeningBalance ledger.Money `json:"opening_balance"`
+	TotalCredits   ledger.Money `json:"total_credits"`
+	TotalDebits    ledger.Money `json:"total_debits"`
+	ClosingBalance ledger.Money `json:"closing_balance"`
+	Lines          []Line       `json:"entries"`
+	GeneratedAt    time.Time    `json:"generated_at"`
+}
+
+// Period é o intervalo do extrato, ambas as datas inclusivas
+type Period struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ParsePeriod interpreta from/to (YYYY-MM-DD, inclusivos).
+// Sem parâmetros, o extrato cobre o mês corrente até hoje.
+func ParsePeriod(from, to string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := today
+
+	var err error
+	if from != "" {
+		if start, err = time.Parse("2006-01-02", from); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidPeriod)
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse("2006-01-02", to); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidPeriod)
+		}
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from must not be after to", ErrInvalidPeriod)
+	}
+	if end.Sub(start) > MaxRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: period must be at most 366 days", ErrInvalidPeriod)
+	}
+	return start, end, nil
+}
+
+// Store monta extratos a partir do ledger
+type Store struct {
+	db *sql.DB
+}
+
+// NewStore cria um Store sobre o pool de conexões informado
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Build monta o extrato da conta entre from e to (inclusivos, por partition_date)
+func (s *Store) Build(ctx context.Context, accountID string, from, to time.Time) (*Statement, error) {
+	if !ledger.IsUUID(accountID) {
+		return nil, ErrAccountNotFound
+	}
+
+	// Snapshot consistente entre saldo de abertura e lançamentos
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st := &Statement{
+		AccountID:   accountID,
+		From:        from,
+		To:          to,
+		Period:      Period{From: from.Format("2006-01-02"), To: to.Format("2006-01-02")},
+		Lines:       []Line{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	err = tx.QueryRowContext(ctx, `
+		SELECT account_number, name, currency FROM core.accounts WHERE id = $1
+	`, accountID).Scan(&st.AccountNumber, &st.AccountName, &st.Currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAccountNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load account: %w", err)
+	}
+
+	// Saldo de abertura: saldo após o último lançamento anterior ao período
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE((
+			SELECT balance_after
+			FROM core.ledger_entries
+			WHERE account_id = $1 AND partition_date < $2
+			ORDER BY sequence_number DESC
+			LIMIT 1
+		), 0)
+	`, accountID, from).Scan(&st.OpeningBalance)
+	if err != nil {
+		return nil, fmt.Errorf("load opening balance: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT le.id, le.transaction_id, t.reference_id, t.transaction_type, t.description,
+		       le.entry_type, le.amount, le.created_at,
+		       COALESCE((
+		           SELECT json_agg(json_build_object(
+		               'account_id', o.account_id,
+		               'account_number', oa.account_number,
+		               'name', oa.name,
+		               'amount', o.amount
+		           ) ORDER BY o.sequence_number)
+		           FROM core.ledger_entries o
+		           JOIN core.accounts oa ON oa.id = o.account_id
+		           WHERE o.transaction_id = le.transaction_id
+		             AND o.partition_date = le.partition_date
+		             AND o.entry_type <> le.entry_type
+		       ), '[]')
+		FROM core.ledger_entries le
+		JOIN core.transactions t ON t.id = le.transaction_id AND t.partition_date = le.partition_date
+		WHERE le.account_id = $1 AND le.partition_date >= $2 AND le.partition_date < $3
+		ORDER BY le.sequence_number
+		LIMIT $4
+	`, accountID, from, to.AddDate(0, 0, 1), MaxLines+1)
+	if err != nil {
+		return nil, fmt.Errorf("load entries: %w", err)
+	}
+	defer rows.Close()
+
+	balance := st.OpeningBalance
+	for rows.Next() {
+		var line Line
+		var counterparts []byte
+		if err := rows.Scan(&line.EntryID, &line.TransactionID, &line.ReferenceID,
+			&line.TransactionType, &line.Description, &line.EntryType, &line.Amount,
+			&line.PostedAt, &counterparts); err != nil {
+			return nil, fmt.Errorf("scan entry: %w", err)
+		}
+		if err := json.Unmarshal(counterparts, &line.Counterparts); err != nil {
+			return nil, fmt.Errorf("decode counterparts: %w", err)
+		}
+
+		balance += line.SignedAmount()
+		line.RunningBalance = balance
+		if line.EntryType == ledger.EntryCredit {
+			st.TotalCredits += line.Amount
+		} else {
+			st.TotalDebits += line.Amount
+		}
+		st.Lines = append(st.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("load entries: %w", err)
+	}
+	if len(st.Lines) > MaxLines {
+		return nil, ErrTooManyLines
+	}
+
+	st.ClosingBalance = balance
+	return st, nil
+}
@@ -0,0 +1,121 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kaminoclone/ledger-service/internal/ledger"
+)
+
+func sampleStatement() *Statement {
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	return &Statement{
+		AccountID:      "7d1c1b8e-3f4a-4c55-9b1e-2f7c3a9d0e11",
+		AccountNumber:  "000000123-6",
+		AccountName:    "Conta Corrente João",
+		Currency:       "BRL",
+		From:           from,
+		To:             to,
+		Period:         Period{From: "2026-03-01", To: "2026-03-31"},
+		OpeningBalance: 10000,
+		TotalCredits:   2500,
+		TotalDebits:    1200,
+		ClosingBalance: 11300,
+		Lines: []Line{
+			{EntryID: "e1", ReferenceID: "pix-1", Description: "PIX recebido (Maria)", EntryType: ledger.EntryCredit,
+				Amount: 2500, RunningBalance: 12500, PostedAt: from.Add(10 * time.Hour),
+				Counterparts: []Counterpart{{AccountNumber: "000000007-8", Name: "Liquidação PIX"}}},
+			{EntryID: "e2", ReferenceID: "bol-1", Description: "Pagamento de boleto", EntryType: ledger.EntryDebit,
+				Amount: 1200, RunningBalance: 11300, PostedAt: from.Add(48 * time.Hour)},
+		},
+		GeneratedAt: to,
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	now := time.Date(2026, 4, 15, 13, 0, 0, 0, time.UTC)
+	from, to, err := ParsePeriod("", "", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.Format("2006-01-02") != "2026-04-01" || to.Format("2006-01-02") != "2026-04-15" {
+		t.Fatalf("default period = %v..%v", from, to)
+	}
+	if _, _, err := ParsePeriod("2026-04-10", "2026-04-01", now); !errors.Is(err, ErrInvalidPeriod) {
+		t.Fatalf("expected ErrInvalidPeriod, got %v", err)
+	}
+	if _, _, err := ParsePeriod("2024-01-01", "2026-01-01", now); !errors.Is(err, ErrInvalidPeriod) {
+		t.Fatalf("expected ErrInvalidPeriod for long range, got %v", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, sampleStatement()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("records = %d, want header + opening + 2 entries + closing", len(records))
+	}
+	if records[3][8] != "-12.00" || records[3][9] != "113.00" || records[4][9] != "113.00" {
+		t.Fatalf("unexpected debit row: %v / %v", records[3], records[4])
+	}
+
+	// Texto livre iniciado por caractere de fórmula é neutralizado
+	st := sampleStatement()
+	st.Lines[0].Description = `=HYPERLINK("http://evil.example","x")`
+	st.Lines[0].ReferenceID = "@SUM(A1)"
+	st.Lines[0].Counterparts = []Counterpart{{AccountNumber: "-1+1", Name: "x"}}
+	buf.Reset()
+	if err := WriteCSV(&buf, st); err != nil {
+		t.Fatal(err)
+	}
+	records, err = csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range []int{3, 5, 6} {
+		if !strings.HasPrefix(records[2][col], "'") {
+			t.Errorf("column %d = %q, want formula escaped", col, records[2][col])
+		}
+	}
+	if records[2][8] != "25.00" {
+		t.Errorf("amount = %q, want unescaped", records[2][8])
+	}
+}
+
+func TestWriteOFX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteOFX(&buf, sampleStatement()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"<TRNTYPE>CREDIT", "<TRNAMT>-12.00", "<FITID>e2", "<BALAMT>113.00", "<ACCTID>000000123-6"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("OFX missing %q", want)
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, sampleStatement()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "%PDF-1.4") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatal("not a PDF document")
+	}
+	if !strings.Contains(out, `PIX recebido \(Maria\)`) || !strings.Contains(out, `Jo\343o`) {
+		t.Fatal("PDF text not escaped/encoded as WinAnsi")
+	}
+}
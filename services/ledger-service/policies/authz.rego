@@ -0,0 +1,55 @@
+# ============================================================================
+# KAMINOCLONE - LEDGER SERVICE
+# Política de autorização das rotas /v1
+#
+# O serviço avalia data.kamino.ledger.authz: allow precisa ser true e reasons
+# vazio. Alterações neste diretório são recarregadas sem redeploy.
+# ============================================================================
+
+package kamino.ledger.authz
+
+import rego.v1
+
+default allow := false
+
+# Limite por operação fora do papel ADMIN, em centavos (data.json)
+default user_operation_amount := 1000000
+
+user_operation_amount := data.kamino.limits.user_operation_amount
+
+# ADMIN opera sobre qualquer conta
+allow if input.user.role == "ADMIN"
+
+# USER e VIEWER leem recursos de contas próprias
+allow if {
+	input.route.action == "read"
+	input.user.role in {"USER", "VIEWER"}
+	owns_resource
+}
+
+# USER altera recursos de contas próprias
+allow if {
+	input.route.action == "write"
+	input.user.role == "USER"
+	owns_resource
+}
+
+owns_resource if input.user.id in input.resource.owner_ids
+
+# Negações prevalecem sobre allow e aparecem no log de decisões
+
+reasons contains "VIEWER is read-only" if {
+	input.user.role == "VIEWER"
+	input.route.action == "write"
+}
+
+reasons contains sprintf("amount %d exceeds the per-operation limit %d for %s", [input.resource.amount, user_operation_amount, input.user.role]) if {
+	input.user.role != "ADMIN"
+	input.resource.amount > user_operation_amount
+}
+
+# Rotas de manutenção do ledger são exclusivas de ADMIN
+reasons contains "ledger maintenance requires ADMIN" if {
+	input.resource.type == "ledger"
+	input.user.role != "ADMIN"
+}
@@ -0,0 +1,3 @@
+module github.com/kaminoclone/shared
+
+go 1.21
@@ -0,0 +1,197 @@
+// ============================================================================
+// KAMINOCLONE - SHARED
+// Credenciais de banco estáticas ou dinâmicas (Vault) com renovação de lease
+// ============================================================================
+
+package secrets
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Credentials é um par usuário/senha do banco
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialSource fornece as credenciais vigentes
+type CredentialSource interface {
+	Current() Credentials
+}
+
+// StaticCredentials vêm da configuração e nunca mudam
+type StaticCredentials Credentials
+
+// Current implementa CredentialSource
+func (s StaticCredentials) Current() Credentials {
+	return Credentials(s)
+}
+
+// Logger é o subconjunto de *zap.SugaredLogger usado aqui
+type Logger interface {
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// DynamicCredentials emite usuários temporários pelo engine database do Vault.
+// Run renova o lease e, quando a renovação falha ou atinge o max_ttl, emite
+// novas credenciais e avisa os observadores (ex.: para reciclar o pool).
+type DynamicCredentials struct {
+	vault  *VaultClient
+	mount  string
+	role   string
+	logger Logger
+
+	mu       sync.RWMutex
+	current  Credentials
+	lease    *Lease
+	onChange []func(Credentials)
+}
+
+// NewDynamicCredentials emite as primeiras credenciais do papel informado
+func NewDynamicCredentials(ctx context.Context, vault *VaultClient, mount, role string, logger Logger) (*DynamicCredentials, error) {
+	d := &DynamicCredentials{vault: vault, mount: mount, role: role, logger: logger}
+	if err := d.issue(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Current implementa CredentialSource
+func (d *DynamicCredentials) Current() Credentials {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.current
+}
+
+// OnChange registra um observador chamado após cada rotação
+func (d *DynamicCredentials) OnChange(fn func(Credentials)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onChange = append(d.onChange, fn)
+}
+
+func (d *DynamicCredentials) issue(ctx context.Context) error {
+	lease, err := d.vault.DatabaseCredentials(ctx, d.mount, d.role)
+	if err != nil {
+		return fmt.Errorf("issue database credentials for role %s: %w", d.role, err)
+	}
+	username, _ := lease.Data["username"].(string)
+	password, _ := lease.Data["password"].(string)
+	if username == "" || password == "" {
+		return fmt.Errorf("%w: role %s returned no username/password", ErrSecretNotFound, d.role)
+	}
+
+	d.mu.Lock()
+	d.current = Credentials{Username: username, Password: password}
+	d.lease = lease
+	observers := append([]func(Credentials){}, d.onChange...)
+	d.mu.Unlock()
+
+	for _, fn := range observers {
+		fn(Credentials{Username: username, Password: password})
+	}
+	return nil
+}
+
+// renewAfter devolve quando agir sobre um lease: aos 2/3 da duração,
+// deixando margem para a rotação antes de o usuário expirar
+func renewAfter(duration time.Duration) time.Duration {
+	wait := duration * 2 / 3
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait
+}
+
+// Run mantém as credenciais válidas até o contexto ser cancelado
+func (d *DynamicCredentials) Run(ctx context.Context) {
+	for {
+		d.mu.RLock()
+		lease := d.lease
+		d.mu.RUnlock()
+
+		if lease.Duration <= 0 {
+			// Credencial sem expiração: nada a renovar
+			<-ctx.Done()
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(renewAfter(lease.Duration)):
+		}
+
+		if lease.Renewable {
+			renewed, err := d.vault.RenewLease(ctx, lease.ID, lease.Duration)
+			// Concedido menos que o pedido = max_ttl próximo; melhor rotacionar já
+			if err == nil && renewed.Duration >= lease.Duration {
+				d.mu.Lock()
+				d.lease.Duration = renewed.Duration
+				d.mu.Unlock()
+				continue
+			}
+			if err != nil && ctx.Err() == nil {
+				d.logger.Errorw("Database lease renewal failed, rotating credentials", "lease_id", lease.ID, "error", err)
+			}
+		}
+
+		if err := d.issue(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// O lease atual ainda vale por ~1/3 da duração; tenta de novo em breve
+			d.logger.Errorw("Database credential rotation failed", "role", d.role, "error", err)
+			d.mu.Lock()
+			d.lease = &Lease{ID: lease.ID, Duration: 30 * time.Second}
+			d.mu.Unlock()
+			continue
+		}
+		d.logger.Infow("Database credentials rotated", "role", d.role)
+	}
+}
+
+// Connector abre cada conexão nova com as credenciais vigentes, de modo que o
+// mesmo *sql.DB continua válido depois de uma rotação
+type Connector struct {
+	source CredentialSource
+	dsn    func(Credentials) string
+	open   func(dsn string) (driver.Connector, error)
+}
+
+// NewConnector cria o connector; open é o construtor do driver (ex.: pq.NewConnector)
+func NewConnector(source CredentialSource, dsn func(Credentials) string, open func(dsn string) (driver.Connector, error)) *Connector {
+	return &Connector{source: source, dsn: dsn, open: open}
+}
+
+// Connect implementa driver.Connector
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	connector, err := c.open(c.dsn(c.source.Current()))
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+// Driver implementa driver.Connector
+func (c *Connector) Driver() driver.Driver {
+	connector, err := c.open(c.dsn(c.source.Current()))
+	if err != nil {
+		return nil
+	}
+	return connector.Driver()
+}
+
+// FlushIdle fecha as conexões ociosas do pool (abertas com as credenciais
+// anteriores). Conexões em uso saem pelo ConnMaxLifetime do pool.
+func FlushIdle(db *sql.DB, maxIdle int) {
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(maxIdle)
+}
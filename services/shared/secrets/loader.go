@@ -0,0 +1,103 @@
+// ============================================================================
+// KAMINOCLONE - SHARED
+// Resolução de configuração com referências a segredos do Vault
+// ============================================================================
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultDBPassword é a senha de desenvolvimento do docker-compose
+const DefaultDBPassword = "kamino_secure_password"
+
+// Erros de configuração
+var (
+	ErrInvalidReference   = errors.New("invalid vault reference")
+	ErrVaultNotConfigured = errors.New("vault reference used but VAULT_ADDR/VAULT_TOKEN are not set")
+	ErrDefaultPassword    = errors.New("refusing to start in production with the default database password")
+)
+
+const referencePrefix = "vault://"
+
+// Loader resolve valores de configuração. Um valor no formato
+// vault://<mount>/<caminho>#<chave> é lido do KV v2; os demais são literais.
+type Loader struct {
+	vault *VaultClient // nil = Vault não configurado
+	cache map[string]map[string]string
+}
+
+// NewLoader cria um Loader; vault pode ser nil
+func NewLoader(vault *VaultClient) *Loader {
+	return &Loader{vault: vault, cache: map[string]map[string]string{}}
+}
+
+// IsReference informa se o valor aponta para o Vault
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, referencePrefix)
+}
+
+// ParseReference separa mount, caminho e chave de vault://mount/caminho#chave
+func ParseReference(ref string) (mount, path, key string, err error) {
+	rest := strings.TrimPrefix(ref, referencePrefix)
+	location, key, ok := strings.Cut(rest, "#")
+	mount, path, okPath := strings.Cut(location, "/")
+	if !IsReference(ref) || !ok || !okPath || mount == "" || path == "" || key == "" {
+		return "", "", "", fmt.Errorf("%w: %q must be vault://mount/path#key", ErrInvalidReference, ref)
+	}
+	return mount, path, key, nil
+}
+
+// Resolve devolve o valor literal ou o segredo referenciado
+func (l *Loader) Resolve(ctx context.Context, value string) (string, error) {
+	if !IsReference(value) {
+		return value, nil
+	}
+	if l.vault == nil {
+		return "", ErrVaultNotConfigured
+	}
+	mount, path, key, err := ParseReference(value)
+	if err != nil {
+		return "", err
+	}
+
+	// Vários campos costumam vir do mesmo segredo
+	secret, ok := l.cache[mount+"/"+path]
+	if !ok {
+		if secret, err = l.vault.ReadKV(ctx, mount, path); err != nil {
+			return "", err
+		}
+		l.cache[mount+"/"+path] = secret
+	}
+	v, ok := secret[key]
+	if !ok {
+		return "", fmt.Errorf("%w: key %s in %s/%s", ErrSecretNotFound, key, mount, path)
+	}
+	return v, nil
+}
+
+// Env lê a variável de ambiente (ou defaultValue) e resolve referências
+func (l *Loader) Env(ctx context.Context, key, defaultValue string) (string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		value = defaultValue
+	}
+	resolved, err := l.Resolve(ctx, value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return resolved, nil
+}
+
+// RequireNonDefaultPassword impede produção com a senha padrão ou vazia
+func RequireNonDefaultPassword(env, password string) error {
+	if env == "production" && (password == "" || password == DefaultDBPassword) {
+		return ErrDefaultPassword
+	}
+	return nil
+}
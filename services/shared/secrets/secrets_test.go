@@ -0,0 +1,85 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseReference(t *testing.T) {
+	mount, path, key, err := ParseReference("vault://secret/kamino/ledger#db_password")
+	if err != nil || mount != "secret" || path != "kamino/ledger" || key != "db_password" {
+		t.Fatalf("ParseReference = %q %q %q %v", mount, path, key, err)
+	}
+
+	for _, bad := range []string{"vault://secret#key", "vault://secret/path", "vault:///path#key", "secret/path#key"} {
+		if _, _, _, err := ParseReference(bad); !errors.Is(err, ErrInvalidReference) {
+			t.Errorf("ParseReference(%q) = %v, want ErrInvalidReference", bad, err)
+		}
+	}
+}
+
+func TestLoaderResolve(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get("X-Vault-Token") != "token" || r.URL.Path != "/v1/secret/data/kamino/ledger" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"data": map[string]interface{}{"db_password": "s3cret", "redis_password": "r3dis"}},
+		})
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	loader := NewLoader(NewVaultClient(srv.URL, "token"))
+
+	if v, err := loader.Resolve(ctx, "plain"); err != nil || v != "plain" {
+		t.Fatalf("literal = %q, %v", v, err)
+	}
+	if v, err := loader.Resolve(ctx, "vault://secret/kamino/ledger#db_password"); err != nil || v != "s3cret" {
+		t.Fatalf("reference = %q, %v", v, err)
+	}
+	if v, err := loader.Resolve(ctx, "vault://secret/kamino/ledger#redis_password"); err != nil || v != "r3dis" {
+		t.Fatalf("reference = %q, %v", v, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected secret to be read once, got %d calls", calls)
+	}
+	if _, err := loader.Resolve(ctx, "vault://secret/kamino/other#x"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("missing secret = %v", err)
+	}
+	if _, err := NewLoader(nil).Resolve(ctx, "vault://secret/kamino/ledger#db_password"); !errors.Is(err, ErrVaultNotConfigured) {
+		t.Errorf("without vault = %v", err)
+	}
+}
+
+func TestRequireNonDefaultPassword(t *testing.T) {
+	if err := RequireNonDefaultPassword("production", DefaultDBPassword); !errors.Is(err, ErrDefaultPassword) {
+		t.Errorf("production with default password = %v", err)
+	}
+	if err := RequireNonDefaultPassword("production", ""); !errors.Is(err, ErrDefaultPassword) {
+		t.Errorf("production with empty password = %v", err)
+	}
+	if err := RequireNonDefaultPassword("development", DefaultDBPassword); err != nil {
+		t.Errorf("development = %v", err)
+	}
+	if err := RequireNonDefaultPassword("production", "rotated"); err != nil {
+		t.Errorf("production with custom password = %v", err)
+	}
+}
+
+func TestRenewAfter(t *testing.T) {
+	if got := renewAfter(time.Hour); got != 40*time.Minute {
+		t.Errorf("renewAfter(1h) = %v", got)
+	}
+	if got := renewAfter(0); got != time.Second {
+		t.Errorf("renewAfter(0) = %v", got)
+	}
+}
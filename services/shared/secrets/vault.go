@@ -0,0 +1,142 @@
+// ============================================================================
+// KAMINOCLONE - SHARED
+// Cliente HTTP mínimo do HashiCorp Vault (KV v2, credenciais dinâmicas, leases)
+// ============================================================================
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Erros do Vault
+var (
+	ErrVaultUnavailable = errors.New("vault request failed")
+	ErrSecretNotFound   = errors.New("secret not found in vault")
+)
+
+// VaultClient acessa a API HTTP do Vault com um token
+type VaultClient struct {
+	addr  string
+	token string
+	http  *http.Client
+}
+
+// NewVaultClient cria um cliente para addr (ex.: http://vault:8200)
+func NewVaultClient(addr, token string) *VaultClient {
+	return &VaultClient{
+		addr:  strings.TrimRight(addr, "/"),
+		token: token,
+		http:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Lease é uma credencial com prazo emitida pelo Vault
+type Lease struct {
+	ID        string
+	Duration  time.Duration
+	Renewable bool
+	Data      map[string]interface{}
+}
+
+type vaultResponse struct {
+	LeaseID       string                 `json:"lease_id"`
+	LeaseDuration int                    `json:"lease_duration"`
+	Renewable     bool                   `json:"renewable"`
+	Data          map[string]interface{} `json:"data"`
+	Errors        []string               `json:"errors"`
+}
+
+func (v *VaultClient) do(ctx context.Context, method, path string, body interface{}) (*vaultResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+"/v1/"+strings.TrimLeft(path, "/"), reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVaultUnavailable, err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVaultUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var out vaultResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&out)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, path)
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("%w: %s returned %d: %s", ErrVaultUnavailable, path, resp.StatusCode, strings.Join(out.Errors, "; "))
+	case decodeErr != nil && decodeErr != io.EOF:
+		return nil, fmt.Errorf("%w: decode %s: %v", ErrVaultUnavailable, path, decodeErr)
+	}
+	return &out, nil
+}
+
+// ReadKV lê um segredo do engine KV v2 montado em mount
+func (v *VaultClient) ReadKV(ctx context.Context, mount, path string) (map[string]string, error) {
+	resp, err := v.do(ctx, http.MethodGet, mount+"/data/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := resp.Data["data"].(map[string]interface{})
+	if data == nil {
+		// Versão mais recente apagada
+		return nil, fmt.Errorf("%w: %s/%s", ErrSecretNotFound, mount, path)
+	}
+	out := make(map[string]string, len(data))
+	for k, val := range data {
+		out[k] = fmt.Sprint(val)
+	}
+	return out, nil
+}
+
+// DatabaseCredentials emite um usuário temporário no engine database
+func (v *VaultClient) DatabaseCredentials(ctx context.Context, mount, role string) (*Lease, error) {
+	resp, err := v.do(ctx, http.MethodGet, mount+"/creds/"+role, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{
+		ID:        resp.LeaseID,
+		Duration:  time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable: resp.Renewable,
+		Data:      resp.Data,
+	}, nil
+}
+
+// RenewLease estende o lease; o Vault pode conceder menos que increment (max_ttl)
+func (v *VaultClient) RenewLease(ctx context.Context, leaseID string, increment time.Duration) (*Lease, error) {
+	resp, err := v.do(ctx, http.MethodPut, "sys/leases/renew", map[string]interface{}{
+		"lease_id":  leaseID,
+		"increment": int(increment.Seconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Lease{
+		ID:        resp.LeaseID,
+		Duration:  time.Duration(resp.LeaseDuration) * time.Second,
+		Renewable: resp.Renewable,
+	}, nil
+}